@@ -0,0 +1,323 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/watzon/postpilot/internal/api"
+	"github.com/watzon/postpilot/internal/extract"
+	"github.com/watzon/postpilot/internal/preview"
+	"github.com/watzon/postpilot/internal/smtp"
+)
+
+// maxCodeWait caps how long /api/codes/latest waits for an email
+const maxCodeWait = 5 * time.Minute
+
+// latestCode is a value extracted from the newest matching email
+type latestCode struct {
+	EmailID   string    `json:"emailId"`
+	To        []string  `json:"to"`
+	Subject   string    `json:"subject"`
+	Timestamp time.Time `json:"timestamp"`
+	// Extractor the value was found by: code, link or a configured rule
+	Extractor string `json:"extractor"`
+	Value     string `json:"value"`
+}
+
+// startAPIServer registers the HTTP API routes and starts serving them
+func (a *App) startAPIServer(settings APISettings) error {
+	s := api.NewServer(settings.Host, settings.Port)
+
+	s.Handle("/api/emails", func(r *http.Request) (interface{}, error) {
+		return a.GetEmails(), nil
+	})
+	s.Handle("/api/emails/", a.handleEmail)
+	s.Handle("/api/transcripts", func(r *http.Request) (interface{}, error) {
+		return a.GetTranscripts(), nil
+	})
+	s.Handle("/api/transcripts/", a.handleTranscript)
+	s.Handle("/api/activity", a.handleActivity)
+	s.Handle("/api/notifications/dropped", func(r *http.Request) (interface{}, error) {
+		return map[string]uint64{"dropped": a.GetDroppedNotifications()}, nil
+	})
+	s.Handle("/api/codes/latest", a.handleLatestCode)
+	s.HandleRaw("/preview/", a.previewHandler())
+
+	if err := s.Start(); err != nil {
+		return fmt.Errorf("failed to start API server: %w", err)
+	}
+
+	a.api = s
+	return nil
+}
+
+// RestartAPIServer restarts the HTTP API with the current settings
+func (a *App) RestartAPIServer() error {
+	if a.api != nil {
+		if err := a.api.Stop(); err != nil {
+			return fmt.Errorf("failed to stop API server: %w", err)
+		}
+		a.api = nil
+	}
+
+	settings, err := a.GetSettings()
+	if err != nil {
+		return fmt.Errorf("failed to get settings: %w", err)
+	}
+	if !settings.API.Enabled {
+		return nil
+	}
+
+	return a.startAPIServer(settings.API)
+}
+
+// handleEmail serves /api/emails/{id} and its sub-resources
+func (a *App) handleEmail(r *http.Request) (interface{}, error) {
+	params := api.PathParams(r, "/api/emails/")
+	if len(params) == 0 {
+		return a.GetEmails(), nil
+	}
+
+	email, err := a.GetEmail(params[0])
+	if err != nil {
+		return nil, api.NotFound("%v", err)
+	}
+
+	if len(params) == 1 {
+		return email, nil
+	}
+
+	switch params[1] {
+	case "transcript":
+		t, err := a.GetTranscript(email.SessionID)
+		if err != nil {
+			return nil, api.NotFound("%v", err)
+		}
+		return t, nil
+	case "security":
+		return email.Security, nil
+	case "dkim":
+		return email.DKIM, nil
+	case "auth":
+		return email.Auth, nil
+	case "spam":
+		return email.Spam, nil
+	case "size":
+		return email.Size, nil
+	case "accessibility":
+		return email.Accessibility, nil
+	case "parity":
+		return email.Parity, nil
+	case "extracted":
+		return email.Extracted, nil
+	case "inline":
+		return email.Inline, nil
+	case "calendar":
+		if len(params) < 3 {
+			return email.Calendars, nil
+		}
+		index, err := strconv.Atoi(strings.TrimSuffix(params[2], ".ics"))
+		if err != nil {
+			return nil, api.BadRequest("invalid calendar index %q", params[2])
+		}
+		filename, data, err := smtp.ReadCalendarPart([]byte(email.Raw), index)
+		if err != nil {
+			return nil, api.NotFound("%v", err)
+		}
+		return &api.File{Name: filename, ContentType: "text/calendar; charset=utf-8", Data: data}, nil
+	case "attached":
+		return email.Attached, nil
+	case "tracking":
+		return email.Tracking, nil
+	case "preview":
+		var options preview.Options
+		for param, option := range map[string]*bool{
+			"remote":     &options.LoadRemote,
+			"notracking": &options.RemoveTracking,
+		} {
+			if v := r.URL.Query().Get(param); v != "" {
+				b, err := strconv.ParseBool(v)
+				if err != nil {
+					return nil, api.BadRequest("invalid %s %q", param, v)
+				}
+				*option = b
+			}
+		}
+		return a.PreviewEmail(email.ID, options)
+	case "links":
+		if len(params) > 2 && params[2] == "check" {
+			results, err := a.CheckEmailLinks(email.ID)
+			if err != nil {
+				return nil, api.BadRequest("%v", err)
+			}
+			return results, nil
+		}
+		return email.Links, nil
+	case "compat":
+		var clients []string
+		if v := r.URL.Query().Get("clients"); v != "" {
+			clients = strings.Split(v, ",")
+		}
+		report, err := a.CheckCompatibility(email.ID, clients)
+		if err != nil {
+			return nil, api.BadRequest("%v", err)
+		}
+		return report, nil
+	}
+
+	return nil, api.NotFound("unknown resource %q", params[1])
+}
+
+// handleTranscript serves /api/transcripts/{id}
+func (a *App) handleTranscript(r *http.Request) (interface{}, error) {
+	params := api.PathParams(r, "/api/transcripts/")
+	if len(params) != 1 {
+		return nil, api.NotFound("transcript not found")
+	}
+
+	t, err := a.GetTranscript(params[0])
+	if err != nil {
+		return nil, api.NotFound("%v", err)
+	}
+	return t, nil
+}
+
+// handleActivity serves /api/activity, filtered by the outcome, remote,
+// since (RFC 3339) and limit query parameters
+func (a *App) handleActivity(r *http.Request) (interface{}, error) {
+	q := r.URL.Query()
+	filter := smtp.SessionLogFilter{
+		Outcome:    smtp.SessionOutcome(q.Get("outcome")),
+		RemoteAddr: q.Get("remote"),
+	}
+
+	if v := q.Get("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, api.BadRequest("invalid since: %v", err)
+		}
+		filter.Since = since
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return nil, api.BadRequest("invalid limit %q", v)
+		}
+		filter.Limit = limit
+	}
+
+	return a.GetActivity(filter), nil
+}
+
+// handleLatestCode serves /api/codes/latest: the value found by the
+// extractor named in the extractor parameter (default code) in the newest
+// email to the to address, optionally received after since (RFC 3339).
+// With timeout (e.g. 30s) it waits for such an email to arrive.
+func (a *App) handleLatestCode(r *http.Request) (interface{}, error) {
+	q := r.URL.Query()
+	to := q.Get("to")
+	if to == "" {
+		return nil, api.BadRequest("missing to parameter")
+	}
+	extractor := q.Get("extractor")
+	if extractor == "" {
+		extractor = extract.ExtractorCode
+	}
+
+	var since time.Time
+	if v := q.Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, api.BadRequest("invalid since: %v", err)
+		}
+		since = t
+	}
+
+	var timeout time.Duration
+	if v := q.Get("timeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			// Plain numbers are seconds
+			n, nerr := strconv.Atoi(v)
+			if nerr != nil {
+				return nil, api.BadRequest("invalid timeout %q", v)
+			}
+			d = time.Duration(n) * time.Second
+		}
+		if d < 0 || d > maxCodeWait {
+			return nil, api.BadRequest("timeout must be between 0 and %s", maxCodeWait)
+		}
+		timeout = d
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		// Take the arrival channel before searching so an email stored in
+		// between isn't missed
+		arrived := a.emailArrival()
+		if code := a.latestCode(to, extractor, since); code != nil {
+			return code, nil
+		}
+
+		select {
+		case <-arrived:
+		case <-timer.C:
+			return nil, api.NotFound("no %s found for %s", extractor, to)
+		case <-r.Context().Done():
+			return nil, r.Context().Err()
+		}
+	}
+}
+
+// latestCode returns the value found by extractor in the newest email to
+// the given address received after since, or nil. The address may be an
+// envelope recipient or appear in the To or Cc header.
+func (a *App) latestCode(to, extractor string, since time.Time) *latestCode {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	for i := len(a.emails) - 1; i >= 0; i-- {
+		e := a.emails[i]
+		if e.Extracted == nil || !e.Timestamp.After(since) || !addressedTo(e, to) {
+			continue
+		}
+		for _, m := range e.Extracted.Matches {
+			if m.Extractor == extractor {
+				return &latestCode{
+					EmailID:   e.ID,
+					To:        e.To,
+					Subject:   e.Subject,
+					Timestamp: e.Timestamp,
+					Extractor: extractor,
+					Value:     m.Value,
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// addressedTo reports whether e was delivered to addr or names it as a
+// recipient in its headers
+func addressedTo(e *Email, addr string) bool {
+	return hasRecipient(e.Recipients, addr) || hasRecipient(e.To, addr) || hasRecipient(e.Cc, addr)
+}
+
+// hasRecipient reports whether addr is one of the recipients, which may
+// include display names
+func hasRecipient(recipients []string, addr string) bool {
+	for _, r := range recipients {
+		if parsed, err := mail.ParseAddress(r); err == nil {
+			r = parsed.Address
+		}
+		if strings.EqualFold(strings.TrimSpace(r), strings.TrimSpace(addr)) {
+			return true
+		}
+	}
+	return false
+}
@@ -5,24 +5,65 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"github.com/watzon/postpilot/internal/a11y"
+	"github.com/watzon/postpilot/internal/api"
+	"github.com/watzon/postpilot/internal/calendar"
+	"github.com/watzon/postpilot/internal/compat"
+	"github.com/watzon/postpilot/internal/extract"
+	"github.com/watzon/postpilot/internal/links"
 	"github.com/watzon/postpilot/internal/notify"
+	"github.com/watzon/postpilot/internal/parity"
+	"github.com/watzon/postpilot/internal/preview"
 	"github.com/watzon/postpilot/internal/smtp"
+	"github.com/watzon/postpilot/internal/spam"
+	"github.com/watzon/postpilot/internal/tracking"
 )
 
 type Email struct {
-	ID        string    `json:"id"`
-	From      string    `json:"from"`
-	To        []string  `json:"to"`
-	Subject   string    `json:"subject"`
-	Body      string    `json:"body"`
-	HTML      string    `json:"html"`
-	Timestamp time.Time `json:"timestamp"`
+	ID            string              `json:"id"`
+	From          string              `json:"from"`
+	MailFrom      string              `json:"mailFrom"`
+	Recipients    []string            `json:"recipients"`
+	Helo          string              `json:"helo"`
+	ClientIP      string              `json:"clientIp"`
+	To            []string            `json:"to"`
+	Cc            []string            `json:"cc"`
+	Subject       string              `json:"subject"`
+	Body          string              `json:"body"`
+	HTML          string              `json:"html"`
+	Timestamp     time.Time           `json:"timestamp"`
+	Raw           string              `json:"raw"`
+	ParseWarnings []string            `json:"parseWarnings"`
+	SessionID     string              `json:"sessionId"`
+	Extensions    []string            `json:"extensions"`
+	DSN           smtp.DSNParams      `json:"dsn"`
+	BounceOf      string              `json:"bounceOf"`
+	ParentID      string              `json:"parentId"`
+	Report        *smtp.Report        `json:"report"`
+	Inline        []smtp.InlinePart   `json:"inline"`
+	Calendars     []calendar.Calendar `json:"calendars"`
+	Attachments   []smtp.Attachment   `json:"attachments"`
+	Attached      []*Email            `json:"attached"`
+	Security      *smtp.Security      `json:"security"`
+	DKIM          []smtp.DKIMResult   `json:"dkim"`
+	Auth          *smtp.AuthResults   `json:"auth"`
+	Spam          *spam.Report        `json:"spam"`
+	Size          *smtp.SizeReport    `json:"size"`
+	Links         []links.Link        `json:"links"`
+	Extracted     *extract.Result     `json:"extracted"`
+	Accessibility *a11y.Report        `json:"accessibility"`
+	Parity        *parity.Report      `json:"parity"`
+	Tracking      *tracking.Report    `json:"tracking"`
 }
 
 type UISettings struct {
@@ -34,24 +75,57 @@ type UISettings struct {
 }
 
 type SMTPSettings struct {
-	Host     string `json:"host"`
-	Port     int    `json:"port"`
-	Auth     string `json:"auth"`
-	Username string `json:"username"`
-	Password string `json:"password"`
-	TLS      string `json:"tls"`
+	Host       string          `json:"host"`
+	Port       int             `json:"port"`
+	Auth       string          `json:"auth"`
+	Username   string          `json:"username"`
+	Password   string          `json:"password"`
+	TLS        string          `json:"tls"`
+	Limits     smtp.Limits     `json:"limits"`
+	Extensions smtp.Extensions `json:"extensions"`
+}
+
+type FaultSettings struct {
+	Enabled bool             `json:"enabled"`
+	Rules   []smtp.FaultRule `json:"rules"`
+}
+
+type GreylistSettings struct {
+	Enabled      bool `json:"enabled"`
+	DelaySeconds int  `json:"delaySeconds"`
+}
+
+type APISettings struct {
+	Enabled bool   `json:"enabled"`
+	Host    string `json:"host"`
+	Port    int    `json:"port"`
 }
 
 type Settings struct {
-	UI   UISettings   `json:"ui"`
-	SMTP SMTPSettings `json:"smtp"`
+	UI       UISettings        `json:"ui"`
+	SMTP     SMTPSettings      `json:"smtp"`
+	API      APISettings       `json:"api"`
+	Faults   FaultSettings     `json:"faults"`
+	Greylist GreylistSettings  `json:"greylist"`
+	Bounces  smtp.BounceConfig `json:"bounces"`
+	DKIM     smtp.DKIMConfig   `json:"dkim"`
+	Zone     smtp.ZoneConfig   `json:"zone"`
+	Spam     spam.Config       `json:"spam"`
+	Size     smtp.SizeConfig   `json:"size"`
+	Links    links.Config      `json:"links"`
+	Extract  extract.Config    `json:"extract"`
+	SMIME    smtp.SMIMEConfig  `json:"smime"`
 }
 
 type App struct {
 	ctx    context.Context
 	smtp   *smtp.Server
+	api    *api.Server
 	emails []*Email
 	mu     sync.RWMutex
+	// Closed and replaced when an email is stored, to wake API requests
+	// waiting for one
+	arrived chan struct{}
 }
 
 func NewApp() *App {
@@ -80,6 +154,13 @@ func (a *App) startup(ctx context.Context) {
 		_ = os.Remove(a.getEmailsPath())
 	}
 
+	// Start the HTTP API, independently of the SMTP server
+	if settings.API.Enabled {
+		if err := a.startAPIServer(settings.API); err != nil {
+			log.Printf("Failed to start API server: %v", err)
+		}
+	}
+
 	// Start SMTP server
 	if err := a.startSMTPServer(); err != nil {
 		log.Printf("Failed to start SMTP server: %v", err)
@@ -96,6 +177,11 @@ func (a *App) shutdown(ctx context.Context) {
 			log.Printf("Error stopping SMTP server: %v", err)
 		}
 	}
+	if a.api != nil {
+		if err := a.api.Stop(); err != nil {
+			log.Printf("Error stopping API server: %v", err)
+		}
+	}
 }
 
 func (a *App) startSMTPServer() error {
@@ -114,6 +200,41 @@ func (a *App) startSMTPServer() error {
 		settings.SMTP.TLS,
 	)
 
+	if err := s.SetLimits(settings.SMTP.Limits); err != nil {
+		log.Printf("Ignoring invalid server limits: %v", err)
+	}
+	if err := s.SetExtensions(settings.SMTP.Extensions); err != nil {
+		log.Printf("Ignoring invalid server extensions: %v", err)
+	}
+	if err := s.SetFaultRules(settings.Faults.Enabled, settings.Faults.Rules); err != nil {
+		log.Printf("Ignoring invalid fault rules: %v", err)
+	}
+	s.SetGreylisting(settings.Greylist.Enabled, time.Duration(settings.Greylist.DelaySeconds)*time.Second)
+	if err := s.SetBounces(settings.Bounces); err != nil {
+		log.Printf("Ignoring invalid bounce settings: %v", err)
+	}
+	if err := s.SetDKIM(settings.DKIM); err != nil {
+		log.Printf("Ignoring invalid DKIM settings: %v", err)
+	}
+	if err := s.SetZone(settings.Zone); err != nil {
+		log.Printf("Ignoring invalid zone settings: %v", err)
+	}
+	if err := s.SetSpam(settings.Spam); err != nil {
+		log.Printf("Ignoring invalid spam settings: %v", err)
+	}
+	if err := s.SetSizeBudget(settings.Size); err != nil {
+		log.Printf("Ignoring invalid size settings: %v", err)
+	}
+	if err := s.SetLinks(settings.Links); err != nil {
+		log.Printf("Ignoring invalid link settings: %v", err)
+	}
+	if err := s.SetExtractors(settings.Extract); err != nil {
+		log.Printf("Ignoring invalid extractor settings: %v", err)
+	}
+	if err := s.SetSMIME(settings.SMIME); err != nil {
+		log.Printf("Ignoring invalid S/MIME settings: %v", err)
+	}
+
 	// Start server
 	if err := s.Start(); err != nil {
 		return fmt.Errorf("failed to start SMTP server: %w", err)
@@ -123,6 +244,7 @@ func (a *App) startSMTPServer() error {
 
 	// Start goroutine to handle incoming emails
 	go a.handleIncomingEmails()
+	go a.handleSessionLog(s)
 
 	return nil
 }
@@ -132,19 +254,15 @@ func (a *App) handleIncomingEmails() {
 
 	for email := range emailChan {
 		// Convert SMTP email to our Email type
-		newEmail := &Email{
-			ID:        email.ID,
-			From:      email.From,
-			To:        email.To,
-			Subject:   email.Subject,
-			Body:      email.Body,
-			HTML:      email.HTML,
-			Timestamp: email.Timestamp,
-		}
+		newEmail := convertEmail(email)
 
 		// Store email
 		a.mu.Lock()
 		a.emails = append(a.emails, newEmail)
+		if a.arrived != nil {
+			close(a.arrived)
+			a.arrived = nil
+		}
 		a.mu.Unlock()
 
 		// Get current settings
@@ -174,6 +292,69 @@ func (a *App) handleIncomingEmails() {
 	}
 }
 
+// convertEmail converts an email received by the SMTP server, and the
+// messages attached to it, to our Email type
+func convertEmail(email *smtp.Email) *Email {
+	e := &Email{
+		ID:            email.ID,
+		From:          email.From,
+		MailFrom:      email.MailFrom,
+		Recipients:    email.Recipients,
+		Helo:          email.Helo,
+		ClientIP:      email.ClientIP,
+		To:            email.To,
+		Cc:            email.Cc,
+		Subject:       email.Subject,
+		Body:          email.Body,
+		HTML:          email.HTML,
+		Timestamp:     email.Timestamp,
+		Raw:           email.Raw,
+		SessionID:     email.SessionID,
+		Extensions:    email.Extensions,
+		DSN:           email.DSN,
+		BounceOf:      email.BounceOf,
+		ParentID:      email.ParentID,
+		Report:        email.Report,
+		ParseWarnings: email.ParseWarnings,
+		Inline:        email.Inline,
+		Calendars:     email.Calendars,
+		Attachments:   email.Attachments,
+		Attached:      make([]*Email, 0, len(email.Attached)),
+		Security:      email.Security,
+		DKIM:          email.DKIM,
+		Auth:          email.Auth,
+		Spam:          email.Spam,
+		Size:          email.Size,
+		Links:         email.Links,
+		Extracted:     email.Extracted,
+		Accessibility: email.Accessibility,
+		Parity:        email.Parity,
+		Tracking:      email.Tracking,
+	}
+	for _, child := range email.Attached {
+		e.Attached = append(e.Attached, convertEmail(child))
+	}
+	return e
+}
+
+// emailArrival returns a channel that is closed when the next email is
+// stored
+func (a *App) emailArrival() <-chan struct{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.arrived == nil {
+		a.arrived = make(chan struct{})
+	}
+	return a.arrived
+}
+
+// handleSessionLog forwards finished SMTP sessions to the frontend's activity stream
+func (a *App) handleSessionLog(s *smtp.Server) {
+	for entry := range s.SessionsChan() {
+		runtime.EventsEmit(a.ctx, "activity:session", entry)
+	}
+}
+
 // GetEmails returns all stored emails
 func (a *App) GetEmails() []*Email {
 	a.mu.RLock()
@@ -181,7 +362,229 @@ func (a *App) GetEmails() []*Email {
 	return a.emails
 }
 
-// RestartSMTPServer restarts the SMTP server with new settings
+// GetEmail returns the stored email with the given ID, which may be a
+// message attached to one
+func (a *App) GetEmail(id string) (*Email, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if e := findEmail(a.emails, id); e != nil {
+		return e, nil
+	}
+	return nil, fmt.Errorf("email %s not found", id)
+}
+
+// findEmail returns the email with the given ID among emails and the
+// messages attached to them, or nil
+func findEmail(emails []*Email, id string) *Email {
+	for _, e := range emails {
+		if e.ID == id {
+			return e
+		}
+		if child := findEmail(e.Attached, id); child != nil {
+			return child
+		}
+	}
+	return nil
+}
+
+// GetDroppedNotifications returns how many received emails never reached
+// the UI because the SMTP server's notification channel was full
+func (a *App) GetDroppedNotifications() uint64 {
+	if a.smtp == nil {
+		return 0
+	}
+	return a.smtp.DroppedNotifications()
+}
+
+// GetTranscripts returns the SMTP transcripts of recent sessions
+func (a *App) GetTranscripts() []smtp.Transcript {
+	if a.smtp == nil {
+		return []smtp.Transcript{}
+	}
+	return a.smtp.Transcripts()
+}
+
+// GetTranscript returns the SMTP transcript of a single session
+func (a *App) GetTranscript(id string) (smtp.Transcript, error) {
+	if a.smtp != nil {
+		if t, ok := a.smtp.Transcript(id); ok {
+			return t, nil
+		}
+	}
+	return smtp.Transcript{}, fmt.Errorf("transcript %s not found", id)
+}
+
+// GetEmailTranscript returns the transcript of the session an email was delivered in
+func (a *App) GetEmailTranscript(emailID string) (smtp.Transcript, error) {
+	email, err := a.GetEmail(emailID)
+	if err != nil {
+		return smtp.Transcript{}, err
+	}
+	return a.GetTranscript(email.SessionID)
+}
+
+// SignEmailDKIM signs the raw source of a stored email with the configured
+// DKIM signing key. canonicalization (e.g. "simple/simple") overrides the
+// configured one if not empty.
+func (a *App) SignEmailDKIM(id string, canonicalization string) (*smtp.DKIMSignature, error) {
+	email, err := a.GetEmail(id)
+	if err != nil {
+		return nil, err
+	}
+	if email.Raw == "" {
+		return nil, fmt.Errorf("email %s has no raw source", id)
+	}
+
+	settings, err := a.GetSettings()
+	if err != nil {
+		return nil, err
+	}
+	return smtp.SignDKIM([]byte(email.Raw), settings.DKIM.Signing, canonicalization)
+}
+
+// EvaluateEmailAuth re-runs the SPF and DMARC evaluation of a stored email
+// against the current zone settings, e.g. after editing the zone file
+func (a *App) EvaluateEmailAuth(id string) (*smtp.AuthResults, error) {
+	email, err := a.GetEmail(id)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := a.GetSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	results := smtp.EvaluateAuth(settings.Zone, email.MailFrom, email.Helo, email.From, email.ClientIP, email.DKIM)
+
+	a.mu.Lock()
+	email.Auth = results
+	a.mu.Unlock()
+
+	if err := a.saveEmails(); err != nil {
+		log.Printf("Failed to save emails: %v", err)
+	}
+	return results, nil
+}
+
+// CheckCompatibility reports the HTML and CSS features of an email that the
+// given client families do not fully support. No clients means all of them.
+func (a *App) CheckCompatibility(id string, clients []string) (*compat.Report, error) {
+	email, err := a.GetEmail(id)
+	if err != nil {
+		return nil, err
+	}
+	return compat.Check(email.HTML, clients)
+}
+
+// CheckEmailLinks sends a HEAD request for each link of an email under the
+// configured check base URL and reports the status codes
+func (a *App) CheckEmailLinks(id string) ([]links.CheckResult, error) {
+	email, err := a.GetEmail(id)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := a.GetSettings()
+	if err != nil {
+		return nil, err
+	}
+	return links.Check(context.Background(), settings.Links, email.Links)
+}
+
+// SaveCalendar asks where to save the calendar part of an email at index
+// and writes the original .ics there. It returns the path, or an empty
+// string if the dialog was cancelled.
+func (a *App) SaveCalendar(id string, index int) (string, error) {
+	email, err := a.GetEmail(id)
+	if err != nil {
+		return "", err
+	}
+	filename, data, err := smtp.ReadCalendarPart([]byte(email.Raw), index)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		DefaultFilename: filepath.Base(filename),
+		Filters: []runtime.FileFilter{
+			{DisplayName: "iCalendar (*.ics)", Pattern: "*.ics"},
+		},
+	})
+	if err != nil || path == "" {
+		return "", err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to save calendar: %w", err)
+	}
+	return path, nil
+}
+
+// PreviewEmail returns the HTML part of an email made safe to display in
+// the app: scripts, frames and event handlers are removed, cid: URLs point
+// at the inline parts, and remote images are blocked unless
+// options.LoadRemote routes them through the local proxy. With
+// options.RemoveTracking it shows the email without its tracking.
+func (a *App) PreviewEmail(id string, options preview.Options) (*preview.Result, error) {
+	email, err := a.GetEmail(id)
+	if err != nil {
+		return nil, err
+	}
+	return preview.Render(email.HTML, preview.InlinePath+url.PathEscape(email.ID)+"/", options), nil
+}
+
+// previewHandler serves the resources previews refer to. It is mounted on
+// both the AssetServer and the HTTP API.
+func (a *App) previewHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(preview.RemotePath, preview.NewProxy())
+	mux.HandleFunc(preview.InlinePath, a.serveInlinePart)
+	return mux
+}
+
+// serveInlinePart serves the part with a Content-ID of a stored email at
+// InlinePath/{email id}/{content id}
+func (a *App) serveInlinePart(w http.ResponseWriter, r *http.Request) {
+	emailID, contentID, ok := strings.Cut(strings.TrimPrefix(r.URL.EscapedPath(), preview.InlinePath), "/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if id, err := url.PathUnescape(emailID); err == nil {
+		emailID = id
+	}
+
+	email, err := a.GetEmail(emailID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	mediaType, data, err := smtp.ReadInlinePart([]byte(email.Raw), contentID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// Inline parts can be HTML or SVG, which must not run script if one is
+	// opened directly
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+	w.Write(data)
+}
+
+// GetActivity returns the log of recent SMTP sessions matching filter,
+// including sessions that were rejected or aborted before delivering mail
+func (a *App) GetActivity(filter smtp.SessionLogFilter) []smtp.SessionLogEntry {
+	if a.smtp == nil {
+		return []smtp.SessionLogEntry{}
+	}
+	return a.smtp.SessionLog(filter)
+}
+
+// RestartSMTPServer restarts the SMTP server with new settings, including
+// listener settings such as the address and limits
 func (a *App) RestartSMTPServer() error {
 	if a.smtp != nil {
 		if err := a.smtp.Stop(); err != nil {
@@ -205,10 +608,51 @@ func (a *App) GetSettings() (Settings, error) {
 			Persistence:  false,
 		},
 		SMTP: SMTPSettings{
-			Host: "localhost",
-			Port: 1025,
-			Auth: "none",
-			TLS:  "none",
+			Host:       "localhost",
+			Port:       1025,
+			Auth:       "none",
+			TLS:        "none",
+			Limits:     smtp.DefaultLimits(),
+			Extensions: smtp.DefaultExtensions(),
+		},
+		API: APISettings{
+			Enabled: true,
+			Host:    "localhost",
+			Port:    8025,
+		},
+		Faults: FaultSettings{
+			Enabled: false,
+			Rules:   []smtp.FaultRule{},
+		},
+		Greylist: GreylistSettings{
+			Enabled:      false,
+			DelaySeconds: 60,
+		},
+		Bounces: smtp.BounceConfig{
+			Enabled:    false,
+			Recipients: []string{},
+			Code:       550,
+		},
+		DKIM: smtp.DKIMConfig{
+			Keys: []smtp.DKIMKey{},
+		},
+		Zone: smtp.ZoneConfig{
+			Records: []smtp.ZoneRecord{},
+		},
+		Spam: spam.Config{
+			Threshold: spam.DefaultThreshold,
+		},
+		Size: smtp.SizeConfig{
+			HTMLLimit:    smtp.DefaultHTMLSizeLimit,
+			MessageLimit: smtp.DefaultMessageSizeLimit,
+			ImageLimit:   smtp.DefaultImageSizeLimit,
+		},
+		Links: links.Config{
+			InternalHosts: []string{},
+			UTMParams:     []string{},
+		},
+		Extract: extract.Config{
+			Rules: []extract.Rule{},
 		},
 	}
 
@@ -230,6 +674,40 @@ func (a *App) GetSettings() (Settings, error) {
 }
 
 func (a *App) SaveSettings(settings Settings) error {
+	if err := smtp.ValidateFaultRules(settings.Faults.Rules); err != nil {
+		return err
+	}
+	if err := settings.SMTP.Limits.Validate(); err != nil {
+		return err
+	}
+	if err := settings.SMTP.Extensions.Validate(); err != nil {
+		return err
+	}
+	if err := settings.Bounces.Validate(); err != nil {
+		return err
+	}
+	if err := settings.DKIM.Validate(); err != nil {
+		return err
+	}
+	if err := settings.Zone.Validate(); err != nil {
+		return err
+	}
+	if err := settings.Spam.Validate(); err != nil {
+		return err
+	}
+	if err := settings.Size.Validate(); err != nil {
+		return err
+	}
+	if err := settings.Links.Validate(); err != nil {
+		return err
+	}
+	if err := settings.Extract.Validate(); err != nil {
+		return err
+	}
+	if err := settings.SMIME.Validate(); err != nil {
+		return err
+	}
+
 	configPath := a.getConfigPath()
 
 	// Create config directory if it doesn't exist
@@ -245,7 +723,74 @@ func (a *App) SaveSettings(settings Settings) error {
 	}
 
 	// Write to file
-	return os.WriteFile(configPath, data, 0644)
+	if err := os.WriteFile(configPath, data, 0644); err != nil {
+		return err
+	}
+
+	return a.applyRuntimeSettings(settings)
+}
+
+// applyRuntimeSettings pushes settings that can change without a restart
+// to the running SMTP server
+func (a *App) applyRuntimeSettings(settings Settings) error {
+	if a.smtp == nil {
+		return nil
+	}
+
+	a.smtp.SetGreylisting(settings.Greylist.Enabled, time.Duration(settings.Greylist.DelaySeconds)*time.Second)
+	if err := a.smtp.SetBounces(settings.Bounces); err != nil {
+		return err
+	}
+	if err := a.smtp.SetDKIM(settings.DKIM); err != nil {
+		return err
+	}
+	if err := a.smtp.SetZone(settings.Zone); err != nil {
+		return err
+	}
+	if err := a.smtp.SetSpam(settings.Spam); err != nil {
+		return err
+	}
+	if err := a.smtp.SetSizeBudget(settings.Size); err != nil {
+		return err
+	}
+	if err := a.smtp.SetLinks(settings.Links); err != nil {
+		return err
+	}
+	if err := a.smtp.SetExtractors(settings.Extract); err != nil {
+		return err
+	}
+	if err := a.smtp.SetSMIME(settings.SMIME); err != nil {
+		return err
+	}
+	return a.smtp.SetFaultRules(settings.Faults.Enabled, settings.Faults.Rules)
+}
+
+// SetFaultInjectionEnabled turns fault injection on or off for the running server
+func (a *App) SetFaultInjectionEnabled(enabled bool) error {
+	settings, err := a.GetSettings()
+	if err != nil {
+		return err
+	}
+
+	settings.Faults.Enabled = enabled
+	return a.SaveSettings(settings)
+}
+
+// SaveFaultRules replaces the fault injection rules and applies them immediately
+func (a *App) SaveFaultRules(rules []smtp.FaultRule) error {
+	settings, err := a.GetSettings()
+	if err != nil {
+		return err
+	}
+
+	for i := range rules {
+		if rules[i].ID == "" {
+			rules[i].ID = uuid.New().String()
+		}
+	}
+
+	settings.Faults.Rules = rules
+	return a.SaveSettings(settings)
 }
 
 func (a *App) getConfigPath() string {
@@ -343,6 +888,22 @@ func (a *App) ClearEmails() error {
 	return nil
 }
 
+// GetGreylist returns the greylisting triplet table
+func (a *App) GetGreylist() []smtp.GreylistEntry {
+	if a.smtp == nil {
+		return []smtp.GreylistEntry{}
+	}
+	return a.smtp.GreylistEntries()
+}
+
+// ResetGreylist clears the greylisting triplet table so every sender is
+// greylisted again on its next attempt
+func (a *App) ResetGreylist() {
+	if a.smtp != nil {
+		a.smtp.ResetGreylist()
+	}
+}
+
 func (a *App) GetVersion() string {
 	return version
 }
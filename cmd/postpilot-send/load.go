@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// loadConfig describes a load-generation run against an SMTP server
+type loadConfig struct {
+	// Address of the SMTP server in host:port form
+	Addr string
+	// Total number of messages to send
+	Messages int
+	// Number of concurrent connections, each reused across messages
+	Connections int
+	// Bounds for the size of the text body in bytes
+	MinSize int
+	MaxSize int
+	// Upper bounds for the number and size of attachments per message
+	MaxAttachments    int
+	MaxAttachmentSize int
+	// Seed for the random source used to vary messages
+	Seed int64
+}
+
+func (c loadConfig) validate() error {
+	switch {
+	case c.Messages < 1:
+		return errors.New("message count must be at least 1")
+	case c.Connections < 1:
+		return errors.New("connection count must be at least 1")
+	case c.MinSize < 0 || c.MaxSize < c.MinSize:
+		return errors.New("size bounds must satisfy 0 <= min-size <= max-size")
+	case c.MaxAttachments < 0 || c.MaxAttachmentSize < 0:
+		return errors.New("attachment limits must not be negative")
+	}
+	return nil
+}
+
+// loadReport collects the outcome of a load-generation run
+type loadReport struct {
+	config    loadConfig
+	elapsed   time.Duration
+	sent      int
+	failed    int
+	bytes     int64
+	dials     int
+	latencies []time.Duration
+	errors    map[string]int
+}
+
+// loadWorker owns a single SMTP connection that is reused between messages
+type loadWorker struct {
+	cfg    loadConfig
+	rand   *rand.Rand
+	client *smtp.Client
+}
+
+// runLoad sends cfg.Messages messages over cfg.Connections concurrent
+// connections and reports throughput, latency and errors
+func runLoad(cfg loadConfig) *loadReport {
+	report := &loadReport{
+		config: cfg,
+		errors: make(map[string]int),
+	}
+
+	jobs := make(chan int)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	for i := 0; i < cfg.Connections; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+
+			w := &loadWorker{
+				cfg:  cfg,
+				rand: rand.New(rand.NewSource(cfg.Seed + int64(id))),
+			}
+			defer w.close()
+
+			for n := range jobs {
+				msg := w.buildMessage(n)
+				dialed, latency, err := w.send(msg)
+
+				mu.Lock()
+				if dialed {
+					report.dials++
+				}
+				if err != nil {
+					report.failed++
+					report.errors[classifyError(err)]++
+				} else {
+					report.sent++
+					report.bytes += int64(len(msg))
+					report.latencies = append(report.latencies, latency)
+				}
+				mu.Unlock()
+			}
+		}(i)
+	}
+
+	for n := 0; n < cfg.Messages; n++ {
+		jobs <- n
+	}
+	close(jobs)
+	wg.Wait()
+	report.elapsed = time.Since(start)
+
+	return report
+}
+
+// stageError records which SMTP stage an error occurred in
+type stageError struct {
+	stage string
+	err   error
+}
+
+func (e *stageError) Error() string { return e.stage + ": " + e.err.Error() }
+func (e *stageError) Unwrap() error { return e.err }
+
+// send delivers msg over the worker's connection, dialing a new one if needed.
+// After a successful delivery the session is reset with RSET so the
+// connection can be reused for the next message.
+func (w *loadWorker) send(msg []byte) (dialed bool, latency time.Duration, err error) {
+	start := time.Now()
+
+	if w.client == nil {
+		c, err := smtp.Dial(w.cfg.Addr)
+		if err != nil {
+			return false, 0, &stageError{"connect", err}
+		}
+		if err := c.Hello("localhost"); err != nil {
+			c.Close()
+			return false, 0, &stageError{"ehlo", err}
+		}
+		w.client = c
+		dialed = true
+	}
+
+	if err := w.transaction(msg); err != nil {
+		// Protocol-level rejections leave the connection usable, anything
+		// else means we have to start over with a fresh connection
+		var tpErr *textproto.Error
+		if errors.As(err, &tpErr) && w.client.Reset() == nil {
+			return dialed, 0, err
+		}
+		w.close()
+		return dialed, 0, err
+	}
+
+	latency = time.Since(start)
+
+	if err := w.client.Reset(); err != nil {
+		w.close()
+	}
+
+	return dialed, latency, nil
+}
+
+func (w *loadWorker) transaction(msg []byte) error {
+	if err := w.client.Mail("load@example.com"); err != nil {
+		return &stageError{"mail", err}
+	}
+	if err := w.client.Rcpt("recipient@example.com"); err != nil {
+		return &stageError{"rcpt", err}
+	}
+
+	wc, err := w.client.Data()
+	if err != nil {
+		return &stageError{"data", err}
+	}
+	if _, err := wc.Write(msg); err != nil {
+		wc.Close()
+		return &stageError{"data", err}
+	}
+	if err := wc.Close(); err != nil {
+		return &stageError{"data", err}
+	}
+
+	return nil
+}
+
+func (w *loadWorker) close() {
+	if w.client != nil {
+		w.client.Close()
+		w.client = nil
+	}
+}
+
+// buildMessage generates message n with a randomly sized text body and
+// a random number of binary attachments
+func (w *loadWorker) buildMessage(n int) []byte {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: load@example.com\r\n")
+	fmt.Fprintf(&buf, "To: recipient@example.com\r\n")
+	fmt.Fprintf(&buf, "Subject: Load test message %d\r\n", n)
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <load-%d-%d@postpilot.local>\r\n", w.cfg.Seed, n)
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mw.Boundary())
+
+	size := w.cfg.MinSize
+	if w.cfg.MaxSize > w.cfg.MinSize {
+		size += w.rand.Intn(w.cfg.MaxSize - w.cfg.MinSize + 1)
+	}
+	text, _ := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"text/plain; charset=UTF-8"},
+	})
+	writeFiller(text, size)
+
+	attachments := 0
+	if w.cfg.MaxAttachments > 0 {
+		attachments = w.rand.Intn(w.cfg.MaxAttachments + 1)
+	}
+	for i := 0; i < attachments; i++ {
+		data := make([]byte, w.rand.Intn(w.cfg.MaxAttachmentSize+1))
+		w.rand.Read(data)
+
+		part, _ := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {"application/octet-stream"},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {fmt.Sprintf("attachment; filename=\"attachment-%d.bin\"", i)},
+		})
+		writeBase64Lines(part, data)
+	}
+
+	mw.Close()
+	return buf.Bytes()
+}
+
+// writeFiller writes size bytes of printable text in short lines
+func writeFiller(w io.Writer, size int) {
+	const line = "The quick brown fox jumps over the lazy dog while PostPilot takes notes.\r\n"
+	var b strings.Builder
+	for b.Len() < size {
+		b.WriteString(line)
+	}
+	io.WriteString(w, b.String()[:size])
+}
+
+// writeBase64Lines writes data as base64 wrapped at 76 columns
+func writeBase64Lines(w io.Writer, data []byte) {
+	const lineLen = 76
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > lineLen {
+		io.WriteString(w, encoded[:lineLen]+"\r\n")
+		encoded = encoded[lineLen:]
+	}
+	io.WriteString(w, encoded+"\r\n")
+}
+
+// classifyError maps a delivery error to a short, stable class name such
+// as "rcpt: 452" or "data: connection reset"
+func classifyError(err error) string {
+	stage := "unknown"
+	var se *stageError
+	if errors.As(err, &se) {
+		stage = se.stage
+	}
+
+	var tpErr *textproto.Error
+	var netErr net.Error
+	switch {
+	case errors.As(err, &tpErr):
+		return fmt.Sprintf("%s: %d", stage, tpErr.Code)
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return stage + ": connection refused"
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return stage + ": connection reset"
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return stage + ": connection closed"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return stage + ": timeout"
+	default:
+		return stage + ": other"
+	}
+}
+
+// percentile returns the q-th percentile of the sorted durations
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(q*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+func (r *loadReport) print() {
+	seconds := r.elapsed.Seconds()
+
+	fmt.Printf("Sent %d messages (%d ok, %d failed) in %s over %d connections (%d dials)\n",
+		r.sent+r.failed, r.sent, r.failed, r.elapsed.Round(time.Millisecond), r.config.Connections, r.dials)
+	if seconds > 0 {
+		fmt.Printf("Throughput: %.1f msg/s, %.2f MB/s\n",
+			float64(r.sent)/seconds, float64(r.bytes)/seconds/(1024*1024))
+	}
+
+	if len(r.latencies) > 0 {
+		sort.Slice(r.latencies, func(i, j int) bool { return r.latencies[i] < r.latencies[j] })
+		fmt.Printf("Latency: min %s, p50 %s, p90 %s, p95 %s, p99 %s, max %s\n",
+			r.latencies[0].Round(time.Microsecond),
+			percentile(r.latencies, 0.50).Round(time.Microsecond),
+			percentile(r.latencies, 0.90).Round(time.Microsecond),
+			percentile(r.latencies, 0.95).Round(time.Microsecond),
+			percentile(r.latencies, 0.99).Round(time.Microsecond),
+			r.latencies[len(r.latencies)-1].Round(time.Microsecond))
+	}
+
+	if len(r.errors) > 0 {
+		classes := make([]string, 0, len(r.errors))
+		for class := range r.errors {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+
+		fmt.Println("Errors:")
+		for _, class := range classes {
+			fmt.Printf("  %-28s %d\n", class, r.errors[class])
+		}
+	}
+}
@@ -1,18 +1,54 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"net"
 	"net/smtp"
+	"strconv"
 	"time"
 )
 
 func main() {
-	// For now, let's hardcode these values since we're not using the config package yet
-	host := "localhost"
-	port := 1025
+	host := flag.String("host", "localhost", "SMTP server host")
+	port := flag.Int("port", 1025, "SMTP server port")
+
+	load := flag.Bool("load", false, "Run in load-generation mode instead of sending a single email")
+	messages := flag.Int("n", 1000, "Total number of messages to send in load mode")
+	connections := flag.Int("c", 10, "Number of concurrent connections in load mode")
+	minSize := flag.Int("min-size", 1024, "Minimum body size in bytes in load mode")
+	maxSize := flag.Int("max-size", 64*1024, "Maximum body size in bytes in load mode")
+	maxAttachments := flag.Int("max-attachments", 3, "Maximum number of attachments per message in load mode")
+	attachmentSize := flag.Int("attachment-size", 32*1024, "Maximum attachment size in bytes in load mode")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "Random seed used to vary message sizes in load mode")
+	flag.Parse()
+
+	addr := net.JoinHostPort(*host, strconv.Itoa(*port))
+
+	if *load {
+		cfg := loadConfig{
+			Addr:              addr,
+			Messages:          *messages,
+			Connections:       *connections,
+			MinSize:           *minSize,
+			MaxSize:           *maxSize,
+			MaxAttachments:    *maxAttachments,
+			MaxAttachmentSize: *attachmentSize,
+			Seed:              *seed,
+		}
+		if err := cfg.validate(); err != nil {
+			log.Fatalf("Invalid load options: %v", err)
+		}
+		runLoad(cfg).print()
+		return
+	}
+
+	sendWelcome(addr)
+}
 
+// sendWelcome sends a single sample email to the server at addr
+func sendWelcome(addr string) {
 	// Prepare email
 	from := "test@example.com"
 	to := []string{"recipient@example.com"}
@@ -109,7 +145,6 @@ localhost:1025`
 		"--boundary--"
 
 	// Connect to the SMTP server
-	addr := fmt.Sprintf("%s:%d", host, port)
 	log.Printf("Connecting to SMTP server at %s", addr)
 
 	// Try to establish a connection first
@@ -0,0 +1,121 @@
+import React from 'react';
+import { XMarkIcon } from '@heroicons/react/24/outline';
+import { GetActivity } from '../../../wailsjs/go/main/App';
+import { EventsOn } from '../../../wailsjs/runtime/runtime';
+import { smtp } from '../../../wailsjs/go/models';
+
+interface ActivityModalProps {
+  isOpen: boolean;
+  onClose: () => void;
+}
+
+const outcomes = ['', 'delivered', 'rejected', 'timeout', 'aborted', 'closed', 'open'];
+
+const outcomeStyles: Record<string, string> = {
+  delivered: 'bg-green-100 text-green-800 dark:bg-green-900 dark:text-green-200',
+  rejected: 'bg-red-100 text-red-800 dark:bg-red-900 dark:text-red-200',
+  timeout: 'bg-yellow-100 text-yellow-800 dark:bg-yellow-900 dark:text-yellow-200',
+  aborted: 'bg-orange-100 text-orange-800 dark:bg-orange-900 dark:text-orange-200',
+  closed: 'bg-gray-100 text-gray-800 dark:bg-gray-700 dark:text-gray-200',
+  open: 'bg-blue-100 text-blue-800 dark:bg-blue-900 dark:text-blue-200',
+};
+
+const formatBytes = (bytes: number) => {
+  if (bytes < 1024) return `${bytes} B`;
+  if (bytes < 1024 * 1024) return `${(bytes / 1024).toFixed(1)} KB`;
+  return `${(bytes / (1024 * 1024)).toFixed(1)} MB`;
+};
+
+const ActivityModal: React.FC<ActivityModalProps> = ({ isOpen, onClose }) => {
+  const [entries, setEntries] = React.useState<smtp.SessionLogEntry[]>([]);
+  const [outcome, setOutcome] = React.useState('');
+
+  React.useEffect(() => {
+    if (!isOpen) return;
+
+    GetActivity(smtp.SessionLogFilter.createFrom({ limit: 500 }))
+      .then(setEntries)
+      .catch((error) => console.error('Failed to load activity:', error));
+
+    // Finished sessions replace their open entry or are added to the top
+    const unsubscribe = EventsOn('activity:session', (entry: smtp.SessionLogEntry) => {
+      setEntries(prev => [entry, ...prev.filter(e => e.id !== entry.id)]);
+    });
+
+    return () => {
+      unsubscribe();
+    };
+  }, [isOpen]);
+
+  if (!isOpen) return null;
+
+  const visible = entries.filter(e => !outcome || e.outcome === outcome);
+
+  return (
+    <div className="fixed inset-0 bg-black bg-opacity-25 flex items-center justify-center p-4 z-50">
+      <div className="bg-white dark:bg-gray-800 rounded-lg shadow-xl w-full max-w-4xl max-h-[90vh] flex flex-col">
+        <div className="flex justify-between items-center p-4 border-b border-gray-200 dark:border-gray-700">
+          <h2 className="text-lg font-bold text-gray-900 dark:text-white">Activity</h2>
+          <div className="flex items-center gap-3">
+            <select
+              value={outcome}
+              onChange={(e) => setOutcome(e.target.value)}
+              className="px-2 py-1 text-sm border border-gray-200 dark:border-gray-700 rounded-md bg-white dark:bg-gray-800 text-gray-900 dark:text-gray-100"
+            >
+              {outcomes.map(o => (
+                <option key={o} value={o}>{o || 'All outcomes'}</option>
+              ))}
+            </select>
+            <button
+              onClick={onClose}
+              className="p-1 text-gray-500 hover:text-gray-700 dark:text-gray-400 dark:hover:text-gray-300 rounded-md hover:bg-gray-100 dark:hover:bg-gray-700"
+            >
+              <XMarkIcon className="w-5 h-5" />
+            </button>
+          </div>
+        </div>
+
+        <div className="flex-1 overflow-auto">
+          {visible.length === 0 ? (
+            <p className="p-6 text-center text-gray-500 dark:text-gray-400">No sessions yet</p>
+          ) : (
+            <table className="w-full text-sm">
+              <thead className="text-left text-gray-500 dark:text-gray-400 border-b border-gray-200 dark:border-gray-700">
+                <tr>
+                  <th className="px-4 py-2 font-medium">Time</th>
+                  <th className="px-4 py-2 font-medium">Remote</th>
+                  <th className="px-4 py-2 font-medium">Outcome</th>
+                  <th className="px-4 py-2 font-medium">Reason</th>
+                  <th className="px-4 py-2 font-medium text-right">Duration</th>
+                  <th className="px-4 py-2 font-medium text-right">In / Out</th>
+                </tr>
+              </thead>
+              <tbody className="text-gray-900 dark:text-gray-100">
+                {visible.map(entry => (
+                  <tr key={entry.id} className="border-b border-gray-100 dark:border-gray-700">
+                    <td className="px-4 py-2 whitespace-nowrap">
+                      {new Date(entry.startedAt).toLocaleTimeString()}
+                    </td>
+                    <td className="px-4 py-2 font-mono whitespace-nowrap">{entry.remoteAddr}</td>
+                    <td className="px-4 py-2">
+                      <span className={`px-2 py-0.5 rounded-full text-xs ${outcomeStyles[entry.outcome] ?? outcomeStyles.closed}`}>
+                        {entry.outcome}
+                      </span>
+                    </td>
+                    <td className="px-4 py-2 text-gray-600 dark:text-gray-300 break-all">{entry.reason}</td>
+                    <td className="px-4 py-2 text-right whitespace-nowrap">{entry.durationMs} ms</td>
+                    <td className="px-4 py-2 text-right whitespace-nowrap">
+                      {formatBytes(entry.bytesIn)} / {formatBytes(entry.bytesOut)}
+                    </td>
+                  </tr>
+                ))}
+              </tbody>
+            </table>
+          )}
+        </div>
+      </div>
+    </div>
+  );
+};
+
+export default ActivityModal;
@@ -3,7 +3,8 @@ import { Email } from '../../types/email';
 import EmailListItem from './EmailListItem';
 import { GetEmails, ClearEmails } from '../../../wailsjs/go/main/App';
 import SettingsModal from '../Settings/SettingsModal';
-import { Cog6ToothIcon, TrashIcon } from '@heroicons/react/24/outline';
+import ActivityModal from '../Activity/ActivityModal';
+import { Cog6ToothIcon, QueueListIcon, TrashIcon } from '@heroicons/react/24/outline';
 import { useSettings } from '../../hooks/useSettings';
 import toast from 'react-hot-toast';
 import { EventsOn } from '../../../wailsjs/runtime/runtime';
@@ -18,6 +19,7 @@ interface EmailListProps {
 const EmailList: React.FC<EmailListProps> = ({ emails, onSelectEmail, selectedEmail }) => {
     const [searchTerm, setSearchTerm] = React.useState('');
     const [isSettingsOpen, setIsSettingsOpen] = React.useState(false);
+    const [isActivityOpen, setIsActivityOpen] = React.useState(false);
     const { settings } = useSettings();
 
     const [showClearConfirm, setShowClearConfirm] = React.useState(false);
@@ -91,13 +93,22 @@ const EmailList: React.FC<EmailListProps> = ({ emails, onSelectEmail, selectedEm
                         </button>
                     )}
                 </div>
-                <button
-                    onClick={() => setIsSettingsOpen(true)}
-                    className="p-2 text-gray-500 hover:text-gray-700 dark:hover:text-gray-300 rounded-md hover:bg-gray-100 dark:hover:bg-gray-700"
-                    title="Settings"
-                >
-                    <Cog6ToothIcon className="w-6 h-6" />
-                </button>
+                <div className="flex items-center">
+                    <button
+                        onClick={() => setIsActivityOpen(true)}
+                        className="p-2 text-gray-500 hover:text-gray-700 dark:hover:text-gray-300 rounded-md hover:bg-gray-100 dark:hover:bg-gray-700"
+                        title="Activity"
+                    >
+                        <QueueListIcon className="w-6 h-6" />
+                    </button>
+                    <button
+                        onClick={() => setIsSettingsOpen(true)}
+                        className="p-2 text-gray-500 hover:text-gray-700 dark:hover:text-gray-300 rounded-md hover:bg-gray-100 dark:hover:bg-gray-700"
+                        title="Settings"
+                    >
+                        <Cog6ToothIcon className="w-6 h-6" />
+                    </button>
+                </div>
             </div>
 
             {showClearConfirm && (
@@ -131,6 +142,11 @@ const EmailList: React.FC<EmailListProps> = ({ emails, onSelectEmail, selectedEm
                 isOpen={isSettingsOpen}
                 onClose={() => setIsSettingsOpen(false)}
             />
+
+            <ActivityModal
+                isOpen={isActivityOpen}
+                onClose={() => setIsActivityOpen(false)}
+            />
         </div>
     );
 };
@@ -0,0 +1,51 @@
+import React from 'react';
+import { Email } from '../../types/email';
+
+interface AttachmentsBarProps {
+  email: Email;
+  onOpen: (child: Email) => void;
+}
+
+const formatBytes = (bytes: number) => {
+  if (bytes < 1024) return `${bytes} B`;
+  if (bytes < 1024 * 1024) return `${(bytes / 1024).toFixed(1)} KB`;
+  return `${(bytes / (1024 * 1024)).toFixed(1)} MB`;
+};
+
+const isMessage = (contentType: string) => /^message\/(rfc822|global)$/i.test(contentType);
+
+// Lists the attachments of an email. Attached messages open like any other
+// email; the rest are only listed.
+const AttachmentsBar: React.FC<AttachmentsBarProps> = ({ email, onOpen }) => {
+  const attached = email.attached || [];
+  const files = (email.attachments || []).filter(a => !isMessage(a.contentType));
+  if (!attached.length && !files.length) {
+    return null;
+  }
+
+  return (
+    <div className="px-6 py-2 flex flex-wrap gap-2 text-sm border-b border-gray-200 dark:border-gray-700">
+      {attached.map(child => (
+        <button
+          key={child.id}
+          onClick={() => onOpen(child)}
+          className="px-2 py-1 rounded bg-blue-50 text-blue-800 hover:bg-blue-100 dark:bg-blue-900/40 dark:text-blue-200 dark:hover:bg-blue-900/60"
+          title={child.from}
+        >
+          ✉ {child.subject || '(no subject)'}
+        </button>
+      ))}
+      {files.map((file, i) => (
+        <span
+          key={i}
+          className="px-2 py-1 rounded bg-gray-100 text-gray-700 dark:bg-gray-800 dark:text-gray-300"
+          title={file.contentType}
+        >
+          {file.filename || file.contentType} <span className="text-xs opacity-70">{formatBytes(file.size)}</span>
+        </span>
+      ))}
+    </div>
+  );
+};
+
+export default AttachmentsBar;
@@ -0,0 +1,67 @@
+import React from 'react';
+import { AuthResults } from '../../types/email';
+import { EvaluateEmailAuth } from '../../../wailsjs/go/main/App';
+
+interface AuthBadgesProps {
+  emailId: string;
+  auth: AuthResults;
+}
+
+const resultStyles: Record<string, string> = {
+  pass: 'bg-green-100 text-green-800 dark:bg-green-900 dark:text-green-200',
+  fail: 'bg-red-100 text-red-800 dark:bg-red-900 dark:text-red-200',
+  softfail: 'bg-yellow-100 text-yellow-800 dark:bg-yellow-900 dark:text-yellow-200',
+  permerror: 'bg-red-100 text-red-800 dark:bg-red-900 dark:text-red-200',
+  temperror: 'bg-yellow-100 text-yellow-800 dark:bg-yellow-900 dark:text-yellow-200',
+  none: 'bg-gray-100 text-gray-700 dark:bg-gray-700 dark:text-gray-200',
+  neutral: 'bg-gray-100 text-gray-700 dark:bg-gray-700 dark:text-gray-200',
+};
+
+const AuthBadges: React.FC<AuthBadgesProps> = ({ emailId, auth }) => {
+  const [results, setResults] = React.useState(auth);
+
+  React.useEffect(() => {
+    setResults(auth);
+  }, [auth]);
+
+  // Clicking re-evaluates against the current zone settings
+  const reevaluate = () => {
+    EvaluateEmailAuth(emailId)
+      .then(setResults)
+      .catch((error) => console.error('Failed to evaluate SPF and DMARC:', error));
+  };
+
+  const { spf, dmarc } = results;
+  const spfTitle = [spf.domain && `Domain: ${spf.domain}`, spf.record && `Record: ${spf.record}`, spf.reason]
+    .filter(Boolean).join('\n');
+  const dmarcTitle = [
+    dmarc.record && `Record: ${dmarc.record}`,
+    `SPF aligned: ${dmarc.spfAligned ? 'yes' : 'no'}`,
+    `DKIM aligned: ${dmarc.dkimAligned ? `yes (${dmarc.dkimDomain})` : 'no'}`,
+    dmarc.reason,
+  ].filter(Boolean).join('\n');
+
+  return (
+    <>
+      <button
+        type="button"
+        onClick={reevaluate}
+        title={spfTitle}
+        className={`px-2 py-0.5 rounded-full text-xs font-medium whitespace-nowrap ${resultStyles[spf.result] ?? resultStyles.none}`}
+      >
+        SPF {spf.result}
+      </button>
+      <button
+        type="button"
+        onClick={reevaluate}
+        title={dmarcTitle}
+        className={`px-2 py-0.5 rounded-full text-xs font-medium whitespace-nowrap ${resultStyles[dmarc.result] ?? resultStyles.none}`}
+      >
+        DMARC {dmarc.result}
+        {dmarc.result === 'fail' && ` (${dmarc.disposition})`}
+      </button>
+    </>
+  );
+};
+
+export default AuthBadges;
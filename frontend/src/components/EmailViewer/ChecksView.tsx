@@ -0,0 +1,36 @@
+import React from 'react';
+import { Email } from '../../types/email';
+import SpamSection from './checks/SpamSection';
+import SecuritySection from './checks/SecuritySection';
+import ExtractedSection from './checks/ExtractedSection';
+import CompatSection from './checks/CompatSection';
+import AccessibilitySection from './checks/AccessibilitySection';
+import ParitySection from './checks/ParitySection';
+import TrackingSection from './checks/TrackingSection';
+import CalendarSection from './checks/CalendarSection';
+import SizeSection from './checks/SizeSection';
+import LinksSection from './checks/LinksSection';
+
+interface ChecksViewProps {
+  email: Email;
+}
+
+// ChecksView collects the offline analyses run on a captured email
+const ChecksView: React.FC<ChecksViewProps> = ({ email }) => {
+  return (
+    <div className="p-6 space-y-8">
+      {email.extracted && email.extracted.matches.length > 0 && <ExtractedSection values={email.extracted} />}
+      {email.security?.signature && <SecuritySection signature={email.security.signature} />}
+      {email.spam && <SpamSection report={email.spam} />}
+      {email.size && <SizeSection report={email.size} />}
+      {email.links && email.links.length > 0 && <LinksSection emailId={email.id} links={email.links} />}
+      {email.html && <CompatSection emailId={email.id} />}
+      {email.accessibility && <AccessibilitySection report={email.accessibility} />}
+      {email.parity && <ParitySection report={email.parity} />}
+      {email.tracking && <TrackingSection report={email.tracking} />}
+      {email.calendars && email.calendars.length > 0 && <CalendarSection emailId={email.id} calendars={email.calendars} />}
+    </div>
+  );
+};
+
+export default ChecksView;
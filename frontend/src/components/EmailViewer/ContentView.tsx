@@ -1,23 +1,54 @@
 import React from 'react';
-import { Email } from '../../types/email';
+import { Email, PreviewResult } from '../../types/email';
 import { BrowserOpenURL } from '../../../wailsjs/runtime/runtime';
+import { PreviewEmail } from '../../../wailsjs/go/main/App';
 
 interface ContentViewProps {
   email: Email;
 }
 
 const ContentView: React.FC<ContentViewProps> = ({ email }) => {
-  const handleLinkClick = (e: React.MouseEvent) => {
-    const target = e.target as HTMLElement;
-    const link = target.closest('a');
-    
-    if (link) {
-      e.preventDefault();
-      const href = link.getAttribute('href');
-      if (href) {
-        BrowserOpenURL(href);
-      }
+  const [loadRemote, setLoadRemote] = React.useState(false);
+  const [removeTracking, setRemoveTracking] = React.useState(false);
+  const [preview, setPreview] = React.useState<PreviewResult | null>(null);
+  const [height, setHeight] = React.useState(0);
+  const frameRef = React.useRef<HTMLIFrameElement>(null);
+
+  React.useEffect(() => {
+    setLoadRemote(false);
+    setRemoveTracking(false);
+  }, [email.id]);
+
+  React.useEffect(() => {
+    PreviewEmail(email.id, { loadRemote, removeTracking })
+      .then(setPreview)
+      .catch((error) => console.error('Failed to prepare preview:', error));
+  }, [email.id, loadRemote, removeTracking]);
+
+  const tracked = (email.tracking?.pixels.length ?? 0) + (email.tracking?.links.length ?? 0);
+
+  // The preview is sandboxed without scripts, so links are opened and the
+  // frame is sized from here
+  const handleFrameLoad = () => {
+    const doc = frameRef.current?.contentDocument;
+    if (!doc) {
+      return;
     }
+
+    const resize = () => setHeight(doc.documentElement.scrollHeight);
+    resize();
+    doc.querySelectorAll('img').forEach(img => img.addEventListener('load', resize));
+
+    doc.addEventListener('click', (e) => {
+      const link = (e.target as HTMLElement).closest('a');
+      if (link) {
+        e.preventDefault();
+        const href = link.getAttribute('href');
+        if (href) {
+          BrowserOpenURL(href);
+        }
+      }
+    });
   };
 
   return (
@@ -47,13 +78,54 @@ const ContentView: React.FC<ContentViewProps> = ({ email }) => {
         </div>
         <div className="border-b border-gray-200 dark:border-gray-700"></div>
       </div>
-      <div 
-        className="prose dark:prose-invert max-w-none px-6 py-4"
-        dangerouslySetInnerHTML={{ __html: email.html }}
-        onClick={handleLinkClick}
-      />
+      {tracked > 0 && (
+        <div className="flex items-center justify-between px-6 py-2 text-sm bg-gray-50 dark:bg-gray-800 text-gray-600 dark:text-gray-300 border-b border-gray-200 dark:border-gray-700">
+          <span>
+            {removeTracking
+              ? 'Showing the email without tracking pixels and click tracking'
+              : `${tracked} tracking pixels and links found`}
+          </span>
+          <button
+            onClick={() => setRemoveTracking(!removeTracking)}
+            className="text-blue-600 dark:text-blue-400 hover:underline"
+          >
+            {removeTracking ? 'Show original' : 'Remove tracking'}
+          </button>
+        </div>
+      )}
+      {preview && preview.remote.length > 0 && (
+        <div className="flex items-center justify-between px-6 py-2 text-sm bg-gray-50 dark:bg-gray-800 text-gray-600 dark:text-gray-300 border-b border-gray-200 dark:border-gray-700">
+          <span>
+            {preview.remoteLoaded
+              ? `Showing ${preview.remote.length} remote images through the local proxy`
+              : `${preview.remote.length} remote images blocked`}
+          </span>
+          <button
+            onClick={() => setLoadRemote(!loadRemote)}
+            className="text-blue-600 dark:text-blue-400 hover:underline"
+          >
+            {preview.remoteLoaded ? 'Block remote content' : 'Load remote content'}
+          </button>
+        </div>
+      )}
+      {preview && preview.removed.length > 0 && (
+        <div className="px-6 py-2 text-xs text-gray-500 dark:text-gray-400 border-b border-gray-200 dark:border-gray-700">
+          Removed from preview: {preview.removed.join(', ')}
+        </div>
+      )}
+      {preview && (
+        <iframe
+          ref={frameRef}
+          title="Email preview"
+          sandbox="allow-same-origin"
+          srcDoc={preview.html}
+          onLoad={handleFrameLoad}
+          className="w-full bg-white"
+          style={{ height: height || 200 }}
+        />
+      )}
     </div>
   );
 };
 
-export default ContentView; 
\ No newline at end of file
+export default ContentView;
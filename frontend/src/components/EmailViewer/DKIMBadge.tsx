@@ -0,0 +1,40 @@
+import React from 'react';
+import { DKIMResult } from '../../types/email';
+
+interface DKIMBadgeProps {
+  results: DKIMResult[];
+  onClick?: () => void;
+}
+
+const statusStyles: Record<string, string> = {
+  pass: 'bg-green-100 text-green-800 dark:bg-green-900 dark:text-green-200',
+  fail: 'bg-red-100 text-red-800 dark:bg-red-900 dark:text-red-200',
+  permerror: 'bg-red-100 text-red-800 dark:bg-red-900 dark:text-red-200',
+  temperror: 'bg-yellow-100 text-yellow-800 dark:bg-yellow-900 dark:text-yellow-200',
+  none: 'bg-gray-100 text-gray-700 dark:bg-gray-700 dark:text-gray-200',
+};
+
+const DKIMBadge: React.FC<DKIMBadgeProps> = ({ results, onClick }) => {
+  // A single broken signature is what we want to notice, so it wins over passes
+  const status = results.length === 0
+    ? 'none'
+    : results.find(r => r.status !== 'pass')?.status ?? 'pass';
+
+  const details = results.map(r => {
+    const line = `d=${r.domain} s=${r.selector} ${r.algorithm} ${r.headerCanonicalization}/${r.bodyCanonicalization}: ${r.status}`;
+    return r.reason ? `${line} (${r.reason})` : line;
+  });
+
+  return (
+    <button
+      type="button"
+      onClick={onClick}
+      title={details.length ? details.join('\n') : 'No DKIM-Signature header'}
+      className={`px-2 py-0.5 rounded-full text-xs font-medium whitespace-nowrap ${statusStyles[status] ?? statusStyles.none}`}
+    >
+      DKIM {status}
+    </button>
+  );
+};
+
+export default DKIMBadge;
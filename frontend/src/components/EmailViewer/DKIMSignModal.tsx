@@ -0,0 +1,84 @@
+import React from 'react';
+import { XMarkIcon } from '@heroicons/react/24/outline';
+import { SignEmailDKIM } from '../../../wailsjs/go/main/App';
+import { smtp } from '../../../wailsjs/go/models';
+
+interface DKIMSignModalProps {
+  emailId: string;
+  isOpen: boolean;
+  onClose: () => void;
+}
+
+const canonicalizations = ['relaxed/relaxed', 'relaxed/simple', 'simple/relaxed', 'simple/simple'];
+
+const DKIMSignModal: React.FC<DKIMSignModalProps> = ({ emailId, isOpen, onClose }) => {
+  const [canonicalization, setCanonicalization] = React.useState('');
+  const [signature, setSignature] = React.useState<smtp.DKIMSignature | null>(null);
+  const [error, setError] = React.useState('');
+
+  React.useEffect(() => {
+    if (!isOpen) return;
+
+    setError('');
+    SignEmailDKIM(emailId, canonicalization)
+      .then(setSignature)
+      .catch((err) => {
+        setSignature(null);
+        setError(String(err));
+      });
+  }, [isOpen, emailId, canonicalization]);
+
+  if (!isOpen) return null;
+
+  return (
+    <div className="fixed inset-0 bg-black bg-opacity-25 flex items-center justify-center p-4 z-50">
+      <div className="bg-white dark:bg-gray-800 rounded-lg shadow-xl w-full max-w-4xl max-h-[90vh] flex flex-col">
+        <div className="flex justify-between items-center p-4 border-b border-gray-200 dark:border-gray-700">
+          <h2 className="text-lg font-bold text-gray-900 dark:text-white">DKIM signing preview</h2>
+          <div className="flex items-center gap-3">
+            <select
+              value={canonicalization}
+              onChange={(e) => setCanonicalization(e.target.value)}
+              className="px-2 py-1 text-sm border border-gray-200 dark:border-gray-700 rounded-md bg-white dark:bg-gray-800 text-gray-900 dark:text-gray-100"
+            >
+              <option value="">Configured canonicalization</option>
+              {canonicalizations.map(c => (
+                <option key={c} value={c}>{c}</option>
+              ))}
+            </select>
+            <button
+              onClick={onClose}
+              className="p-1 text-gray-500 hover:text-gray-700 dark:text-gray-400 dark:hover:text-gray-300 rounded-md hover:bg-gray-100 dark:hover:bg-gray-700"
+            >
+              <XMarkIcon className="w-5 h-5" />
+            </button>
+          </div>
+        </div>
+
+        <div className="flex-1 overflow-auto p-4 space-y-4 text-sm text-gray-900 dark:text-gray-100">
+          {error && <p className="text-red-600 dark:text-red-400">{error}</p>}
+          {signature && (
+            <>
+              <div>
+                <h3 className="font-medium mb-1">Covered headers ({signature.canonicalization}), in hash order</h3>
+                <ol className="list-decimal list-inside font-mono text-xs space-y-0.5 break-all">
+                  {signature.coveredHeaders.map((h, i) => (
+                    <li key={i}>{h}</li>
+                  ))}
+                </ol>
+              </div>
+              <div>
+                <h3 className="font-medium mb-1">Signed source</h3>
+                <pre className="whitespace-pre-wrap font-mono text-xs bg-gray-50 dark:bg-gray-900 rounded-md p-3">
+                  {signature.signed}
+                </pre>
+              </div>
+            </>
+          )}
+        </div>
+      </div>
+    </div>
+  );
+};
+
+export default DKIMSignModal;
@@ -5,6 +5,14 @@ import ContentView from './ContentView';
 import HeadersPanel from './HeadersPanel';
 import TextView from './TextView';
 import RawView from './RawView';
+import TranscriptView from './TranscriptView';
+import ReportBanner from './ReportBanner';
+import SecurityBanner from './SecurityBanner';
+import AttachmentsBar from './AttachmentsBar';
+import DKIMBadge from './DKIMBadge';
+import DKIMSignModal from './DKIMSignModal';
+import AuthBadges from './AuthBadges';
+import ChecksView from './ChecksView';
 import { Settings } from '../../types/settings';
 import { useSettings } from '../../hooks/useSettings';
 import { useClipboard } from '../../hooks/useClipboard';
@@ -13,12 +21,21 @@ interface EmailViewerProps {
   email: Email | null;
 }
 
-const EmailViewer: React.FC<EmailViewerProps> = ({ email }) => {
+const EmailViewer: React.FC<EmailViewerProps> = ({ email: selected }) => {
   const [activeTab, setActiveTab] = React.useState('content');
+  // Attached messages opened from the selected email, innermost last
+  const [opened, setOpened] = React.useState<Email[]>([]);
   const [isOpen, setIsOpen] = React.useState(false);
+  const [isSignOpen, setIsSignOpen] = React.useState(false);
   const { settings } = useSettings();
   const { copyToClipboard, copied } = useClipboard({ timeout: 2000 });
 
+  useEffect(() => {
+    setOpened([]);
+  }, [selected?.id]);
+
+  const email = opened.length ? opened[opened.length - 1] : selected;
+
   // Reset to available content when email changes
   useEffect(() => {
     if (email) {
@@ -109,13 +126,26 @@ const EmailViewer: React.FC<EmailViewerProps> = ({ email }) => {
       id: 'raw', 
       label: 'Raw' 
     },
+    { 
+      id: 'transcript', 
+      label: 'Transcript',
+      disabled: !email.sessionId
+    },
+    {
+      id: 'checks',
+      label: 'Checks',
+    },
   ];
 
   return (
     <div className="h-full flex">
       <div className="flex-1 flex flex-col">
         <div className="flex justify-between items-center border-b border-gray-200 dark:border-gray-700">
-          <h1 className="text-lg font-bold px-6 py-4 dark:text-white">{email.subject}</h1>
+          <div className="flex items-center gap-3 px-6 py-4">
+            <h1 className="text-lg font-bold dark:text-white">{email.subject}</h1>
+            {email.dkim && <DKIMBadge results={email.dkim} onClick={() => setIsSignOpen(true)} />}
+            {email.auth && <AuthBadges emailId={email.id} auth={email.auth} />}
+          </div>
           <TabPanel
             tabs={tabs}
             activeTab={activeTab}
@@ -127,10 +157,26 @@ const EmailViewer: React.FC<EmailViewerProps> = ({ email }) => {
             }}
           />
         </div>
+        {opened.length > 0 && (
+          <div className="px-6 py-2 text-sm border-b border-gray-200 dark:border-gray-700 bg-gray-50 dark:bg-gray-800 dark:text-gray-300">
+            <button
+              onClick={() => setOpened(opened.slice(0, -1))}
+              className="text-blue-600 hover:underline dark:text-blue-400"
+            >
+              ← Back to {opened.length > 1 ? opened[opened.length - 2].subject : selected?.subject || 'parent'}
+            </button>
+            <span className="ml-2 text-gray-500 dark:text-gray-400">Attached message</span>
+          </div>
+        )}
+        {email.security && <SecurityBanner security={email.security} />}
+        {email.report && <ReportBanner report={email.report} />}
+        <AttachmentsBar email={email} onOpen={child => setOpened([...opened, child])} />
         <div className="flex-1 overflow-auto dark:bg-gray-900">
           {activeTab === 'content' && email.html && <ContentView email={email} />}
           {activeTab === 'text' && email.body && <TextView email={email} />}
           {activeTab === 'raw' && <RawView email={email} />}
+          {activeTab === 'transcript' && email.sessionId && <TranscriptView email={email} />}
+          {activeTab === 'checks' && <ChecksView email={email} />}
         </div>
       </div>
       
@@ -143,6 +189,8 @@ const EmailViewer: React.FC<EmailViewerProps> = ({ email }) => {
           {<HeadersPanel email={email} onClose={() => setIsOpen(false)} />}
         </div>
       </div>
+
+      <DKIMSignModal emailId={email.id} isOpen={isSignOpen} onClose={() => setIsSignOpen(false)} />
     </div>
   );
 };
@@ -19,6 +19,7 @@ const HeadersPanel: React.FC<HeadersPanelProps> = ({ email, onClose }) => {
     email.replyTo ? { key: 'Reply-To', value: email.replyTo } : null,
     { key: 'Subject', value: email.subject },
     { key: 'Date', value: new Date(email.timestamp).toUTCString() },
+    email.extensions?.length ? { key: 'ESMTP Extensions', value: email.extensions.join(', ') } : null,
     // Add any additional headers
     ...(email.headers ? Object.entries(email.headers).map(([key, values]) => ({
       key,
@@ -0,0 +1,60 @@
+import React from 'react';
+import { EmailReport } from '../../types/email';
+
+interface ReportBannerProps {
+  report: EmailReport;
+}
+
+const tones = {
+  red: 'bg-red-50 text-red-800 dark:bg-red-900/40 dark:text-red-200',
+  yellow: 'bg-yellow-50 text-yellow-800 dark:bg-yellow-900/40 dark:text-yellow-200',
+  blue: 'bg-blue-50 text-blue-800 dark:bg-blue-900/40 dark:text-blue-200',
+};
+
+// Summarizes a parsed DSN, MDN or ARF report in one line per finding
+const describe = (report: EmailReport): { tone: keyof typeof tones; lines: string[] } => {
+  switch (report.type) {
+    case 'delivery-status': {
+      const lines = report.recipients.map(r => {
+        const kind = r.bounce === 'hard' ? 'Hard bounce' : r.bounce === 'soft' ? 'Soft bounce' : `Delivery ${r.action}`;
+        const detail = [r.status, r.diagnosticCode].filter(Boolean).join(' – ');
+        return `${kind} for ${r.finalRecipient}${detail ? `: ${detail}` : ''}`;
+      });
+      const hard = report.recipients.some(r => r.bounce === 'hard');
+      return { tone: hard ? 'red' : 'yellow', lines: lines.length ? lines : ['Delivery status notification'] };
+    }
+    case 'disposition-notification': {
+      const d = report.disposition;
+      return {
+        tone: 'blue',
+        lines: [`Read receipt: ${d?.type || 'unknown disposition'}${d?.finalRecipient ? ` by ${d.finalRecipient}` : ''}`],
+      };
+    }
+    case 'feedback-report': {
+      const f = report.feedback;
+      const recipients = f?.originalRcptTo.length ? ` from ${f.originalRcptTo.join(', ')}` : '';
+      return { tone: 'red', lines: [`Feedback report (${f?.feedbackType || 'unknown'})${recipients}`] };
+    }
+    default:
+      return { tone: 'blue', lines: [`Report of type ${report.type}`] };
+  }
+};
+
+const ReportBanner: React.FC<ReportBannerProps> = ({ report }) => {
+  const { tone, lines } = describe(report);
+
+  return (
+    <div className={`px-6 py-3 text-sm border-b border-gray-200 dark:border-gray-700 ${tones[tone]}`}>
+      {lines.map((line, i) => (
+        <div key={i} className="font-medium">{line}</div>
+      ))}
+      {(report.originalMessageId || report.originalSubject) && (
+        <div className="mt-1 text-xs opacity-80">
+          Original message: {[report.originalSubject && `"${report.originalSubject}"`, report.originalMessageId].filter(Boolean).join(' ')}
+        </div>
+      )}
+    </div>
+  );
+};
+
+export default ReportBanner;
@@ -0,0 +1,76 @@
+import React from 'react';
+import { Security } from '../../types/email';
+
+interface SecurityBannerProps {
+  security: Security;
+}
+
+const tones = {
+  red: 'bg-red-50 text-red-800 dark:bg-red-900/40 dark:text-red-200',
+  yellow: 'bg-yellow-50 text-yellow-800 dark:bg-yellow-900/40 dark:text-yellow-200',
+  green: 'bg-green-50 text-green-800 dark:bg-green-900/40 dark:text-green-200',
+  gray: 'bg-gray-50 text-gray-800 dark:bg-gray-800 dark:text-gray-200',
+};
+
+const protocols = { smime: 'S/MIME', pgp: 'PGP', unknown: 'Unknown protocol' };
+
+const decryptions: Record<string, string> = {
+  decrypted: 'Decrypted with the test key',
+  'no-key': 'Encrypted, no test key configured',
+  failed: 'Encrypted, decryption failed',
+  unsupported: 'Encrypted in a form that is not supported',
+};
+
+// Summarizes how an email is signed or encrypted in one line per layer
+const describe = (security: Security): { tone: keyof typeof tones; lines: string[] } => {
+  const protocol = protocols[security.protocol];
+  const lines: string[] = [];
+  let tone: keyof typeof tones = 'gray';
+
+  if (security.encrypted) {
+    lines.push(`${protocol}: ${decryptions[security.decryption] || 'Encrypted'}`);
+    tone = security.decryption === 'decrypted' ? 'green' : 'yellow';
+  }
+
+  const sig = security.signature;
+  if (sig) {
+    const signer = sig.signer ? ` by ${sig.signer.emails[0] || sig.signer.subject}` : '';
+    switch (sig.status) {
+      case 'valid':
+        lines.push(`${protocol}: Valid signature${signer}`);
+        if (tone === 'gray') tone = 'green';
+        break;
+      case 'untrusted':
+        lines.push(`${protocol}: Signature matches${signer}, but the signer is not trusted: ${sig.reason}`);
+        tone = 'yellow';
+        break;
+      case 'invalid':
+        lines.push(`${protocol}: Invalid signature${signer}: ${sig.reason}`);
+        tone = 'red';
+        break;
+      default:
+        lines.push(`${protocol}: Signed, not verified: ${sig.reason}`);
+    }
+  } else if (security.signed) {
+    lines.push(`${protocol}: Signed, not verified`);
+  }
+
+  return { tone, lines };
+};
+
+const SecurityBanner: React.FC<SecurityBannerProps> = ({ security }) => {
+  const { tone, lines } = describe(security);
+
+  return (
+    <div className={`px-6 py-3 text-sm border-b border-gray-200 dark:border-gray-700 ${tones[tone]}`}>
+      {lines.map((line, i) => (
+        <div key={i} className="font-medium">{line}</div>
+      ))}
+      {security.notes.map((note, i) => (
+        <div key={i} className="mt-1 text-xs opacity-80">{note}</div>
+      ))}
+    </div>
+  );
+};
+
+export default SecurityBanner;
@@ -0,0 +1,67 @@
+import React, { useEffect, useState } from 'react';
+import { Email } from '../../types/email';
+import { GetEmailTranscript } from '../../../wailsjs/go/main/App';
+import { smtp } from '../../../wailsjs/go/models';
+
+interface TranscriptViewProps {
+  email: Email;
+}
+
+const directionStyles: Record<string, string> = {
+  client: 'text-blue-700 dark:text-blue-300',
+  server: 'text-gray-800 dark:text-gray-200',
+  event: 'text-amber-600 dark:text-amber-400 italic',
+};
+
+const directionPrefix: Record<string, string> = {
+  client: 'C:',
+  server: 'S:',
+  event: '--',
+};
+
+const TranscriptView: React.FC<TranscriptViewProps> = ({ email }) => {
+  const [transcript, setTranscript] = useState<smtp.Transcript | null>(null);
+  const [error, setError] = useState<string | null>(null);
+
+  useEffect(() => {
+    setTranscript(null);
+    setError(null);
+    GetEmailTranscript(email.id)
+      .then(setTranscript)
+      .catch((err) => setError(String(err)));
+  }, [email.id]);
+
+  if (error) {
+    return (
+      <div className="p-6 text-sm text-gray-500 dark:text-gray-400">
+        No transcript is available for this email.
+      </div>
+    );
+  }
+
+  if (!transcript) {
+    return null;
+  }
+
+  return (
+    <div className="p-6 space-y-4">
+      <div className="text-sm text-gray-600 dark:text-gray-400 space-y-1">
+        <div>Client: {transcript.remoteAddr} ({transcript.helo || 'no HELO'})</div>
+        {transcript.authMechanism && <div>AUTH: {transcript.authMechanism}</div>}
+        {transcript.tls && <div>Upgraded with STARTTLS</div>}
+      </div>
+      <pre className="whitespace-pre-wrap font-mono text-sm">
+        {transcript.lines?.map((line, i) => (
+          <div key={i} className={directionStyles[line.direction]}>
+            <span className="text-gray-400 dark:text-gray-500 mr-2">
+              {new Date(line.time).toLocaleTimeString()}
+            </span>
+            {directionPrefix[line.direction]} {line.text}
+          </div>
+        ))}
+      </pre>
+    </div>
+  );
+};
+
+export default TranscriptView;
@@ -0,0 +1,55 @@
+import React from 'react';
+import { AccessibilityReport } from '../../../types/email';
+
+interface AccessibilitySectionProps {
+  report: AccessibilityReport;
+}
+
+const severityStyles: Record<string, string> = {
+  error: 'bg-red-100 text-red-800 dark:bg-red-900 dark:text-red-200',
+  warning: 'bg-yellow-100 text-yellow-800 dark:bg-yellow-900 dark:text-yellow-200',
+};
+
+const AccessibilitySection: React.FC<AccessibilitySectionProps> = ({ report }) => {
+  return (
+    <section>
+      <div className="flex items-center gap-3 mb-3">
+        <h2 className="text-base font-semibold text-gray-900 dark:text-white">Accessibility</h2>
+        <span className="text-sm text-gray-500 dark:text-gray-400">
+          {report.errors} errors, {report.warnings} warnings
+        </span>
+      </div>
+
+      {report.findings.length === 0 ? (
+        <p className="text-sm text-gray-500 dark:text-gray-400">No problems found</p>
+      ) : (
+        <table className="w-full text-sm">
+          <thead className="text-left text-gray-500 dark:text-gray-400 border-b border-gray-200 dark:border-gray-700">
+            <tr>
+              <th className="py-2 pr-4 font-medium">Severity</th>
+              <th className="py-2 pr-4 font-medium">Rule</th>
+              <th className="py-2 pr-4 font-medium">Line</th>
+              <th className="py-2 font-medium">Problem</th>
+            </tr>
+          </thead>
+          <tbody className="text-gray-900 dark:text-gray-100">
+            {report.findings.map((f, i) => (
+              <tr key={i} className="border-b border-gray-100 dark:border-gray-700 align-top">
+                <td className="py-2 pr-4">
+                  <span className={`px-1.5 py-0.5 rounded text-xs font-medium ${severityStyles[f.severity]}`}>
+                    {f.severity}
+                  </span>
+                </td>
+                <td className="py-2 pr-4 font-mono text-xs whitespace-nowrap">{f.rule}</td>
+                <td className="py-2 pr-4">{f.line}</td>
+                <td className="py-2">{f.message}</td>
+              </tr>
+            ))}
+          </tbody>
+        </table>
+      )}
+    </section>
+  );
+};
+
+export default AccessibilitySection;
@@ -0,0 +1,114 @@
+import React from 'react';
+import { Calendar, CalendarPerson, CalendarTime } from '../../../types/email';
+import { SaveCalendar } from '../../../../wailsjs/go/main/App';
+
+interface CalendarSectionProps {
+  emailId: string;
+  calendars: Calendar[];
+}
+
+const formatTime = (t: CalendarTime | null) => {
+  if (!t) {
+    return '—';
+  }
+  if (t.allDay) {
+    return `${t.time} (all day)`;
+  }
+  if (t.time) {
+    const zone = t.utc ? 'UTC' : t.tzid;
+    return `${new Date(t.time).toLocaleString()} (${zone})`;
+  }
+  return t.floating ? `${t.value} (floating)` : `${t.value} (${t.tzid})`;
+};
+
+const formatPerson = (p: CalendarPerson) => (p.name ? `${p.name} <${p.email}>` : p.email);
+
+const CalendarSection: React.FC<CalendarSectionProps> = ({ emailId, calendars }) => {
+  const [error, setError] = React.useState('');
+
+  const save = (index: number) => {
+    setError('');
+    SaveCalendar(emailId, index).catch(err => setError(String(err)));
+  };
+
+  return (
+    <section>
+      <h2 className="text-base font-semibold text-gray-900 dark:text-white mb-3">Calendar invites</h2>
+      {error && <p className="text-sm text-red-700 dark:text-red-300 mb-2">{error}</p>}
+
+      <div className="space-y-6">
+        {calendars.map((calendar, index) => (
+          <div key={index}>
+            <div className="flex items-center gap-3 mb-2">
+              <span className="font-mono text-sm text-gray-700 dark:text-gray-300">{calendar.filename}</span>
+              {calendar.method && (
+                <span className="px-2 py-0.5 rounded-full text-xs font-medium bg-blue-100 text-blue-800 dark:bg-blue-900 dark:text-blue-200">
+                  {calendar.method}
+                </span>
+              )}
+              <button
+                onClick={() => save(index)}
+                className="ml-auto px-3 py-1 text-sm rounded-md bg-gray-100 hover:bg-gray-200 text-gray-700 dark:bg-gray-700 dark:hover:bg-gray-600 dark:text-gray-200"
+              >
+                Download .ics
+              </button>
+            </div>
+
+            {calendar.events.map((event, i) => (
+              <dl key={i} className="grid grid-cols-[8rem_1fr] gap-y-1 text-sm mb-3">
+                <dt className="text-gray-500 dark:text-gray-400">Summary</dt>
+                <dd className="text-gray-900 dark:text-gray-100">{event.summary || '—'}</dd>
+                <dt className="text-gray-500 dark:text-gray-400">Start</dt>
+                <dd className="text-gray-900 dark:text-gray-100">{formatTime(event.start)}</dd>
+                <dt className="text-gray-500 dark:text-gray-400">End</dt>
+                <dd className="text-gray-900 dark:text-gray-100">{event.end ? formatTime(event.end) : event.duration || '—'}</dd>
+                {event.recurrence.length > 0 && (
+                  <>
+                    <dt className="text-gray-500 dark:text-gray-400">Repeats</dt>
+                    <dd className="font-mono text-xs text-gray-900 dark:text-gray-100">
+                      {event.recurrence.map(r => r.rule).join('; ')}
+                    </dd>
+                  </>
+                )}
+                {event.location && (
+                  <>
+                    <dt className="text-gray-500 dark:text-gray-400">Location</dt>
+                    <dd className="text-gray-900 dark:text-gray-100">{event.location}</dd>
+                  </>
+                )}
+                <dt className="text-gray-500 dark:text-gray-400">Organizer</dt>
+                <dd className="text-gray-900 dark:text-gray-100">{event.organizer ? formatPerson(event.organizer) : '—'}</dd>
+                <dt className="text-gray-500 dark:text-gray-400">Attendees</dt>
+                <dd className="text-gray-900 dark:text-gray-100">
+                  {event.attendees.length === 0
+                    ? '—'
+                    : event.attendees.map(a => `${formatPerson(a)}${a.status ? ` (${a.status.toLowerCase()})` : ''}`).join(', ')}
+                </dd>
+                <dt className="text-gray-500 dark:text-gray-400">UID</dt>
+                <dd className="font-mono text-xs text-gray-900 dark:text-gray-100 break-all">
+                  {event.uid || '—'} (sequence {event.sequence})
+                </dd>
+              </dl>
+            ))}
+
+            {calendar.problems.length > 0 && (
+              <ul className="text-sm space-y-1">
+                {calendar.problems.map((p, i) => (
+                  <li
+                    key={i}
+                    className={p.severity === 'error' ? 'text-red-700 dark:text-red-300' : 'text-yellow-700 dark:text-yellow-300'}
+                  >
+                    {p.line > 0 && <span className="text-gray-500 dark:text-gray-400">Line {p.line}: </span>}
+                    {p.message}
+                  </li>
+                ))}
+              </ul>
+            )}
+          </div>
+        ))}
+      </div>
+    </section>
+  );
+};
+
+export default CalendarSection;
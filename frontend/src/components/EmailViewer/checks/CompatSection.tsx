@@ -0,0 +1,73 @@
+import React from 'react';
+import { CompatReport } from '../../../types/email';
+import { CheckCompatibility } from '../../../../wailsjs/go/main/App';
+
+interface CompatSectionProps {
+  emailId: string;
+}
+
+const CompatSection: React.FC<CompatSectionProps> = ({ emailId }) => {
+  const [report, setReport] = React.useState<CompatReport | null>(null);
+
+  React.useEffect(() => {
+    CheckCompatibility(emailId, [])
+      .then(setReport)
+      .catch((error) => console.error('Failed to check client compatibility:', error));
+  }, [emailId]);
+
+  if (!report) {
+    return null;
+  }
+
+  const clientName = (id: string) => report.clients.find(c => c.id === id)?.name ?? id;
+
+  return (
+    <section>
+      <div className="flex items-center gap-3 mb-3">
+        <h2 className="text-base font-semibold text-gray-900 dark:text-white">Client compatibility</h2>
+        <span
+          className={`px-2 py-0.5 rounded-full text-xs font-medium ${
+            report.passed
+              ? 'bg-green-100 text-green-800 dark:bg-green-900 dark:text-green-200'
+              : 'bg-red-100 text-red-800 dark:bg-red-900 dark:text-red-200'
+          }`}
+        >
+          {report.passed ? 'Supported' : `${report.summary.filter(s => s.unsupported.length > 0).length} clients affected`}
+        </span>
+      </div>
+
+      {report.issues.length === 0 ? (
+        <p className="text-sm text-gray-500 dark:text-gray-400">No compatibility issues found</p>
+      ) : (
+        <table className="w-full text-sm">
+          <thead className="text-left text-gray-500 dark:text-gray-400 border-b border-gray-200 dark:border-gray-700">
+            <tr>
+              <th className="py-2 pr-4 font-medium">Feature</th>
+              <th className="py-2 pr-4 font-medium">Lines</th>
+              <th className="py-2 pr-4 font-medium">Unsupported</th>
+              <th className="py-2 font-medium">Partial</th>
+            </tr>
+          </thead>
+          <tbody className="text-gray-900 dark:text-gray-100">
+            {report.issues.map(issue => (
+              <tr key={issue.feature} className="border-b border-gray-100 dark:border-gray-700 align-top">
+                <td className="py-2 pr-4 whitespace-nowrap">{issue.title}</td>
+                <td className="py-2 pr-4 font-mono text-xs">{issue.lines.join(', ')}</td>
+                <td className="py-2 pr-4 text-red-700 dark:text-red-300">
+                  {issue.unsupported.map(clientName).join(', ')}
+                </td>
+                <td className="py-2 text-yellow-700 dark:text-yellow-300">
+                  {issue.partial.map(id => (
+                    <div key={id} title={issue.notes?.[id]}>{clientName(id)}</div>
+                  ))}
+                </td>
+              </tr>
+            ))}
+          </tbody>
+        </table>
+      )}
+    </section>
+  );
+};
+
+export default CompatSection;
@@ -0,0 +1,52 @@
+import React from 'react';
+import { ExtractedValues } from '../../../types/email';
+import { useClipboard } from '../../../hooks/useClipboard';
+
+interface ExtractedSectionProps {
+  values: ExtractedValues;
+}
+
+const extractorLabels: Record<string, string> = {
+  code: 'Verification code',
+  link: 'Sign-in link',
+};
+
+const ExtractedSection: React.FC<ExtractedSectionProps> = ({ values }) => {
+  const { copyToClipboard } = useClipboard();
+
+  return (
+    <section>
+      <h2 className="text-base font-semibold text-gray-900 dark:text-white mb-3">Extracted values</h2>
+      <table className="w-full text-sm">
+        <thead className="text-left text-gray-500 dark:text-gray-400 border-b border-gray-200 dark:border-gray-700">
+          <tr>
+            <th className="py-2 pr-4 font-medium">Extractor</th>
+            <th className="py-2 pr-4 font-medium">Value</th>
+            <th className="py-2 font-medium">Found in</th>
+          </tr>
+        </thead>
+        <tbody className="text-gray-900 dark:text-gray-100">
+          {values.matches.map(m => (
+            <tr key={`${m.extractor}:${m.value}`} className="border-b border-gray-100 dark:border-gray-700">
+              <td className="py-2 pr-4 whitespace-nowrap">{extractorLabels[m.extractor] ?? m.extractor}</td>
+              <td className="py-2 pr-4">
+                <button
+                  onClick={() => copyToClipboard(m.value)}
+                  title="Copy"
+                  className={`font-mono text-xs break-all text-left hover:underline ${
+                    m.value === values.code || m.value === values.link ? 'font-semibold' : ''
+                  }`}
+                >
+                  {m.value}
+                </button>
+              </td>
+              <td className="py-2 text-gray-600 dark:text-gray-300">{m.source}</td>
+            </tr>
+          ))}
+        </tbody>
+      </table>
+    </section>
+  );
+};
+
+export default ExtractedSection;
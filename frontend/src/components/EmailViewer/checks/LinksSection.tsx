@@ -0,0 +1,105 @@
+import React from 'react';
+import { EmailLink } from '../../../types/email';
+import { CheckEmailLinks } from '../../../../wailsjs/go/main/App';
+import { links as linkModels } from '../../../../wailsjs/go/models';
+
+interface LinksSectionProps {
+  emailId: string;
+  links: EmailLink[];
+}
+
+const flagLabels: Record<string, string> = {
+  'text-mismatch': 'Text mismatch',
+  insecure: 'http',
+  'internal-host': 'Internal host',
+  'missing-utm': 'No UTM',
+  invalid: 'Invalid',
+};
+
+const LinksSection: React.FC<LinksSectionProps> = ({ emailId, links }) => {
+  const [results, setResults] = React.useState<Record<string, linkModels.CheckResult>>({});
+  const [checking, setChecking] = React.useState(false);
+  const [error, setError] = React.useState('');
+
+  React.useEffect(() => {
+    setResults({});
+    setError('');
+  }, [emailId]);
+
+  const check = () => {
+    setChecking(true);
+    setError('');
+    CheckEmailLinks(emailId)
+      .then(list => setResults(Object.fromEntries(list.map(r => [r.url, r]))))
+      .catch(err => setError(String(err)))
+      .finally(() => setChecking(false));
+  };
+
+  const flagged = links.filter(l => l.flags.length > 0).length;
+
+  return (
+    <section>
+      <div className="flex items-center gap-3 mb-3">
+        <h2 className="text-base font-semibold text-gray-900 dark:text-white">Links</h2>
+        <span className="text-sm text-gray-500 dark:text-gray-400">
+          {links.length} found, {flagged} flagged
+        </span>
+        <button
+          onClick={check}
+          disabled={checking}
+          className="ml-auto px-3 py-1 text-sm rounded-md bg-gray-100 hover:bg-gray-200 text-gray-700 dark:bg-gray-700 dark:hover:bg-gray-600 dark:text-gray-200 disabled:opacity-50"
+        >
+          {checking ? 'Checking…' : 'Check links'}
+        </button>
+      </div>
+      {error && <p className="text-sm text-red-700 dark:text-red-300 mb-2">{error}</p>}
+
+      <table className="w-full text-sm">
+        <thead className="text-left text-gray-500 dark:text-gray-400 border-b border-gray-200 dark:border-gray-700">
+          <tr>
+            <th className="py-2 pr-4 font-medium">URL</th>
+            <th className="py-2 pr-4 font-medium">Text</th>
+            <th className="py-2 pr-4 font-medium">Where</th>
+            <th className="py-2 pr-4 font-medium">Flags</th>
+            <th className="py-2 font-medium">Status</th>
+          </tr>
+        </thead>
+        <tbody className="text-gray-900 dark:text-gray-100">
+          {links.map((link, i) => {
+            const result = results[link.url];
+            return (
+              <tr key={i} className="border-b border-gray-100 dark:border-gray-700 align-top">
+                <td className="py-2 pr-4 font-mono text-xs break-all">{link.url}</td>
+                <td className="py-2 pr-4">{link.text}</td>
+                <td className="py-2 pr-4 whitespace-nowrap text-gray-600 dark:text-gray-300">
+                  {link.kind} · {link.location}{link.line > 0 && `:${link.line}`}
+                </td>
+                <td className="py-2 pr-4">
+                  <div className="flex flex-wrap gap-1">
+                    {link.flags.map(flag => (
+                      <span
+                        key={flag}
+                        className="px-1.5 py-0.5 rounded text-xs bg-yellow-100 text-yellow-800 dark:bg-yellow-900 dark:text-yellow-200"
+                      >
+                        {flagLabels[flag] ?? flag}
+                      </span>
+                    ))}
+                  </div>
+                </td>
+                <td className="py-2 whitespace-nowrap" title={result?.error || result?.location}>
+                  {result?.checked && (
+                    <span className={result.ok ? 'text-green-700 dark:text-green-300' : 'text-red-700 dark:text-red-300'}>
+                      {result.status || 'error'}
+                    </span>
+                  )}
+                </td>
+              </tr>
+            );
+          })}
+        </tbody>
+      </table>
+    </section>
+  );
+};
+
+export default LinksSection;
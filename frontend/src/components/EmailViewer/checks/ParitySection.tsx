@@ -0,0 +1,61 @@
+import React from 'react';
+import { ParityReport } from '../../../types/email';
+
+interface ParitySectionProps {
+  report: ParityReport;
+}
+
+const chunkStyles: Record<string, string> = {
+  equal: 'text-gray-700 dark:text-gray-300',
+  delete: 'bg-red-100 text-red-800 line-through dark:bg-red-900 dark:text-red-200',
+  insert: 'bg-green-100 text-green-800 dark:bg-green-900 dark:text-green-200',
+};
+
+const LinkList: React.FC<{ title: string; links: string[] }> = ({ title, links }) => (
+  <div className="mt-3">
+    <h3 className="text-sm font-medium text-gray-700 dark:text-gray-300 mb-1">{title}</h3>
+    <ul className="text-xs font-mono text-gray-600 dark:text-gray-400 space-y-0.5">
+      {links.map(link => <li key={link} className="break-all">{link}</li>)}
+    </ul>
+  </div>
+);
+
+const ParitySection: React.FC<ParitySectionProps> = ({ report }) => {
+  return (
+    <section>
+      <div className="flex items-center gap-3 mb-3">
+        <h2 className="text-base font-semibold text-gray-900 dark:text-white">Text and HTML parity</h2>
+        <span
+          className={`px-2 py-0.5 rounded-full text-xs font-medium ${
+            report.mismatch
+              ? 'bg-yellow-100 text-yellow-800 dark:bg-yellow-900 dark:text-yellow-200'
+              : 'bg-green-100 text-green-800 dark:bg-green-900 dark:text-green-200'
+          }`}
+        >
+          {report.missingText ? 'No text part' : `${Math.round(report.similarity * 100)}% similar`}
+        </span>
+      </div>
+
+      {report.missingText ? (
+        <p className="text-sm text-gray-500 dark:text-gray-400">
+          The email has no plain text alternative. Clients that can't show HTML, and some spam filters, will treat it as empty.
+        </p>
+      ) : report.diff.length > 0 ? (
+        <p className="text-sm leading-relaxed">
+          {report.diff.map((chunk, i) => (
+            <span key={i} className={`${chunkStyles[chunk.op]} ${chunk.op !== 'equal' ? 'px-0.5 rounded' : ''}`}>
+              {chunk.text}{' '}
+            </span>
+          ))}
+        </p>
+      ) : (
+        <p className="text-sm text-gray-500 dark:text-gray-400">The parts are too different to show a word diff.</p>
+      )}
+
+      {report.linksOnlyInHtml.length > 0 && <LinkList title="Links only in the HTML part" links={report.linksOnlyInHtml} />}
+      {report.linksOnlyInText.length > 0 && <LinkList title="Links only in the text part" links={report.linksOnlyInText} />}
+    </section>
+  );
+};
+
+export default ParitySection;
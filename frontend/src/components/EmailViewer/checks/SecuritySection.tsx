@@ -0,0 +1,83 @@
+import React from 'react';
+import { Certificate, Signature } from '../../../types/email';
+
+interface SecuritySectionProps {
+  signature: Signature;
+}
+
+const statusStyles: Record<string, string> = {
+  valid: 'bg-green-100 text-green-800 dark:bg-green-900 dark:text-green-200',
+  untrusted: 'bg-yellow-100 text-yellow-800 dark:bg-yellow-900 dark:text-yellow-200',
+  invalid: 'bg-red-100 text-red-800 dark:bg-red-900 dark:text-red-200',
+  unverified: 'bg-gray-100 text-gray-800 dark:bg-gray-700 dark:text-gray-200',
+};
+
+const validityLabels: Record<string, string> = {
+  valid: 'Valid',
+  expired: 'Expired',
+  'not-yet-valid': 'Not yet valid',
+};
+
+const CertificateRows: React.FC<{ cert: Certificate; role: string }> = ({ cert, role }) => (
+  <tr className="border-b border-gray-100 dark:border-gray-700 align-top">
+    <td className="py-2 pr-4 text-gray-500 dark:text-gray-400 whitespace-nowrap">{role}</td>
+    <td className="py-2 pr-4">
+      <div>{cert.subject}</div>
+      {cert.emails.length > 0 && <div className="text-gray-600 dark:text-gray-300">{cert.emails.join(', ')}</div>}
+      <div className="text-xs text-gray-500 dark:text-gray-400">
+        Issued by {cert.selfSigned ? 'itself' : cert.issuer}
+      </div>
+      <div className="font-mono text-xs text-gray-500 dark:text-gray-400 break-all">SHA-256 {cert.fingerprint}</div>
+    </td>
+    <td className="py-2 whitespace-nowrap">
+      <div className={cert.validity === 'valid' ? '' : 'text-red-600 dark:text-red-400'}>
+        {validityLabels[cert.validity]}
+      </div>
+      <div className="text-xs text-gray-500 dark:text-gray-400">
+        {new Date(cert.notBefore).toLocaleDateString()} – {new Date(cert.notAfter).toLocaleDateString()}
+      </div>
+    </td>
+  </tr>
+);
+
+const SecuritySection: React.FC<SecuritySectionProps> = ({ signature }) => {
+  return (
+    <section>
+      <div className="flex items-center gap-3 mb-3">
+        <h2 className="text-base font-semibold text-gray-900 dark:text-white">Signature</h2>
+        <span className={`px-2 py-0.5 rounded-full text-xs font-medium ${statusStyles[signature.status]}`}>
+          {signature.status}
+        </span>
+      </div>
+
+      {signature.reason && (
+        <p className="text-sm text-gray-600 dark:text-gray-300 mb-3">{signature.reason}</p>
+      )}
+      {signature.signingTime && (
+        <p className="text-sm text-gray-500 dark:text-gray-400 mb-3">
+          Signed {new Date(signature.signingTime).toLocaleString()}
+        </p>
+      )}
+
+      {(signature.signer || signature.certificates.length > 0) && (
+        <table className="w-full text-sm">
+          <thead className="text-left text-gray-500 dark:text-gray-400 border-b border-gray-200 dark:border-gray-700">
+            <tr>
+              <th className="py-2 pr-4 font-medium">Role</th>
+              <th className="py-2 pr-4 font-medium">Certificate</th>
+              <th className="py-2 font-medium">Validity</th>
+            </tr>
+          </thead>
+          <tbody className="text-gray-900 dark:text-gray-100">
+            {signature.signer && <CertificateRows cert={signature.signer} role="Signer" />}
+            {signature.certificates.map(cert => (
+              <CertificateRows key={cert.fingerprint} cert={cert} role="Included" />
+            ))}
+          </tbody>
+        </table>
+      )}
+    </section>
+  );
+};
+
+export default SecuritySection;
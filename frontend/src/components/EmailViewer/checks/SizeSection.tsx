@@ -0,0 +1,80 @@
+import React from 'react';
+import { SizeReport } from '../../../types/email';
+
+interface SizeSectionProps {
+  report: SizeReport;
+}
+
+const formatBytes = (bytes: number) => {
+  if (bytes < 1024) return `${bytes} B`;
+  if (bytes < 1024 * 1024) return `${(bytes / 1024).toFixed(1)} KB`;
+  return `${(bytes / (1024 * 1024)).toFixed(1)} MB`;
+};
+
+const sectionLabels: Record<string, string> = {
+  'inline-styles': 'Inline styles',
+  'style-blocks': '<style> blocks',
+  'base64-images': 'Base64 images',
+  tracking: 'Tracking markup',
+  comments: 'Comments',
+  other: 'Other markup and text',
+};
+
+const SizeSection: React.FC<SizeSectionProps> = ({ report }) => {
+  const over = (budget: string) => report.warnings.some(w => w.budget === budget);
+
+  return (
+    <section>
+      <div className="flex items-center gap-3 mb-3">
+        <h2 className="text-base font-semibold text-gray-900 dark:text-white">Size</h2>
+        {report.warnings.length > 0 && (
+          <span className="px-2 py-0.5 rounded-full text-xs font-medium bg-red-100 text-red-800 dark:bg-red-900 dark:text-red-200">
+            {report.warnings.length} over budget
+          </span>
+        )}
+      </div>
+
+      <dl className="grid grid-cols-3 gap-4 mb-4 text-sm">
+        {[
+          ['HTML part', report.html, 'html'],
+          ['Message', report.message, 'message'],
+          ['Embedded images', report.images, 'images'],
+        ].map(([label, size, budget]) => (
+          <div key={budget as string}>
+            <dt className="text-gray-500 dark:text-gray-400">{label}</dt>
+            <dd className={over(budget as string) ? 'text-red-700 dark:text-red-300 font-medium' : 'text-gray-900 dark:text-gray-100'}>
+              {formatBytes(size as number)}
+            </dd>
+          </div>
+        ))}
+      </dl>
+
+      {report.warnings.map(w => (
+        <p key={w.budget} className="text-sm text-red-700 dark:text-red-300 mb-1">{w.message}</p>
+      ))}
+
+      {report.contributors.length > 0 && (
+        <table className="w-full text-sm mt-3">
+          <thead className="text-left text-gray-500 dark:text-gray-400 border-b border-gray-200 dark:border-gray-700">
+            <tr>
+              <th className="py-2 pr-4 font-medium">HTML section</th>
+              <th className="py-2 pr-4 font-medium text-right">Size</th>
+              <th className="py-2 font-medium text-right">Share</th>
+            </tr>
+          </thead>
+          <tbody className="text-gray-900 dark:text-gray-100">
+            {report.contributors.map(c => (
+              <tr key={c.section} className="border-b border-gray-100 dark:border-gray-700">
+                <td className="py-2 pr-4">{sectionLabels[c.section] ?? c.section}</td>
+                <td className="py-2 pr-4 text-right">{formatBytes(c.size)}</td>
+                <td className="py-2 text-right">{c.percent.toFixed(1)}%</td>
+              </tr>
+            ))}
+          </tbody>
+        </table>
+      )}
+    </section>
+  );
+};
+
+export default SizeSection;
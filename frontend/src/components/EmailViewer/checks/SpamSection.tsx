@@ -0,0 +1,52 @@
+import React from 'react';
+import { SpamReport } from '../../../types/email';
+
+interface SpamSectionProps {
+  report: SpamReport;
+}
+
+const SpamSection: React.FC<SpamSectionProps> = ({ report }) => {
+  return (
+    <section>
+      <div className="flex items-center gap-3 mb-3">
+        <h2 className="text-base font-semibold text-gray-900 dark:text-white">Spam score</h2>
+        <span
+          className={`px-2 py-0.5 rounded-full text-xs font-medium ${
+            report.spam
+              ? 'bg-red-100 text-red-800 dark:bg-red-900 dark:text-red-200'
+              : 'bg-green-100 text-green-800 dark:bg-green-900 dark:text-green-200'
+          }`}
+        >
+          {report.score.toFixed(1)} / {report.threshold.toFixed(1)}
+        </span>
+      </div>
+
+      {report.hits.length === 0 ? (
+        <p className="text-sm text-gray-500 dark:text-gray-400">No rules hit</p>
+      ) : (
+        <table className="w-full text-sm">
+          <thead className="text-left text-gray-500 dark:text-gray-400 border-b border-gray-200 dark:border-gray-700">
+            <tr>
+              <th className="py-2 pr-4 font-medium">Rule</th>
+              <th className="py-2 pr-4 font-medium">Description</th>
+              <th className="py-2 pr-4 font-medium">Detail</th>
+              <th className="py-2 font-medium text-right">Score</th>
+            </tr>
+          </thead>
+          <tbody className="text-gray-900 dark:text-gray-100">
+            {report.hits.map(hit => (
+              <tr key={hit.rule} className="border-b border-gray-100 dark:border-gray-700">
+                <td className="py-2 pr-4 font-mono text-xs whitespace-nowrap">{hit.rule}</td>
+                <td className="py-2 pr-4">{hit.description}</td>
+                <td className="py-2 pr-4 text-gray-600 dark:text-gray-300 break-all">{hit.detail}</td>
+                <td className="py-2 text-right">{hit.score.toFixed(1)}</td>
+              </tr>
+            ))}
+          </tbody>
+        </table>
+      )}
+    </section>
+  );
+};
+
+export default SpamSection;
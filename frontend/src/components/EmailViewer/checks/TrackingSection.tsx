@@ -0,0 +1,63 @@
+import React from 'react';
+import { TrackingReport } from '../../../types/email';
+
+interface TrackingSectionProps {
+  report: TrackingReport;
+}
+
+const TrackingSection: React.FC<TrackingSectionProps> = ({ report }) => {
+  const rows = [
+    ...report.pixels.map(p => ({ kind: 'Pixel', url: p.url, detail: '', service: p.service, reasons: p.reasons, line: p.line })),
+    ...report.links.map(l => ({ kind: 'Link', url: l.url, detail: l.destination, service: l.service, reasons: l.reasons, line: l.line })),
+  ].sort((a, b) => a.line - b.line);
+
+  return (
+    <section>
+      <div className="flex items-center gap-3 mb-3">
+        <h2 className="text-base font-semibold text-gray-900 dark:text-white">Tracking</h2>
+        <span
+          className={`px-2 py-0.5 rounded-full text-xs font-medium ${
+            rows.length > 0
+              ? 'bg-yellow-100 text-yellow-800 dark:bg-yellow-900 dark:text-yellow-200'
+              : 'bg-green-100 text-green-800 dark:bg-green-900 dark:text-green-200'
+          }`}
+        >
+          {report.pixels.length} pixels, {report.links.length} tracked links
+        </span>
+        {report.services.length > 0 && (
+          <span className="text-xs text-gray-500 dark:text-gray-400">{report.services.join(', ')}</span>
+        )}
+      </div>
+
+      {rows.length === 0 ? (
+        <p className="text-sm text-gray-500 dark:text-gray-400">No tracking found</p>
+      ) : (
+        <table className="w-full text-sm">
+          <thead className="text-left text-gray-500 dark:text-gray-400 border-b border-gray-200 dark:border-gray-700">
+            <tr>
+              <th className="py-2 pr-4 font-medium">Line</th>
+              <th className="py-2 pr-4 font-medium">Type</th>
+              <th className="py-2 pr-4 font-medium">URL</th>
+              <th className="py-2 font-medium">Reasons</th>
+            </tr>
+          </thead>
+          <tbody className="text-gray-900 dark:text-gray-100">
+            {rows.map((row, i) => (
+              <tr key={i} className="border-b border-gray-100 dark:border-gray-700">
+                <td className="py-2 pr-4 text-gray-500 dark:text-gray-400">{row.line}</td>
+                <td className="py-2 pr-4 whitespace-nowrap">{row.kind}{row.service && ` (${row.service})`}</td>
+                <td className="py-2 pr-4 font-mono text-xs break-all">
+                  {row.url}
+                  {row.detail && <div className="text-gray-500 dark:text-gray-400">→ {row.detail}</div>}
+                </td>
+                <td className="py-2 font-mono text-xs">{row.reasons.join(', ')}</td>
+              </tr>
+            ))}
+          </tbody>
+        </table>
+      )}
+    </section>
+  );
+};
+
+export default TrackingSection;
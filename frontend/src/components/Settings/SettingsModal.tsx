@@ -3,7 +3,7 @@ import { XMarkIcon } from '@heroicons/react/24/outline';
 import toast from 'react-hot-toast';
 import { useSettings } from '../../hooks/useSettings';
 import type { Settings } from '../../types/settings';
-import { GetVersion } from '../../../wailsjs/go/main/App';
+import { GetVersion, GetSettings, RestartSMTPServer } from '../../../wailsjs/go/main/App';
 
 interface SettingsModalProps {
   isOpen: boolean;
@@ -31,8 +31,15 @@ const SettingsModal: React.FC<SettingsModalProps> = ({ isOpen, onClose }) => {
     try {
       const loadingToast = toast.loading('Saving settings...');
       
+      // Listener settings such as limits only apply after a restart
+      const saved = await GetSettings();
+      const smtpChanged = JSON.stringify(saved.smtp) !== JSON.stringify({ ...saved.smtp, ...localSettings.smtp });
+
       // Save settings to backend
       await updateSettings(localSettings);
+      if (smtpChanged) {
+        await RestartSMTPServer();
+      }
       
       toast.dismiss(loadingToast);
       toast.success('Settings saved successfully');
@@ -319,6 +326,62 @@ const SettingsModal: React.FC<SettingsModalProps> = ({ isOpen, onClose }) => {
                   </div>
                 </div>
               </div>
+
+              <div>
+                <h3 className="text-sm font-semibold text-gray-900 dark:text-white mb-1">Limits</h3>
+                <p className="text-xs text-gray-500 dark:text-gray-400 mb-3">
+                  Use 0 to disable a limit. Changes apply when the SMTP server restarts.
+                </p>
+                <div className="grid grid-cols-1 sm:grid-cols-2 gap-4">
+                  {[
+                    { key: 'maxMessageBytes', label: 'Max Message Size (bytes)' },
+                    { key: 'maxRecipients', label: 'Max Recipients' },
+                    { key: 'maxConnections', label: 'Max Connections' },
+                    { key: 'maxLineLength', label: 'Max Line Length (bytes)' },
+                    { key: 'readTimeoutSeconds', label: 'Read Timeout (seconds)' },
+                    { key: 'writeTimeoutSeconds', label: 'Write Timeout (seconds)' },
+                    { key: 'idleTimeoutSeconds', label: 'Idle Timeout (seconds)' },
+                  ].map(({ key, label }) => (
+                    <div key={key}>
+                      <label className="block text-sm font-medium text-gray-700 dark:text-gray-300 mb-1">
+                        {label}
+                      </label>
+                      <input
+                        type="number"
+                        min={0}
+                        className="w-full px-3 py-2 border border-gray-300 dark:border-gray-600 rounded-md bg-white dark:bg-gray-700 text-gray-900 dark:text-gray-100"
+                        value={localSettings.smtp.limits[key as keyof Settings['smtp']['limits']]}
+                        onChange={(e) => updateLocalSettings(['smtp', 'limits', key], parseInt(e.target.value) || 0)}
+                      />
+                    </div>
+                  ))}
+                </div>
+              </div>
+
+              <div>
+                <h3 className="text-sm font-semibold text-gray-900 dark:text-white mb-1">Extensions</h3>
+                <p className="text-xs text-gray-500 dark:text-gray-400 mb-3">
+                  Turn extensions off to test how clients fall back. BINARYMIME requires CHUNKING.
+                </p>
+                <div className="grid grid-cols-2 gap-2">
+                  {[
+                    { key: 'smtputf8', label: 'SMTPUTF8' },
+                    { key: 'eightBitMime', label: '8BITMIME' },
+                    { key: 'binaryMime', label: 'BINARYMIME' },
+                    { key: 'chunking', label: 'CHUNKING (BDAT)' },
+                    { key: 'dsn', label: 'DSN' },
+                  ].map(({ key, label }) => (
+                    <label key={key} className="flex items-center gap-2 text-sm text-gray-700 dark:text-gray-300">
+                      <input
+                        type="checkbox"
+                        checked={localSettings.smtp.extensions[key as keyof Settings['smtp']['extensions']]}
+                        onChange={(e) => updateLocalSettings(['smtp', 'extensions', key], e.target.checked)}
+                      />
+                      {label}
+                    </label>
+                  ))}
+                </div>
+              </div>
             </div>
           )}
 
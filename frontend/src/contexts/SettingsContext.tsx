@@ -24,6 +24,22 @@ export const defaultSettings: Settings = {
     username: '',
     password: '',
     tls: 'none',
+    limits: {
+      readTimeoutSeconds: 10,
+      writeTimeoutSeconds: 10,
+      idleTimeoutSeconds: 300,
+      maxMessageBytes: 10 * 1024 * 1024,
+      maxRecipients: 50,
+      maxConnections: 0,
+      maxLineLength: 2000,
+    },
+    extensions: {
+      smtputf8: true,
+      eightBitMime: true,
+      binaryMime: true,
+      chunking: true,
+      dsn: true,
+    },
   },
 };
 
@@ -50,7 +66,8 @@ export const SettingsProvider: React.FC<{ children: React.ReactNode }> = ({ chil
 
   const updateSettings = async (newSettings: Settings) => {
     try {
-      await SaveSettings(toBackendSettings(newSettings));
+      const current = await GetSettings();
+      await SaveSettings(toBackendSettings(newSettings, current));
       setSettings(newSettings);
     } catch (error) {
       console.error('Failed to save settings:', error);
@@ -1,3 +1,298 @@
+export interface ReportRecipient {
+  originalRecipient: string;
+  finalRecipient: string;
+  action: string;
+  status: string;
+  diagnosticCode: string;
+  remoteMta: string;
+  bounce: string;
+}
+
+export interface EmailReport {
+  type: 'delivery-status' | 'disposition-notification' | 'feedback-report' | string;
+  reportedBy: string;
+  originalEnvelopeId: string;
+  recipients: ReportRecipient[];
+  disposition?: {
+    finalRecipient: string;
+    originalRecipient: string;
+    disposition: string;
+    type: string;
+  } | null;
+  feedback?: {
+    feedbackType: string;
+    userAgent: string;
+    originalMailFrom: string;
+    originalRcptTo: string[];
+    arrivalDate: string;
+    sourceIp: string;
+    reportedDomains: string[];
+    reportedUris: string[];
+    authenticationResults: string;
+  } | null;
+  originalMessageId: string;
+  originalSubject: string;
+}
+
+export interface DKIMResult {
+  status: 'pass' | 'fail' | 'permerror' | 'temperror' | string;
+  reason: string;
+  domain: string;
+  selector: string;
+  identity: string;
+  algorithm: string;
+  headerCanonicalization: string;
+  bodyCanonicalization: string;
+  signedHeaders: string[];
+  bodyLength: number;
+  keySource: string;
+}
+
+export interface AuthResults {
+  spf: {
+    result: string;
+    domain: string;
+    record: string;
+    mechanism: string;
+    reason: string;
+  };
+  dmarc: {
+    result: string;
+    domain: string;
+    policyDomain: string;
+    record: string;
+    policy: string;
+    disposition: string;
+    percent: number;
+    spfAligned: boolean;
+    dkimAligned: boolean;
+    dkimDomain: string;
+    reason: string;
+  };
+}
+
+export interface SpamReport {
+  score: number;
+  threshold: number;
+  spam: boolean;
+  hits: {
+    rule: string;
+    description: string;
+    score: number;
+    detail: string;
+  }[];
+}
+
+export interface SizeReport {
+  html: number;
+  message: number;
+  images: number;
+  embeddedImages: {
+    name: string;
+    type: string;
+    size: number;
+    line: number;
+  }[];
+  contributors: {
+    section: string;
+    size: number;
+    percent: number;
+  }[];
+  warnings: {
+    budget: string;
+    size: number;
+    limit: number;
+    message: string;
+  }[];
+}
+
+export interface EmailLink {
+  url: string;
+  text: string;
+  kind: 'link' | 'image' | 'unsubscribe';
+  location: 'html' | 'text' | 'header';
+  line: number;
+  flags: string[];
+}
+
+export interface ExtractedValues {
+  code: string;
+  link: string;
+  matches: {
+    extractor: string;
+    value: string;
+    source: 'subject' | 'text' | 'html';
+  }[];
+}
+
+export interface AccessibilityReport {
+  errors: number;
+  warnings: number;
+  findings: {
+    rule: string;
+    severity: 'error' | 'warning';
+    message: string;
+    line: number;
+  }[];
+}
+
+export interface ParityReport {
+  missingText: boolean;
+  mismatch: boolean;
+  similarity: number;
+  htmlText: string;
+  diff: {
+    op: 'equal' | 'delete' | 'insert';
+    text: string;
+  }[];
+  linksOnlyInHtml: string[];
+  linksOnlyInText: string[];
+}
+
+export interface CalendarTime {
+  value: string;
+  tzid: string;
+  utc: boolean;
+  allDay: boolean;
+  floating: boolean;
+  time: string;
+}
+
+export interface CalendarPerson {
+  email: string;
+  name: string;
+  role: string;
+  status: string;
+  rsvp: boolean;
+}
+
+export interface CalendarEvent {
+  uid: string;
+  sequence: number;
+  summary: string;
+  description: string;
+  location: string;
+  status: string;
+  recurrenceId: CalendarTime | null;
+  organizer: CalendarPerson | null;
+  attendees: CalendarPerson[];
+  start: CalendarTime | null;
+  end: CalendarTime | null;
+  duration: string;
+  recurrence: {
+    rule: string;
+    freq: string;
+    interval: number;
+    count: number;
+    until: string;
+    byDay: string[];
+  }[];
+  exceptions: string[];
+}
+
+export interface Calendar {
+  mediaType: string;
+  filename: string;
+  method: string;
+  prodId: string;
+  version: string;
+  events: CalendarEvent[];
+  timeZones: string[];
+  problems: {
+    severity: 'error' | 'warning';
+    message: string;
+    line: number;
+  }[];
+}
+
+export interface TrackingReport {
+  pixels: {
+    url: string;
+    service: string;
+    reasons: string[];
+    line: number;
+  }[];
+  links: {
+    url: string;
+    destination: string;
+    service: string;
+    reasons: string[];
+    line: number;
+  }[];
+  services: string[];
+}
+
+export interface InlinePart {
+  contentId: string;
+  contentType: string;
+  filename: string;
+  size: number;
+}
+
+export interface PreviewResult {
+  html: string;
+  remote: string[];
+  remoteLoaded: boolean;
+  removed: string[];
+}
+
+export interface Attachment {
+  filename: string;
+  contentType: string;
+  size: number;
+}
+
+export interface Certificate {
+  subject: string;
+  issuer: string;
+  emails: string[];
+  serialNumber: string;
+  notBefore: string;
+  notAfter: string;
+  validity: 'valid' | 'expired' | 'not-yet-valid';
+  selfSigned: boolean;
+  fingerprint: string;
+}
+
+export interface Signature {
+  status: 'valid' | 'untrusted' | 'invalid' | 'unverified';
+  reason: string;
+  signingTime: string | null;
+  signer: Certificate | null;
+  certificates: Certificate[];
+}
+
+export interface Security {
+  protocol: 'smime' | 'pgp' | 'unknown';
+  mediaType: string;
+  signed: boolean;
+  encrypted: boolean;
+  signature: Signature | null;
+  decryption: '' | 'decrypted' | 'no-key' | 'failed' | 'unsupported';
+  notes: string[];
+}
+
+export interface CompatIssue {
+  feature: string;
+  title: string;
+  lines: number[];
+  unsupported: string[];
+  partial: string[];
+  notes?: Record<string, string>;
+}
+
+export interface CompatReport {
+  clients: { id: string; name: string }[];
+  issues: CompatIssue[];
+  summary: {
+    client: string;
+    name: string;
+    unsupported: string[];
+    partial: string[];
+  }[];
+  passed: boolean;
+}
+
 export interface Email {
   id: string;
   from: string;
@@ -11,4 +306,28 @@ export interface Email {
   timestamp: string;
   headers?: Record<string, string[]>;
   raw?: string;
+  sessionId?: string;
+  extensions?: string[];
+  bounceOf?: string;
+  parentId?: string;
+  report?: EmailReport | null;
+  inline?: InlinePart[] | null;
+  calendars?: Calendar[] | null;
+  attachments?: Attachment[] | null;
+  attached?: Email[] | null;
+  security?: Security | null;
+  dkim?: DKIMResult[] | null;
+  auth?: AuthResults | null;
+  mailFrom?: string;
+  parseWarnings?: string[] | null;
+  recipients?: string[];
+  helo?: string;
+  clientIp?: string;
+  spam?: SpamReport | null;
+  size?: SizeReport | null;
+  links?: EmailLink[] | null;
+  extracted?: ExtractedValues | null;
+  accessibility?: AccessibilityReport | null;
+  parity?: ParityReport | null;
+  tracking?: TrackingReport | null;
 } 
\ No newline at end of file
@@ -17,6 +17,22 @@ export interface Settings {
     username: string;
     password: string;
     tls: string;
+    limits: {
+      readTimeoutSeconds: number;
+      writeTimeoutSeconds: number;
+      idleTimeoutSeconds: number;
+      maxMessageBytes: number;
+      maxRecipients: number;
+      maxConnections: number;
+      maxLineLength: number;
+    };
+    extensions: {
+      smtputf8: boolean;
+      eightBitMime: boolean;
+      binaryMime: boolean;
+      chunking: boolean;
+      dsn: boolean;
+    };
   };
 }
 
@@ -37,13 +53,18 @@ export function toFrontendSettings(backendSettings: main.Settings): Settings {
       username: backendSettings.smtp.username,
       password: backendSettings.smtp.password,
       tls: backendSettings.smtp.tls,
+      limits: { ...backendSettings.smtp.limits },
+      extensions: { ...backendSettings.smtp.extensions },
     },
   };
 }
 
-export function toBackendSettings(frontendSettings: Settings): main.Settings {
-  const settings = new main.Settings();
+// base carries the stored backend settings so that sections and fields this
+// form doesn't manage (e.g. fault rules) survive a save
+export function toBackendSettings(frontendSettings: Settings, base: main.Settings = new main.Settings()): main.Settings {
+  const settings = main.Settings.createFrom(base);
   settings.ui = {
+    ...settings.ui,
     theme: frontendSettings.ui.theme,
     showPreview: frontendSettings.ui.showPreview,
     timeFormat: frontendSettings.ui.timeFormat,
@@ -51,12 +72,15 @@ export function toBackendSettings(frontendSettings: Settings): main.Settings {
     persistence: frontendSettings.ui.persistence,
   };
   settings.smtp = {
+    ...settings.smtp,
     host: frontendSettings.smtp.host,
     port: frontendSettings.smtp.port,
     auth: frontendSettings.smtp.auth,
     username: frontendSettings.smtp.username,
     password: frontendSettings.smtp.password,
     tls: frontendSettings.smtp.tls,
+    limits: { ...frontendSettings.smtp.limits },
+    extensions: { ...frontendSettings.smtp.extensions },
   };
   return settings;
 } 
\ No newline at end of file
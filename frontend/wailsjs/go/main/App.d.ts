@@ -1,15 +1,53 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
+import {compat} from '../models';
+import {links} from '../models';
+import {smtp} from '../models';
 import {main} from '../models';
+import {preview} from '../models';
+
+export function CheckCompatibility(arg1:string,arg2:Array<string>):Promise<compat.Report>;
+
+export function CheckEmailLinks(arg1:string):Promise<Array<links.CheckResult>>;
 
 export function ClearEmails():Promise<void>;
 
+export function EvaluateEmailAuth(arg1:string):Promise<smtp.AuthResults>;
+
+export function GetActivity(arg1:smtp.SessionLogFilter):Promise<Array<smtp.SessionLogEntry>>;
+
+export function GetDroppedNotifications():Promise<number>;
+
+export function GetEmail(arg1:string):Promise<main.Email>;
+
+export function GetEmailTranscript(arg1:string):Promise<smtp.Transcript>;
+
 export function GetEmails():Promise<Array<main.Email>>;
 
+export function GetGreylist():Promise<Array<smtp.GreylistEntry>>;
+
 export function GetSettings():Promise<main.Settings>;
 
+export function GetTranscript(arg1:string):Promise<smtp.Transcript>;
+
+export function GetTranscripts():Promise<Array<smtp.Transcript>>;
+
 export function GetVersion():Promise<string>;
 
+export function PreviewEmail(arg1:string,arg2:preview.Options):Promise<preview.Result>;
+
+export function ResetGreylist():Promise<void>;
+
+export function RestartAPIServer():Promise<void>;
+
 export function RestartSMTPServer():Promise<void>;
 
+export function SaveCalendar(arg1:string,arg2:number):Promise<string>;
+
+export function SaveFaultRules(arg1:Array<smtp.FaultRule>):Promise<void>;
+
 export function SaveSettings(arg1:main.Settings):Promise<void>;
+
+export function SetFaultInjectionEnabled(arg1:boolean):Promise<void>;
+
+export function SignEmailDKIM(arg1:string,arg2:string):Promise<smtp.DKIMSignature>;
@@ -2,26 +2,94 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function CheckCompatibility(arg1, arg2) {
+  return window['go']['main']['App']['CheckCompatibility'](arg1, arg2);
+}
+
+export function CheckEmailLinks(arg1) {
+  return window['go']['main']['App']['CheckEmailLinks'](arg1);
+}
+
 export function ClearEmails() {
   return window['go']['main']['App']['ClearEmails']();
 }
 
+export function EvaluateEmailAuth(arg1) {
+  return window['go']['main']['App']['EvaluateEmailAuth'](arg1);
+}
+
+export function GetActivity(arg1) {
+  return window['go']['main']['App']['GetActivity'](arg1);
+}
+
+export function GetDroppedNotifications() {
+  return window['go']['main']['App']['GetDroppedNotifications']();
+}
+
+export function GetEmail(arg1) {
+  return window['go']['main']['App']['GetEmail'](arg1);
+}
+
+export function GetEmailTranscript(arg1) {
+  return window['go']['main']['App']['GetEmailTranscript'](arg1);
+}
+
 export function GetEmails() {
   return window['go']['main']['App']['GetEmails']();
 }
 
+export function GetGreylist() {
+  return window['go']['main']['App']['GetGreylist']();
+}
+
 export function GetSettings() {
   return window['go']['main']['App']['GetSettings']();
 }
 
+export function GetTranscript(arg1) {
+  return window['go']['main']['App']['GetTranscript'](arg1);
+}
+
+export function GetTranscripts() {
+  return window['go']['main']['App']['GetTranscripts']();
+}
+
 export function GetVersion() {
   return window['go']['main']['App']['GetVersion']();
 }
 
+export function PreviewEmail(arg1, arg2) {
+  return window['go']['main']['App']['PreviewEmail'](arg1, arg2);
+}
+
+export function ResetGreylist() {
+  return window['go']['main']['App']['ResetGreylist']();
+}
+
+export function RestartAPIServer() {
+  return window['go']['main']['App']['RestartAPIServer']();
+}
+
 export function RestartSMTPServer() {
   return window['go']['main']['App']['RestartSMTPServer']();
 }
 
+export function SaveCalendar(arg1, arg2) {
+  return window['go']['main']['App']['SaveCalendar'](arg1, arg2);
+}
+
+export function SaveFaultRules(arg1) {
+  return window['go']['main']['App']['SaveFaultRules'](arg1);
+}
+
 export function SaveSettings(arg1) {
   return window['go']['main']['App']['SaveSettings'](arg1);
 }
+
+export function SetFaultInjectionEnabled(arg1) {
+  return window['go']['main']['App']['SetFaultInjectionEnabled'](arg1);
+}
+
+export function SignEmailDKIM(arg1, arg2) {
+  return window['go']['main']['App']['SignEmailDKIM'](arg1, arg2);
+}
@@ -1,28 +1,1871 @@
+export namespace a11y {
+	
+	export class Finding {
+	    rule: string;
+	    severity: string;
+	    message: string;
+	    line: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Finding(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.rule = source["rule"];
+	        this.severity = source["severity"];
+	        this.message = source["message"];
+	        this.line = source["line"];
+	    }
+	}
+	export class Report {
+	    errors: number;
+	    warnings: number;
+	    findings: Finding[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Report(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.errors = source["errors"];
+	        this.warnings = source["warnings"];
+	        this.findings = this.convertValues(source["findings"], Finding);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace calendar {
+	
+	export class Problem {
+	    severity: string;
+	    message: string;
+	    line: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Problem(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.severity = source["severity"];
+	        this.message = source["message"];
+	        this.line = source["line"];
+	    }
+	}
+	export class Recurrence {
+	    rule: string;
+	    freq: string;
+	    interval: number;
+	    count: number;
+	    until: string;
+	    byDay: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Recurrence(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.rule = source["rule"];
+	        this.freq = source["freq"];
+	        this.interval = source["interval"];
+	        this.count = source["count"];
+	        this.until = source["until"];
+	        this.byDay = source["byDay"];
+	    }
+	}
+	export class Person {
+	    email: string;
+	    name: string;
+	    role: string;
+	    status: string;
+	    rsvp: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Person(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.email = source["email"];
+	        this.name = source["name"];
+	        this.role = source["role"];
+	        this.status = source["status"];
+	        this.rsvp = source["rsvp"];
+	    }
+	}
+	export class Time {
+	    value: string;
+	    tzid: string;
+	    utc: boolean;
+	    allDay: boolean;
+	    floating: boolean;
+	    time: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Time(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.value = source["value"];
+	        this.tzid = source["tzid"];
+	        this.utc = source["utc"];
+	        this.allDay = source["allDay"];
+	        this.floating = source["floating"];
+	        this.time = source["time"];
+	    }
+	}
+	export class Event {
+	    uid: string;
+	    sequence: number;
+	    summary: string;
+	    description: string;
+	    location: string;
+	    status: string;
+	    recurrenceId?: Time;
+	    organizer?: Person;
+	    attendees: Person[];
+	    start?: Time;
+	    end?: Time;
+	    duration: string;
+	    recurrence: Recurrence[];
+	    exceptions: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Event(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.uid = source["uid"];
+	        this.sequence = source["sequence"];
+	        this.summary = source["summary"];
+	        this.description = source["description"];
+	        this.location = source["location"];
+	        this.status = source["status"];
+	        this.recurrenceId = this.convertValues(source["recurrenceId"], Time);
+	        this.organizer = this.convertValues(source["organizer"], Person);
+	        this.attendees = this.convertValues(source["attendees"], Person);
+	        this.start = this.convertValues(source["start"], Time);
+	        this.end = this.convertValues(source["end"], Time);
+	        this.duration = source["duration"];
+	        this.recurrence = this.convertValues(source["recurrence"], Recurrence);
+	        this.exceptions = source["exceptions"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Calendar {
+	    mediaType: string;
+	    filename: string;
+	    method: string;
+	    prodId: string;
+	    version: string;
+	    events: Event[];
+	    timeZones: string[];
+	    problems: Problem[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Calendar(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.mediaType = source["mediaType"];
+	        this.filename = source["filename"];
+	        this.method = source["method"];
+	        this.prodId = source["prodId"];
+	        this.version = source["version"];
+	        this.events = this.convertValues(source["events"], Event);
+	        this.timeZones = source["timeZones"];
+	        this.problems = this.convertValues(source["problems"], Problem);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	
+	
+
+}
+
+export namespace compat {
+	
+	export class Client {
+	    id: string;
+	    name: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Client(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	    }
+	}
+	export class ClientSummary {
+	    client: string;
+	    name: string;
+	    unsupported: string[];
+	    partial: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ClientSummary(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.client = source["client"];
+	        this.name = source["name"];
+	        this.unsupported = source["unsupported"];
+	        this.partial = source["partial"];
+	    }
+	}
+	export class Issue {
+	    feature: string;
+	    title: string;
+	    lines: number[];
+	    unsupported: string[];
+	    partial: string[];
+	    notes?: {[key: string]: string};
+	
+	    static createFrom(source: any = {}) {
+	        return new Issue(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.feature = source["feature"];
+	        this.title = source["title"];
+	        this.lines = source["lines"];
+	        this.unsupported = source["unsupported"];
+	        this.partial = source["partial"];
+	        this.notes = source["notes"];
+	    }
+	}
+	export class Report {
+	    clients: Client[];
+	    issues: Issue[];
+	    summary: ClientSummary[];
+	    passed: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Report(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.clients = this.convertValues(source["clients"], Client);
+	        this.issues = this.convertValues(source["issues"], Issue);
+	        this.summary = this.convertValues(source["summary"], ClientSummary);
+	        this.passed = source["passed"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace extract {
+	
+	export class Rule {
+	    name: string;
+	    pattern: string;
+	    target: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Rule(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.pattern = source["pattern"];
+	        this.target = source["target"];
+	    }
+	}
+	export class Config {
+	    linkPattern: string;
+	    rules: Rule[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Config(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.linkPattern = source["linkPattern"];
+	        this.rules = this.convertValues(source["rules"], Rule);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Match {
+	    extractor: string;
+	    value: string;
+	    source: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Match(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.extractor = source["extractor"];
+	        this.value = source["value"];
+	        this.source = source["source"];
+	    }
+	}
+	export class Result {
+	    code: string;
+	    link: string;
+	    matches: Match[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Result(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.code = source["code"];
+	        this.link = source["link"];
+	        this.matches = this.convertValues(source["matches"], Match);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace links {
+	
+	export class CheckResult {
+	    url: string;
+	    checked: boolean;
+	    status: number;
+	    location?: string;
+	    error?: string;
+	    ok: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new CheckResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.url = source["url"];
+	        this.checked = source["checked"];
+	        this.status = source["status"];
+	        this.location = source["location"];
+	        this.error = source["error"];
+	        this.ok = source["ok"];
+	    }
+	}
+	export class Config {
+	    internalHosts: string[];
+	    utmParams: string[];
+	    checkBaseUrl: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Config(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.internalHosts = source["internalHosts"];
+	        this.utmParams = source["utmParams"];
+	        this.checkBaseUrl = source["checkBaseUrl"];
+	    }
+	}
+	export class Link {
+	    url: string;
+	    text: string;
+	    kind: string;
+	    location: string;
+	    line: number;
+	    flags: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Link(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.url = source["url"];
+	        this.text = source["text"];
+	        this.kind = source["kind"];
+	        this.location = source["location"];
+	        this.line = source["line"];
+	        this.flags = source["flags"];
+	    }
+	}
+
+}
+
 export namespace main {
 	
-	export class Email {
+	export class APISettings {
+	    enabled: boolean;
+	    host: string;
+	    port: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new APISettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.host = source["host"];
+	        this.port = source["port"];
+	    }
+	}
+	export class Email {
+	    id: string;
+	    from: string;
+	    mailFrom: string;
+	    recipients: string[];
+	    helo: string;
+	    clientIp: string;
+	    to: string[];
+	    cc: string[];
+	    subject: string;
+	    body: string;
+	    html: string;
+	    // Go type: time
+	    timestamp: any;
+	    raw: string;
+	    parseWarnings: string[];
+	    sessionId: string;
+	    extensions: string[];
+	    dsn: smtp.DSNParams;
+	    bounceOf: string;
+	    parentId: string;
+	    report?: smtp.Report;
+	    inline: smtp.InlinePart[];
+	    calendars: calendar.Calendar[];
+	    attachments: smtp.Attachment[];
+	    attached: Email[];
+	    security?: smtp.Security;
+	    dkim: smtp.DKIMResult[];
+	    auth?: smtp.AuthResults;
+	    spam?: spam.Report;
+	    size?: smtp.SizeReport;
+	    links: links.Link[];
+	    extracted?: extract.Result;
+	    accessibility?: a11y.Report;
+	    parity?: parity.Report;
+	    tracking?: tracking.Report;
+	
+	    static createFrom(source: any = {}) {
+	        return new Email(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.from = source["from"];
+	        this.mailFrom = source["mailFrom"];
+	        this.recipients = source["recipients"];
+	        this.helo = source["helo"];
+	        this.clientIp = source["clientIp"];
+	        this.to = source["to"];
+	        this.cc = source["cc"];
+	        this.subject = source["subject"];
+	        this.body = source["body"];
+	        this.html = source["html"];
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	        this.raw = source["raw"];
+	        this.parseWarnings = source["parseWarnings"];
+	        this.sessionId = source["sessionId"];
+	        this.extensions = source["extensions"];
+	        this.dsn = this.convertValues(source["dsn"], smtp.DSNParams);
+	        this.bounceOf = source["bounceOf"];
+	        this.parentId = source["parentId"];
+	        this.report = this.convertValues(source["report"], smtp.Report);
+	        this.inline = this.convertValues(source["inline"], smtp.InlinePart);
+	        this.calendars = this.convertValues(source["calendars"], calendar.Calendar);
+	        this.attachments = this.convertValues(source["attachments"], smtp.Attachment);
+	        this.attached = this.convertValues(source["attached"], Email);
+	        this.security = this.convertValues(source["security"], smtp.Security);
+	        this.dkim = this.convertValues(source["dkim"], smtp.DKIMResult);
+	        this.auth = this.convertValues(source["auth"], smtp.AuthResults);
+	        this.spam = this.convertValues(source["spam"], spam.Report);
+	        this.size = this.convertValues(source["size"], smtp.SizeReport);
+	        this.links = this.convertValues(source["links"], links.Link);
+	        this.extracted = this.convertValues(source["extracted"], extract.Result);
+	        this.accessibility = this.convertValues(source["accessibility"], a11y.Report);
+	        this.parity = this.convertValues(source["parity"], parity.Report);
+	        this.tracking = this.convertValues(source["tracking"], tracking.Report);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class FaultSettings {
+	    enabled: boolean;
+	    rules: smtp.FaultRule[];
+	
+	    static createFrom(source: any = {}) {
+	        return new FaultSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.rules = this.convertValues(source["rules"], smtp.FaultRule);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class GreylistSettings {
+	    enabled: boolean;
+	    delaySeconds: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new GreylistSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.delaySeconds = source["delaySeconds"];
+	    }
+	}
+	export class SMTPSettings {
+	    host: string;
+	    port: number;
+	    auth: string;
+	    username: string;
+	    password: string;
+	    tls: string;
+	    limits: smtp.Limits;
+	    extensions: smtp.Extensions;
+	
+	    static createFrom(source: any = {}) {
+	        return new SMTPSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.host = source["host"];
+	        this.port = source["port"];
+	        this.auth = source["auth"];
+	        this.username = source["username"];
+	        this.password = source["password"];
+	        this.tls = source["tls"];
+	        this.limits = this.convertValues(source["limits"], smtp.Limits);
+	        this.extensions = this.convertValues(source["extensions"], smtp.Extensions);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class UISettings {
+	    theme: string;
+	    showPreview: boolean;
+	    timeFormat: string;
+	    notification: boolean;
+	    persistence: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new UISettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.theme = source["theme"];
+	        this.showPreview = source["showPreview"];
+	        this.timeFormat = source["timeFormat"];
+	        this.notification = source["notification"];
+	        this.persistence = source["persistence"];
+	    }
+	}
+	export class Settings {
+	    ui: UISettings;
+	    smtp: SMTPSettings;
+	    api: APISettings;
+	    faults: FaultSettings;
+	    greylist: GreylistSettings;
+	    bounces: smtp.BounceConfig;
+	    dkim: smtp.DKIMConfig;
+	    zone: smtp.ZoneConfig;
+	    spam: spam.Config;
+	    size: smtp.SizeConfig;
+	    links: links.Config;
+	    extract: extract.Config;
+	    smime: smtp.SMIMEConfig;
+	
+	    static createFrom(source: any = {}) {
+	        return new Settings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ui = this.convertValues(source["ui"], UISettings);
+	        this.smtp = this.convertValues(source["smtp"], SMTPSettings);
+	        this.api = this.convertValues(source["api"], APISettings);
+	        this.faults = this.convertValues(source["faults"], FaultSettings);
+	        this.greylist = this.convertValues(source["greylist"], GreylistSettings);
+	        this.bounces = this.convertValues(source["bounces"], smtp.BounceConfig);
+	        this.dkim = this.convertValues(source["dkim"], smtp.DKIMConfig);
+	        this.zone = this.convertValues(source["zone"], smtp.ZoneConfig);
+	        this.spam = this.convertValues(source["spam"], spam.Config);
+	        this.size = this.convertValues(source["size"], smtp.SizeConfig);
+	        this.links = this.convertValues(source["links"], links.Config);
+	        this.extract = this.convertValues(source["extract"], extract.Config);
+	        this.smime = this.convertValues(source["smime"], smtp.SMIMEConfig);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace parity {
+	
+	export class Chunk {
+	    op: string;
+	    text: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Chunk(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.op = source["op"];
+	        this.text = source["text"];
+	    }
+	}
+	export class Report {
+	    missingText: boolean;
+	    mismatch: boolean;
+	    similarity: number;
+	    htmlText: string;
+	    diff: Chunk[];
+	    linksOnlyInHtml: string[];
+	    linksOnlyInText: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Report(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.missingText = source["missingText"];
+	        this.mismatch = source["mismatch"];
+	        this.similarity = source["similarity"];
+	        this.htmlText = source["htmlText"];
+	        this.diff = this.convertValues(source["diff"], Chunk);
+	        this.linksOnlyInHtml = source["linksOnlyInHtml"];
+	        this.linksOnlyInText = source["linksOnlyInText"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace preview {
+	
+	export class Options {
+	    loadRemote: boolean;
+	    removeTracking: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Options(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.loadRemote = source["loadRemote"];
+	        this.removeTracking = source["removeTracking"];
+	    }
+	}
+	export class Result {
+	    html: string;
+	    remote: string[];
+	    remoteLoaded: boolean;
+	    removed: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Result(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.html = source["html"];
+	        this.remote = source["remote"];
+	        this.remoteLoaded = source["remoteLoaded"];
+	        this.removed = source["removed"];
+	    }
+	}
+
+}
+
+export namespace smtp {
+	
+	export class Attachment {
+	    filename: string;
+	    contentType: string;
+	    size: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Attachment(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.filename = source["filename"];
+	        this.contentType = source["contentType"];
+	        this.size = source["size"];
+	    }
+	}
+	export class DMARCResult {
+	    result: string;
+	    domain: string;
+	    policyDomain: string;
+	    record: string;
+	    policy: string;
+	    disposition: string;
+	    percent: number;
+	    spfAligned: boolean;
+	    dkimAligned: boolean;
+	    dkimDomain: string;
+	    reason: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new DMARCResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.result = source["result"];
+	        this.domain = source["domain"];
+	        this.policyDomain = source["policyDomain"];
+	        this.record = source["record"];
+	        this.policy = source["policy"];
+	        this.disposition = source["disposition"];
+	        this.percent = source["percent"];
+	        this.spfAligned = source["spfAligned"];
+	        this.dkimAligned = source["dkimAligned"];
+	        this.dkimDomain = source["dkimDomain"];
+	        this.reason = source["reason"];
+	    }
+	}
+	export class SPFResult {
+	    result: string;
+	    domain: string;
+	    record: string;
+	    mechanism: string;
+	    reason: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SPFResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.result = source["result"];
+	        this.domain = source["domain"];
+	        this.record = source["record"];
+	        this.mechanism = source["mechanism"];
+	        this.reason = source["reason"];
+	    }
+	}
+	export class AuthResults {
+	    spf: SPFResult;
+	    dmarc: DMARCResult;
+	
+	    static createFrom(source: any = {}) {
+	        return new AuthResults(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.spf = this.convertValues(source["spf"], SPFResult);
+	        this.dmarc = this.convertValues(source["dmarc"], DMARCResult);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class BounceConfig {
+	    enabled: boolean;
+	    recipients: string[];
+	    code: number;
+	    status: string;
+	    message: string;
+	    sendTo: string;
+	    relay: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new BounceConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.recipients = source["recipients"];
+	        this.code = source["code"];
+	        this.status = source["status"];
+	        this.message = source["message"];
+	        this.sendTo = source["sendTo"];
+	        this.relay = source["relay"];
+	    }
+	}
+	export class Certificate {
+	    subject: string;
+	    issuer: string;
+	    emails: string[];
+	    serialNumber: string;
+	    // Go type: time
+	    notBefore: any;
+	    // Go type: time
+	    notAfter: any;
+	    validity: string;
+	    selfSigned: boolean;
+	    fingerprint: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Certificate(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.subject = source["subject"];
+	        this.issuer = source["issuer"];
+	        this.emails = source["emails"];
+	        this.serialNumber = source["serialNumber"];
+	        this.notBefore = this.convertValues(source["notBefore"], null);
+	        this.notAfter = this.convertValues(source["notAfter"], null);
+	        this.validity = source["validity"];
+	        this.selfSigned = source["selfSigned"];
+	        this.fingerprint = source["fingerprint"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DKIMSigning {
+	    domain: string;
+	    selector: string;
+	    keyFile: string;
+	    canonicalization: string;
+	    headers: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new DKIMSigning(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.domain = source["domain"];
+	        this.selector = source["selector"];
+	        this.keyFile = source["keyFile"];
+	        this.canonicalization = source["canonicalization"];
+	        this.headers = source["headers"];
+	    }
+	}
+	export class DKIMKey {
+	    domain: string;
+	    selector: string;
+	    keyFile: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new DKIMKey(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.domain = source["domain"];
+	        this.selector = source["selector"];
+	        this.keyFile = source["keyFile"];
+	    }
+	}
+	export class DKIMConfig {
+	    keys: DKIMKey[];
+	    resolver: string;
+	    signing: DKIMSigning;
+	
+	    static createFrom(source: any = {}) {
+	        return new DKIMConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.keys = this.convertValues(source["keys"], DKIMKey);
+	        this.resolver = source["resolver"];
+	        this.signing = this.convertValues(source["signing"], DKIMSigning);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class DKIMResult {
+	    status: string;
+	    reason: string;
+	    domain: string;
+	    selector: string;
+	    identity: string;
+	    algorithm: string;
+	    headerCanonicalization: string;
+	    bodyCanonicalization: string;
+	    signedHeaders: string[];
+	    bodyLength: number;
+	    keySource: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new DKIMResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.status = source["status"];
+	        this.reason = source["reason"];
+	        this.domain = source["domain"];
+	        this.selector = source["selector"];
+	        this.identity = source["identity"];
+	        this.algorithm = source["algorithm"];
+	        this.headerCanonicalization = source["headerCanonicalization"];
+	        this.bodyCanonicalization = source["bodyCanonicalization"];
+	        this.signedHeaders = source["signedHeaders"];
+	        this.bodyLength = source["bodyLength"];
+	        this.keySource = source["keySource"];
+	    }
+	}
+	export class DKIMSignature {
+	    signed: string;
+	    header: string;
+	    canonicalization: string;
+	    signedHeaders: string[];
+	    coveredHeaders: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new DKIMSignature(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.signed = source["signed"];
+	        this.header = source["header"];
+	        this.canonicalization = source["canonicalization"];
+	        this.signedHeaders = source["signedHeaders"];
+	        this.coveredHeaders = source["coveredHeaders"];
+	    }
+	}
+	
+	
+	export class DSNRecipient {
+	    address: string;
+	    notify: string[];
+	    orcpt: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new DSNRecipient(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.address = source["address"];
+	        this.notify = source["notify"];
+	        this.orcpt = source["orcpt"];
+	    }
+	}
+	export class DSNParams {
+	    ret: string;
+	    envid: string;
+	    recipients: DSNRecipient[];
+	
+	    static createFrom(source: any = {}) {
+	        return new DSNParams(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ret = source["ret"];
+	        this.envid = source["envid"];
+	        this.recipients = this.convertValues(source["recipients"], DSNRecipient);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class EmbeddedImage {
+	    name: string;
+	    type: string;
+	    size: number;
+	    line: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new EmbeddedImage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.type = source["type"];
+	        this.size = source["size"];
+	        this.line = source["line"];
+	    }
+	}
+	export class Extensions {
+	    smtputf8: boolean;
+	    eightBitMime: boolean;
+	    binaryMime: boolean;
+	    chunking: boolean;
+	    dsn: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Extensions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.smtputf8 = source["smtputf8"];
+	        this.eightBitMime = source["eightBitMime"];
+	        this.binaryMime = source["binaryMime"];
+	        this.chunking = source["chunking"];
+	        this.dsn = source["dsn"];
+	    }
+	}
+	export class FaultRule {
+	    id: string;
+	    name: string;
+	    enabled: boolean;
+	    sender: string;
+	    recipient: string;
+	    subject: string;
+	    percent: number;
+	    stage: string;
+	    action: string;
+	    code: number;
+	    message: string;
+	    delayMs: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new FaultRule(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.enabled = source["enabled"];
+	        this.sender = source["sender"];
+	        this.recipient = source["recipient"];
+	        this.subject = source["subject"];
+	        this.percent = source["percent"];
+	        this.stage = source["stage"];
+	        this.action = source["action"];
+	        this.code = source["code"];
+	        this.message = source["message"];
+	        this.delayMs = source["delayMs"];
+	    }
+	}
+	export class GreylistEntry {
+	    clientIp: string;
+	    sender: string;
+	    recipient: string;
+	    // Go type: time
+	    firstSeen: any;
+	    // Go type: time
+	    lastSeen: any;
+	    rejected: number;
+	    passed: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new GreylistEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.clientIp = source["clientIp"];
+	        this.sender = source["sender"];
+	        this.recipient = source["recipient"];
+	        this.firstSeen = this.convertValues(source["firstSeen"], null);
+	        this.lastSeen = this.convertValues(source["lastSeen"], null);
+	        this.rejected = source["rejected"];
+	        this.passed = source["passed"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class InlinePart {
+	    contentId: string;
+	    contentType: string;
+	    filename: string;
+	    size: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new InlinePart(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.contentId = source["contentId"];
+	        this.contentType = source["contentType"];
+	        this.filename = source["filename"];
+	        this.size = source["size"];
+	    }
+	}
+	export class Limits {
+	    readTimeoutSeconds: number;
+	    writeTimeoutSeconds: number;
+	    idleTimeoutSeconds: number;
+	    maxMessageBytes: number;
+	    maxRecipients: number;
+	    maxConnections: number;
+	    maxLineLength: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Limits(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.readTimeoutSeconds = source["readTimeoutSeconds"];
+	        this.writeTimeoutSeconds = source["writeTimeoutSeconds"];
+	        this.idleTimeoutSeconds = source["idleTimeoutSeconds"];
+	        this.maxMessageBytes = source["maxMessageBytes"];
+	        this.maxRecipients = source["maxRecipients"];
+	        this.maxConnections = source["maxConnections"];
+	        this.maxLineLength = source["maxLineLength"];
+	    }
+	}
+	export class ReportFeedback {
+	    feedbackType: string;
+	    userAgent: string;
+	    originalMailFrom: string;
+	    originalRcptTo: string[];
+	    arrivalDate: string;
+	    sourceIp: string;
+	    reportedDomains: string[];
+	    reportedUris: string[];
+	    authenticationResults: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ReportFeedback(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.feedbackType = source["feedbackType"];
+	        this.userAgent = source["userAgent"];
+	        this.originalMailFrom = source["originalMailFrom"];
+	        this.originalRcptTo = source["originalRcptTo"];
+	        this.arrivalDate = source["arrivalDate"];
+	        this.sourceIp = source["sourceIp"];
+	        this.reportedDomains = source["reportedDomains"];
+	        this.reportedUris = source["reportedUris"];
+	        this.authenticationResults = source["authenticationResults"];
+	    }
+	}
+	export class ReportDisposition {
+	    finalRecipient: string;
+	    originalRecipient: string;
+	    disposition: string;
+	    type: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ReportDisposition(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.finalRecipient = source["finalRecipient"];
+	        this.originalRecipient = source["originalRecipient"];
+	        this.disposition = source["disposition"];
+	        this.type = source["type"];
+	    }
+	}
+	export class ReportRecipient {
+	    originalRecipient: string;
+	    finalRecipient: string;
+	    action: string;
+	    status: string;
+	    diagnosticCode: string;
+	    remoteMta: string;
+	    bounce: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ReportRecipient(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.originalRecipient = source["originalRecipient"];
+	        this.finalRecipient = source["finalRecipient"];
+	        this.action = source["action"];
+	        this.status = source["status"];
+	        this.diagnosticCode = source["diagnosticCode"];
+	        this.remoteMta = source["remoteMta"];
+	        this.bounce = source["bounce"];
+	    }
+	}
+	export class Report {
+	    type: string;
+	    reportedBy: string;
+	    originalEnvelopeId: string;
+	    recipients: ReportRecipient[];
+	    disposition?: ReportDisposition;
+	    feedback?: ReportFeedback;
+	    originalMessageId: string;
+	    originalSubject: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Report(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.type = source["type"];
+	        this.reportedBy = source["reportedBy"];
+	        this.originalEnvelopeId = source["originalEnvelopeId"];
+	        this.recipients = this.convertValues(source["recipients"], ReportRecipient);
+	        this.disposition = this.convertValues(source["disposition"], ReportDisposition);
+	        this.feedback = this.convertValues(source["feedback"], ReportFeedback);
+	        this.originalMessageId = source["originalMessageId"];
+	        this.originalSubject = source["originalSubject"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	
+	export class SMIMEConfig {
+	    trustFile: string;
+	    certFile: string;
+	    keyFile: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SMIMEConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.trustFile = source["trustFile"];
+	        this.certFile = source["certFile"];
+	        this.keyFile = source["keyFile"];
+	    }
+	}
+	
+	export class Signature {
+	    status: string;
+	    reason: string;
+	    // Go type: time
+	    signingTime?: any;
+	    signer?: Certificate;
+	    certificates: Certificate[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Signature(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.status = source["status"];
+	        this.reason = source["reason"];
+	        this.signingTime = this.convertValues(source["signingTime"], null);
+	        this.signer = this.convertValues(source["signer"], Certificate);
+	        this.certificates = this.convertValues(source["certificates"], Certificate);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Security {
+	    protocol: string;
+	    mediaType: string;
+	    signed: boolean;
+	    encrypted: boolean;
+	    signature?: Signature;
+	    decryption: string;
+	    notes: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Security(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.protocol = source["protocol"];
+	        this.mediaType = source["mediaType"];
+	        this.signed = source["signed"];
+	        this.encrypted = source["encrypted"];
+	        this.signature = this.convertValues(source["signature"], Signature);
+	        this.decryption = source["decryption"];
+	        this.notes = source["notes"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SessionLogEntry {
+	    id: string;
+	    remoteAddr: string;
+	    helo: string;
+	    // Go type: time
+	    startedAt: any;
+	    // Go type: time
+	    endedAt: any;
+	    durationMs: number;
+	    bytesIn: number;
+	    bytesOut: number;
+	    outcome: string;
+	    reason: string;
+	    emailIds: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SessionLogEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.remoteAddr = source["remoteAddr"];
+	        this.helo = source["helo"];
+	        this.startedAt = this.convertValues(source["startedAt"], null);
+	        this.endedAt = this.convertValues(source["endedAt"], null);
+	        this.durationMs = source["durationMs"];
+	        this.bytesIn = source["bytesIn"];
+	        this.bytesOut = source["bytesOut"];
+	        this.outcome = source["outcome"];
+	        this.reason = source["reason"];
+	        this.emailIds = source["emailIds"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SessionLogFilter {
+	    outcome: string;
+	    remoteAddr: string;
+	    // Go type: time
+	    since: any;
+	    limit: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SessionLogFilter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.outcome = source["outcome"];
+	        this.remoteAddr = source["remoteAddr"];
+	        this.since = this.convertValues(source["since"], null);
+	        this.limit = source["limit"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class SizeConfig {
+	    htmlLimit: number;
+	    messageLimit: number;
+	    imageLimit: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SizeConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.htmlLimit = source["htmlLimit"];
+	        this.messageLimit = source["messageLimit"];
+	        this.imageLimit = source["imageLimit"];
+	    }
+	}
+	export class SizeContributor {
+	    section: string;
+	    size: number;
+	    percent: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SizeContributor(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.section = source["section"];
+	        this.size = source["size"];
+	        this.percent = source["percent"];
+	    }
+	}
+	export class SizeWarning {
+	    budget: string;
+	    size: number;
+	    limit: number;
+	    message: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SizeWarning(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.budget = source["budget"];
+	        this.size = source["size"];
+	        this.limit = source["limit"];
+	        this.message = source["message"];
+	    }
+	}
+	export class SizeReport {
+	    html: number;
+	    message: number;
+	    images: number;
+	    embeddedImages: EmbeddedImage[];
+	    contributors: SizeContributor[];
+	    warnings: SizeWarning[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SizeReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.html = source["html"];
+	        this.message = source["message"];
+	        this.images = source["images"];
+	        this.embeddedImages = this.convertValues(source["embeddedImages"], EmbeddedImage);
+	        this.contributors = this.convertValues(source["contributors"], SizeContributor);
+	        this.warnings = this.convertValues(source["warnings"], SizeWarning);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class TranscriptLine {
+	    // Go type: time
+	    time: any;
+	    direction: string;
+	    text: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new TranscriptLine(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.time = this.convertValues(source["time"], null);
+	        this.direction = source["direction"];
+	        this.text = source["text"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Transcript {
 	    id: string;
-	    from: string;
-	    to: string[];
-	    subject: string;
-	    body: string;
-	    html: string;
+	    remoteAddr: string;
 	    // Go type: time
-	    timestamp: any;
+	    startedAt: any;
+	    // Go type: time
+	    endedAt: any;
+	    helo: string;
+	    capabilities: string[];
+	    authMechanism: string;
+	    tls: boolean;
+	    emailIds: string[];
+	    bytesIn: number;
+	    bytesOut: number;
+	    outcome: string;
+	    reason: string;
+	    errors: string[];
+	    lines: TranscriptLine[];
 	
 	    static createFrom(source: any = {}) {
-	        return new Email(source);
+	        return new Transcript(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.id = source["id"];
-	        this.from = source["from"];
-	        this.to = source["to"];
-	        this.subject = source["subject"];
-	        this.body = source["body"];
-	        this.html = source["html"];
-	        this.timestamp = this.convertValues(source["timestamp"], null);
+	        this.remoteAddr = source["remoteAddr"];
+	        this.startedAt = this.convertValues(source["startedAt"], null);
+	        this.endedAt = this.convertValues(source["endedAt"], null);
+	        this.helo = source["helo"];
+	        this.capabilities = source["capabilities"];
+	        this.authMechanism = source["authMechanism"];
+	        this.tls = source["tls"];
+	        this.emailIds = source["emailIds"];
+	        this.bytesIn = source["bytesIn"];
+	        this.bytesOut = source["bytesOut"];
+	        this.outcome = source["outcome"];
+	        this.reason = source["reason"];
+	        this.errors = source["errors"];
+	        this.lines = this.convertValues(source["lines"], TranscriptLine);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -43,60 +1886,185 @@ export namespace main {
 		    return a;
 		}
 	}
-	export class SMTPSettings {
-	    host: string;
-	    port: number;
-	    auth: string;
-	    username: string;
-	    password: string;
-	    tls: string;
+	
+	export class ZoneRecord {
+	    name: string;
+	    type: string;
+	    value: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new SMTPSettings(source);
+	        return new ZoneRecord(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.host = source["host"];
-	        this.port = source["port"];
-	        this.auth = source["auth"];
-	        this.username = source["username"];
-	        this.password = source["password"];
-	        this.tls = source["tls"];
+	        this.name = source["name"];
+	        this.type = source["type"];
+	        this.value = source["value"];
 	    }
 	}
-	export class UISettings {
-	    theme: string;
-	    showPreview: boolean;
-	    timeFormat: string;
-	    notification: boolean;
-	    persistence: boolean;
+	export class ZoneConfig {
+	    file: string;
+	    records: ZoneRecord[];
 	
 	    static createFrom(source: any = {}) {
-	        return new UISettings(source);
+	        return new ZoneConfig(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.theme = source["theme"];
-	        this.showPreview = source["showPreview"];
-	        this.timeFormat = source["timeFormat"];
-	        this.notification = source["notification"];
-	        this.persistence = source["persistence"];
+	        this.file = source["file"];
+	        this.records = this.convertValues(source["records"], ZoneRecord);
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
-	export class Settings {
-	    ui: UISettings;
-	    smtp: SMTPSettings;
+
+}
+
+export namespace spam {
+	
+	export class Config {
+	    threshold: number;
+	    rulesFile: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new Settings(source);
+	        return new Config(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.ui = this.convertValues(source["ui"], UISettings);
-	        this.smtp = this.convertValues(source["smtp"], SMTPSettings);
+	        this.threshold = source["threshold"];
+	        this.rulesFile = source["rulesFile"];
+	    }
+	}
+	export class Hit {
+	    rule: string;
+	    description: string;
+	    score: number;
+	    detail: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Hit(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.rule = source["rule"];
+	        this.description = source["description"];
+	        this.score = source["score"];
+	        this.detail = source["detail"];
+	    }
+	}
+	export class Report {
+	    score: number;
+	    threshold: number;
+	    spam: boolean;
+	    hits: Hit[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Report(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.score = source["score"];
+	        this.threshold = source["threshold"];
+	        this.spam = source["spam"];
+	        this.hits = this.convertValues(source["hits"], Hit);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace tracking {
+	
+	export class Link {
+	    url: string;
+	    destination: string;
+	    service: string;
+	    reasons: string[];
+	    line: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Link(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.url = source["url"];
+	        this.destination = source["destination"];
+	        this.service = source["service"];
+	        this.reasons = source["reasons"];
+	        this.line = source["line"];
+	    }
+	}
+	export class Pixel {
+	    url: string;
+	    service: string;
+	    reasons: string[];
+	    line: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Pixel(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.url = source["url"];
+	        this.service = source["service"];
+	        this.reasons = source["reasons"];
+	        this.line = source["line"];
+	    }
+	}
+	export class Report {
+	    pixels: Pixel[];
+	    links: Link[];
+	    services: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Report(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.pixels = this.convertValues(source["pixels"], Pixel);
+	        this.links = this.convertValues(source["links"], Link);
+	        this.services = source["services"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
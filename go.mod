@@ -7,6 +7,7 @@ toolchain go1.23.2
 require (
 	github.com/emersion/go-smtp v0.21.3
 	github.com/google/uuid v1.6.0
+	github.com/smallstep/pkcs7 v0.2.1
 	github.com/wailsapp/wails/v2 v2.9.2
 )
 
@@ -18,7 +19,7 @@ require (
 
 require (
 	github.com/bep/debounce v1.2.1 // indirect
-	github.com/emersion/go-sasl v0.0.0-20200509203442-7bfe0ed36a21 // indirect
+	github.com/emersion/go-sasl v0.0.0-20200509203442-7bfe0ed36a21
 	github.com/gen2brain/beeep v0.0.0-20240516210008-9c006672e7f4
 	github.com/go-ole/go-ole v1.2.6 // indirect
 	github.com/godbus/dbus/v5 v5.1.0 // indirect
@@ -40,11 +41,11 @@ require (
 	github.com/valyala/fasttemplate v1.2.2 // indirect
 	github.com/wailsapp/go-webview2 v1.0.16 // indirect
 	github.com/wailsapp/mimetype v1.4.1 // indirect
-	golang.org/x/crypto v0.23.0 // indirect
+	golang.org/x/crypto v0.33.0 // indirect
 	golang.org/x/exp v0.0.0-20230522175609-2e198f4a06a1 // indirect
-	golang.org/x/net v0.25.0 // indirect
-	golang.org/x/sys v0.20.0 // indirect
-	golang.org/x/text v0.15.0 // indirect
+	golang.org/x/net v0.25.0
+	golang.org/x/sys v0.30.0 // indirect
+	golang.org/x/text v0.22.0
 )
 
 // replace github.com/wailsapp/wails/v2 v2.9.2 => /home/watzon/go/pkg/mod
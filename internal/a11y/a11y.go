@@ -0,0 +1,418 @@
+package a11y
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/watzon/postpilot/internal/tracking"
+	"golang.org/x/net/html"
+)
+
+// Package a11y lints the HTML of a message for common accessibility
+// problems: missing alt text and lang, layout tables announced as data
+// tables, low contrast, tiny text and links that don't say where they go
+
+// Severities of findings
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Rules a finding can come from
+const (
+	RuleImageAlt    = "image-alt"
+	RuleLayoutTable = "layout-table"
+	RuleHTMLLang    = "html-lang"
+	RuleContrast    = "color-contrast"
+	RuleFontSize    = "font-size"
+	RuleLinkText    = "link-text"
+	RuleLinkName    = "link-name"
+)
+
+// Thresholds, following WCAG 2 AA for contrast
+const (
+	minFontSizePx    = 12.0
+	baseFontSizePx   = 16.0
+	minContrast      = 4.5
+	minLargeContrast = 3.0
+)
+
+// vagueLinkText is link text that says nothing about the destination
+var vagueLinkText = regexp.MustCompile(`(?i)^(click|click here|here|this|link|this link|more|read more|learn more|see more|details|more details|go|continue|info|more info)[.!]?$`)
+
+// voidElements never have content or an end tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true,
+	"img": true, "input": true, "link": true, "meta": true, "source": true,
+	"track": true, "wbr": true,
+}
+
+// Report lists the accessibility problems of an HTML part
+type Report struct {
+	// Number of findings with severity error
+	Errors int `json:"errors"`
+	// Number of findings with severity warning
+	Warnings int `json:"warnings"`
+	// Findings in document order
+	Findings []Finding `json:"findings"`
+}
+
+// Finding is an accessibility problem in an HTML part
+type Finding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	// Line of the HTML part, 1-based
+	Line int `json:"line"`
+}
+
+// element is an open element with the styles that cascade from it
+type element struct {
+	name string
+	line int
+	// Text color, background and font size set on the element, if any
+	color    *rgb
+	bg       *rgb
+	fontSize float64
+	bold     bool
+	// Set once a contrast finding was reported for text styled by this
+	// element
+	reported bool
+}
+
+// table tracks whether an open table has header cells
+type table struct {
+	line       int
+	presenting bool
+	headers    bool
+}
+
+// link is an open <a> element collecting its accessible name
+type link struct {
+	line int
+	text strings.Builder
+	alt  string
+	// aria-label or title
+	label string
+}
+
+// Check lints an HTML part. An empty part has no findings.
+func Check(s string) *Report {
+	report := &Report{Findings: []Finding{}}
+	if strings.TrimSpace(s) == "" {
+		return report
+	}
+
+	add := func(rule, severity string, line int, format string, args ...interface{}) {
+		report.Findings = append(report.Findings, Finding{
+			Rule:     rule,
+			Severity: severity,
+			Message:  fmt.Sprintf(format, args...),
+			Line:     line,
+		})
+		if severity == SeverityError {
+			report.Errors++
+		} else {
+			report.Warnings++
+		}
+	}
+
+	var stack []*element
+	var tables []*table
+	var open *link
+	sawHTML := false
+	skip := 0
+
+	line := 1
+	z := html.NewTokenizer(strings.NewReader(s))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		start := line
+		line += strings.Count(string(z.Raw()), "\n")
+
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			attrs := attrMap(t)
+
+			switch t.Data {
+			case "html":
+				sawHTML = true
+				if strings.TrimSpace(attrs["lang"]) == "" {
+					add(RuleHTMLLang, SeverityWarning, start, "<html> has no lang attribute, so screen readers may use the wrong language")
+				}
+			case "head", "style", "script", "title":
+				if tt == html.StartTagToken {
+					skip++
+				}
+			case "img":
+				alt, hasAlt := attrs["alt"]
+				if !hasAlt && !isHidden(attrs) {
+					add(RuleImageAlt, SeverityError, start, "image %s has no alt attribute; use alt=\"\" if it is decorative", shorten(attrs["src"]))
+				}
+				if open != nil && open.alt == "" {
+					open.alt = strings.TrimSpace(alt)
+				}
+			case "table":
+				role := strings.ToLower(strings.TrimSpace(attrs["role"]))
+				tables = append(tables, &table{line: start, presenting: role == "presentation" || role == "none"})
+			case "th":
+				if len(tables) > 0 {
+					tables[len(tables)-1].headers = true
+				}
+			case "a":
+				if _, ok := attrs["href"]; ok && tt == html.StartTagToken {
+					label := attrs["aria-label"]
+					if label == "" {
+						label = attrs["title"]
+					}
+					open = &link{line: start, label: strings.TrimSpace(label)}
+				}
+			}
+
+			el := &element{name: t.Data, line: start}
+			styles := parseStyle(attrs["style"])
+			if c, ok := parseColor(styles["color"]); ok {
+				el.color = c
+			} else if c, ok := parseColor(attrs["color"]); ok && t.Data == "font" {
+				el.color = c
+			}
+			if c, ok := parseColor(styles["background-color"]); ok {
+				el.bg = c
+			} else if c, ok := parseColor(firstColor(styles["background"])); ok {
+				el.bg = c
+			} else if c, ok := parseColor(attrs["bgcolor"]); ok {
+				el.bg = c
+			}
+			if v, ok := styles["font-size"]; ok {
+				if px, ok := fontSizePx(v, inheritedFontSize(stack)); ok {
+					el.fontSize = px
+					if px < minFontSizePx {
+						add(RuleFontSize, SeverityWarning, start, "font size %s is below %gpx", strings.TrimSpace(v), minFontSizePx)
+					}
+				}
+			} else if size, ok := attrs["size"]; ok && t.Data == "font" {
+				if n, err := strconv.Atoi(strings.TrimSpace(size)); err == nil && n <= 1 {
+					el.fontSize = 10
+					add(RuleFontSize, SeverityWarning, start, "<font size=\"%s\"> is below %gpx", size, minFontSizePx)
+				}
+			}
+			weight := strings.ToLower(styles["font-weight"])
+			n, _ := strconv.Atoi(weight)
+			el.bold = t.Data == "b" || t.Data == "strong" || t.Data == "th" ||
+				weight == "bold" || weight == "bolder" || n >= 600
+
+			if tt == html.StartTagToken && !voidElements[t.Data] {
+				stack = append(stack, el)
+			}
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "head", "style", "script", "title":
+				if skip > 0 {
+					skip--
+				}
+			case "table":
+				if n := len(tables); n > 0 {
+					t := tables[n-1]
+					tables = tables[:n-1]
+					if !t.presenting && !t.headers {
+						add(RuleLayoutTable, SeverityWarning, t.line, "layout table without role=\"presentation\"; screen readers will announce its rows and columns")
+					}
+				}
+			case "a":
+				if open != nil {
+					checkLink(open, add)
+					open = nil
+				}
+			}
+			// Pop up to the matching element, closing any left open inside it
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i].name == string(name) {
+					stack = stack[:i]
+					break
+				}
+			}
+
+		case html.TextToken:
+			if skip > 0 {
+				continue
+			}
+			text := strings.TrimSpace(string(z.Text()))
+			if text == "" {
+				continue
+			}
+			if open != nil {
+				open.text.WriteString(text)
+				open.text.WriteByte(' ')
+			}
+			checkContrast(stack, add)
+		}
+	}
+
+	if open != nil {
+		checkLink(open, add)
+	}
+	if !sawHTML {
+		add(RuleHTMLLang, SeverityWarning, 1, "no <html> element with a lang attribute, so screen readers may use the wrong language")
+	}
+
+	// Contrast findings are raised at the text, which may come well after
+	// the element that set the colors
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		return report.Findings[i].Line < report.Findings[j].Line
+	})
+	return report
+}
+
+type addFunc func(rule, severity string, line int, format string, args ...interface{})
+
+func checkLink(l *link, add addFunc) {
+	name := strings.Join(strings.Fields(l.text.String()), " ")
+	if name == "" {
+		name = l.alt
+	}
+	if name == "" {
+		name = l.label
+	}
+
+	switch {
+	case name == "":
+		add(RuleLinkName, SeverityError, l.line, "link has no text, alt text or aria-label")
+	case vagueLinkText.MatchString(name):
+		add(RuleLinkText, SeverityWarning, l.line, "link text %q doesn't describe where the link goes", name)
+	}
+}
+
+// checkContrast compares the text and background colors in effect for
+// text inside the innermost element of stack
+func checkContrast(stack []*element, add addFunc) {
+	fg, bg := &rgb{0, 0, 0}, &rgb{255, 255, 255}
+	fgAt, bgAt := -1, -1
+	for i := len(stack) - 1; i >= 0 && (fgAt < 0 || bgAt < 0); i-- {
+		if fgAt < 0 && stack[i].color != nil {
+			fg, fgAt = stack[i].color, i
+		}
+		if bgAt < 0 && stack[i].bg != nil {
+			bg, bgAt = stack[i].bg, i
+		}
+	}
+	if fgAt < 0 && bgAt < 0 {
+		return
+	}
+
+	// Report against the innermost element that set a color, once
+	el := stack[max(fgAt, bgAt)]
+	if el.reported {
+		return
+	}
+
+	size, bold := inheritedFontSize(stack), false
+	for _, e := range stack {
+		bold = bold || e.bold
+	}
+	required := minContrast
+	if size >= 24 || (bold && size >= 18.66) {
+		required = minLargeContrast
+	}
+
+	if ratio := contrast(fg, bg); ratio < required {
+		el.reported = true
+		add(RuleContrast, SeverityError, el.line, "text color %s on %s has a contrast ratio of %.2f:1, below %.1f:1",
+			fg, bg, ratio, required)
+	}
+}
+
+func inheritedFontSize(stack []*element) float64 {
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i].fontSize > 0 {
+			return stack[i].fontSize
+		}
+	}
+	return baseFontSizePx
+}
+
+// fontSizePx converts a CSS font size to pixels. Relative sizes are taken
+// relative to parent; keywords other than the small ones are ignored.
+func fontSizePx(v string, parent float64) (float64, bool) {
+	v = strings.ToLower(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(v), "!important")))
+	switch v {
+	case "xx-small":
+		return 9, true
+	case "x-small":
+		return 10, true
+	case "small":
+		return 13, true
+	}
+
+	units := []struct {
+		suffix string
+		factor float64
+	}{
+		{"px", 1}, {"pt", 4.0 / 3}, {"rem", baseFontSizePx}, {"em", parent}, {"%", parent / 100},
+	}
+	for _, u := range units {
+		if strings.HasSuffix(v, u.suffix) {
+			n, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(v, u.suffix)), 64)
+			if err != nil {
+				return 0, false
+			}
+			return n * u.factor, true
+		}
+	}
+	return 0, false
+}
+
+// parseStyle splits a style attribute into lower-case properties and
+// their values
+func parseStyle(style string) map[string]string {
+	props := make(map[string]string)
+	for _, decl := range strings.Split(style, ";") {
+		name, value, ok := strings.Cut(decl, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if i := strings.Index(strings.ToLower(value), "!important"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		props[strings.ToLower(strings.TrimSpace(name))] = value
+	}
+	return props
+}
+
+// isHidden reports whether screen readers skip an image: it is aria-hidden,
+// or a tracking pixel that is hidden or 1x1
+func isHidden(attrs map[string]string) bool {
+	if attrs["aria-hidden"] == "true" {
+		return true
+	}
+	p := tracking.CheckImage(attrs)
+	return p != nil && (slices.Contains(p.Reasons, tracking.ReasonTinyImage) || slices.Contains(p.Reasons, tracking.ReasonHiddenImage))
+}
+
+func attrMap(t html.Token) map[string]string {
+	attrs := make(map[string]string, len(t.Attr))
+	for _, a := range t.Attr {
+		attrs[a.Key] = a.Val
+	}
+	return attrs
+}
+
+func shorten(s string) string {
+	if s == "" {
+		return "(no src)"
+	}
+	if len(s) > 60 {
+		return s[:57] + "..."
+	}
+	return s
+}
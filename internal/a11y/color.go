@@ -0,0 +1,125 @@
+package a11y
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// rgb is an opaque sRGB color
+type rgb struct {
+	r, g, b uint8
+}
+
+func (c *rgb) String() string {
+	return fmt.Sprintf("#%02x%02x%02x", c.r, c.g, c.b)
+}
+
+// namedColors are the CSS color keywords common in email markup
+var namedColors = map[string]rgb{
+	"black": {0, 0, 0}, "white": {255, 255, 255}, "red": {255, 0, 0},
+	"green": {0, 128, 0}, "blue": {0, 0, 255}, "yellow": {255, 255, 0},
+	"orange": {255, 165, 0}, "purple": {128, 0, 128}, "gray": {128, 128, 128},
+	"grey": {128, 128, 128}, "silver": {192, 192, 192}, "lightgray": {211, 211, 211},
+	"lightgrey": {211, 211, 211}, "darkgray": {169, 169, 169}, "darkgrey": {169, 169, 169},
+	"gainsboro": {220, 220, 220}, "whitesmoke": {245, 245, 245}, "navy": {0, 0, 128},
+	"maroon": {128, 0, 0}, "teal": {0, 128, 128}, "olive": {128, 128, 0},
+	"lime": {0, 255, 0}, "aqua": {0, 255, 255}, "cyan": {0, 255, 255},
+	"fuchsia": {255, 0, 255}, "magenta": {255, 0, 255}, "pink": {255, 192, 203},
+	"beige": {245, 245, 220}, "ivory": {255, 255, 240},
+}
+
+// parseColor parses a hex, rgb() or named color. Transparent colors and
+// keywords like inherit are not colors.
+func parseColor(s string) (*rgb, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return nil, false
+	}
+
+	if c, ok := namedColors[s]; ok {
+		return &c, true
+	}
+
+	if strings.HasPrefix(s, "#") {
+		hex := s[1:]
+		if len(hex) == 3 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		if len(hex) != 6 {
+			return nil, false
+		}
+		n, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return nil, false
+		}
+		return &rgb{uint8(n >> 16), uint8(n >> 8), uint8(n)}, true
+	}
+
+	if strings.HasPrefix(s, "rgb(") || strings.HasPrefix(s, "rgba(") {
+		args := s[strings.Index(s, "(")+1:]
+		args = strings.TrimSuffix(args, ")")
+		parts := strings.FieldsFunc(args, func(r rune) bool { return r == ',' || r == ' ' || r == '/' })
+		if len(parts) < 3 {
+			return nil, false
+		}
+		if len(parts) == 4 {
+			// Translucent colors depend on what is behind them
+			alpha, err := strconv.ParseFloat(strings.TrimSuffix(parts[3], "%"), 64)
+			if strings.HasSuffix(parts[3], "%") {
+				alpha /= 100
+			}
+			if err != nil || alpha < 1 {
+				return nil, false
+			}
+		}
+		var c [3]uint8
+		for i := 0; i < 3; i++ {
+			v := parts[i]
+			f, err := strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64)
+			if err != nil {
+				return nil, false
+			}
+			if strings.HasSuffix(v, "%") {
+				f = f * 255 / 100
+			}
+			c[i] = uint8(math.Max(0, math.Min(255, math.Round(f))))
+		}
+		return &rgb{c[0], c[1], c[2]}, true
+	}
+
+	return nil, false
+}
+
+// firstColor returns the first token of a background shorthand that is a
+// color
+func firstColor(background string) string {
+	for _, token := range strings.Fields(background) {
+		if _, ok := parseColor(token); ok {
+			return token
+		}
+	}
+	return ""
+}
+
+// contrast returns the WCAG 2 contrast ratio of two colors
+func contrast(a, b *rgb) float64 {
+	la, lb := luminance(a), luminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// luminance returns the relative luminance of a color
+func luminance(c *rgb) float64 {
+	channel := func(v uint8) float64 {
+		s := float64(v) / 255
+		if s <= 0.03928 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	return 0.2126*channel(c.r) + 0.7152*channel(c.g) + 0.0722*channel(c.b)
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Package api implements a small JSON HTTP API so that tests and scripts
+// can inspect captured emails without going through the UI
+
+// HandlerFunc handles an API request. The returned value is encoded as JSON;
+// errors are reported with the status code of an *Error, or 500 otherwise.
+type HandlerFunc func(r *http.Request) (interface{}, error)
+
+// Error is an API error with an HTTP status code
+type Error struct {
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// NotFound returns a 404 error
+func NotFound(format string, args ...interface{}) error {
+	return &Error{Status: http.StatusNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+// BadRequest returns a 400 error
+func BadRequest(format string, args ...interface{}) error {
+	return &Error{Status: http.StatusBadRequest, Message: fmt.Sprintf(format, args...)}
+}
+
+// File is a handler result that is sent as a download instead of JSON
+type File struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// Server represents the HTTP API server
+type Server struct {
+	// Address to listen on in host:port form
+	addr string
+	// Host the server was configured to listen on
+	host string
+	// Request multiplexer holding all registered routes
+	mux *http.ServeMux
+	// Underlying HTTP server, set while running
+	server *http.Server
+}
+
+// NewServer creates an API server listening on host and port
+func NewServer(host string, port int) *Server {
+	return &Server{
+		addr: net.JoinHostPort(host, fmt.Sprint(port)),
+		host: host,
+		mux:  http.NewServeMux(),
+	}
+}
+
+// Handle registers a JSON handler for GET requests to pattern
+func (s *Server) Handle(pattern string, h HandlerFunc) {
+	s.HandleMethod(http.MethodGet, pattern, h)
+}
+
+// HandleMethod registers a JSON handler for requests to pattern with the
+// given method
+func (s *Server) HandleMethod(method, pattern string, h HandlerFunc) {
+	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			writeError(w, &Error{Status: http.StatusMethodNotAllowed, Message: "method not allowed"})
+			return
+		}
+
+		v, err := h(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if f, ok := v.(*File); ok {
+			writeFile(w, f)
+			return
+		}
+		writeJSON(w, http.StatusOK, v)
+	})
+}
+
+// HandleRaw registers a plain http.Handler, for responses that aren't JSON
+func (s *Server) HandleRaw(pattern string, h http.Handler) {
+	s.mux.Handle(pattern, h)
+}
+
+// checkHost rejects requests whose Host header names neither a local
+// address nor the configured host. A web page that rebinds its own domain
+// to 127.0.0.1 would otherwise be able to read captured emails.
+func (s *Server) checkHost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.allowedHost(r.Host) {
+			writeError(w, &Error{Status: http.StatusForbidden, Message: fmt.Sprintf("host %q is not allowed", r.Host)})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowedHost reports whether a Host header is localhost, an IP address
+// or the configured host. DNS rebinding needs a domain name, so IP
+// addresses are safe to accept when listening on all interfaces.
+func (s *Server) allowedHost(hostport string) bool {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+
+	if strings.EqualFold(host, "localhost") || net.ParseIP(host) != nil {
+		return true
+	}
+	return s.host != "" && strings.EqualFold(host, s.host)
+}
+
+// Start begins serving the API in a separate goroutine
+func (s *Server) Start() error {
+	l, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	s.server = &http.Server{
+		Handler:           s.checkHost(s.mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := s.server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("API server error: %v", err)
+		}
+	}()
+	log.Printf("API server listening on %s", s.addr)
+	return nil
+}
+
+// Stop shuts the API server down, waiting briefly for active requests
+func (s *Server) Stop() error {
+	if s.server == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.server.Shutdown(ctx)
+}
+
+// PathParams splits the part of the request path after prefix into its
+// segments, e.g. "/api/emails/abc/transcript" with prefix "/api/emails/"
+// yields ["abc", "transcript"]
+func PathParams(r *http.Request, prefix string) []string {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/")
+	if rest == "" {
+		return nil
+	}
+	return strings.Split(rest, "/")
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to encode API response: %v", err)
+	}
+}
+
+func writeFile(w http.ResponseWriter, f *File) {
+	w.Header().Set("Content-Type", f.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": f.Name}))
+	w.WriteHeader(http.StatusOK)
+	w.Write(f.Data)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		status = apiErr.Status
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,499 @@
+package calendar
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	// Time zones must resolve on systems without a zoneinfo database
+	_ "time/tzdata"
+)
+
+// Package calendar parses iCalendar (RFC 5545) invites into structured
+// events and flags the mistakes that make Outlook or Google Calendar
+// reject or misplace them
+
+// Severities of problems
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Source describes the MIME part a calendar was found in
+type Source struct {
+	// text/calendar, application/ics or the type of an .ics attachment
+	MediaType string
+	// method parameter of the Content-Type
+	Method   string
+	Filename string
+}
+
+// Calendar is a parsed VCALENDAR object
+type Calendar struct {
+	MediaType string `json:"mediaType"`
+	Filename  string `json:"filename"`
+	// REQUEST, CANCEL, REPLY, PUBLISH...
+	Method  string  `json:"method"`
+	ProdID  string  `json:"prodId"`
+	Version string  `json:"version"`
+	Events  []Event `json:"events"`
+	// TZIDs defined by VTIMEZONE components
+	TimeZones []string  `json:"timeZones"`
+	Problems  []Problem `json:"problems"`
+}
+
+// Event is a VEVENT
+type Event struct {
+	UID         string `json:"uid"`
+	Sequence    int    `json:"sequence"`
+	Summary     string `json:"summary"`
+	Description string `json:"description"`
+	Location    string `json:"location"`
+	Status      string `json:"status"`
+	// Set on changes to a single occurrence of a recurring event
+	RecurrenceID *Time    `json:"recurrenceId"`
+	Organizer    *Person  `json:"organizer"`
+	Attendees    []Person `json:"attendees"`
+	Start        *Time    `json:"start"`
+	End          *Time    `json:"end"`
+	// DURATION as written, e.g. PT1H, if given instead of DTEND
+	Duration   string       `json:"duration"`
+	Recurrence []Recurrence `json:"recurrence"`
+	// EXDATE values as written
+	Exceptions []string `json:"exceptions"`
+
+	// Line of BEGIN:VEVENT
+	line int
+}
+
+// Person is an ORGANIZER or ATTENDEE
+type Person struct {
+	Email string `json:"email"`
+	// CN parameter
+	Name string `json:"name"`
+	// ROLE, PARTSTAT and RSVP parameters, for attendees
+	Role   string `json:"role"`
+	Status string `json:"status"`
+	RSVP   bool   `json:"rsvp"`
+}
+
+// Time is a DATE or DATE-TIME value
+type Time struct {
+	// Value as written, e.g. 20261020T100000
+	Value string `json:"value"`
+	// Time zone from the TZID parameter
+	TZID string `json:"tzid"`
+	// The value ends in Z
+	UTC bool `json:"utc"`
+	// The value is a DATE, as for all-day events
+	AllDay bool `json:"allDay"`
+	// Floating times have no time zone and are shown in local time
+	Floating bool `json:"floating"`
+	// The instant in RFC 3339 form, if the time zone is known
+	Time string `json:"time"`
+
+	at time.Time
+}
+
+// Recurrence is an RRULE
+type Recurrence struct {
+	// The rule as written, e.g. FREQ=WEEKLY;BYDAY=MO,WE
+	Rule     string   `json:"rule"`
+	Freq     string   `json:"freq"`
+	Interval int      `json:"interval"`
+	Count    int      `json:"count"`
+	Until    string   `json:"until"`
+	ByDay    []string `json:"byDay"`
+}
+
+// Problem is something in a calendar that clients may reject
+type Problem struct {
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	// Line of the calendar data, 1-based; 0 if it applies to the whole
+	// calendar
+	Line int `json:"line"`
+}
+
+// frequencies are the valid FREQ values of an RRULE
+var frequencies = map[string]bool{
+	"SECONDLY": true, "MINUTELY": true, "HOURLY": true, "DAILY": true,
+	"WEEKLY": true, "MONTHLY": true, "YEARLY": true,
+}
+
+// Parse parses an iCalendar object found in source
+func Parse(data []byte, source Source) *Calendar {
+	c := &Calendar{
+		MediaType: source.MediaType,
+		Filename:  source.Filename,
+		Events:    []Event{},
+		TimeZones: []string{},
+		Problems:  []Problem{},
+	}
+	add := func(severity string, line int, format string, args ...interface{}) {
+		c.Problems = append(c.Problems, Problem{Severity: severity, Message: fmt.Sprintf(format, args...), Line: line})
+	}
+
+	lines, starts, long, bareLF := unfold(string(data))
+	if bareLF {
+		add(SeverityWarning, 0, "lines end in LF instead of CRLF")
+	}
+	if long > 0 {
+		add(SeverityWarning, 0, "lines longer than %d octets should be folded (%d found)", maxLineOctets, long)
+	}
+
+	// Properties of the event being read, and the open components
+	var event *Event
+	var seen map[string]bool
+	var stack []string
+	sawCalendar := false
+	usedTZIDs := make(map[string]int)
+
+	for i, l := range lines {
+		p, ok := parseLine(l, starts[i])
+		if !ok {
+			add(SeverityError, starts[i], "malformed line %q", truncate(l))
+			continue
+		}
+
+		switch p.name {
+		case "BEGIN":
+			component := strings.ToUpper(p.value)
+			if len(stack) == 0 && component != "VCALENDAR" {
+				add(SeverityError, p.line, "BEGIN:%s outside of VCALENDAR", component)
+			}
+			if component == "VCALENDAR" {
+				sawCalendar = true
+			}
+			stack = append(stack, component)
+			if component == "VEVENT" && len(stack) == 2 {
+				event = &Event{Attendees: []Person{}, Recurrence: []Recurrence{}, Exceptions: []string{}, line: p.line}
+				seen = make(map[string]bool)
+			}
+			continue
+		case "END":
+			component := strings.ToUpper(p.value)
+			if len(stack) == 0 || stack[len(stack)-1] != component {
+				add(SeverityError, p.line, "END:%s without matching BEGIN", component)
+				continue
+			}
+			stack = stack[:len(stack)-1]
+			if component == "VEVENT" && event != nil {
+				checkEvent(event, seen, add)
+				c.Events = append(c.Events, *event)
+				event = nil
+			}
+			continue
+		}
+
+		if len(stack) == 0 {
+			add(SeverityError, p.line, "%s outside of VCALENDAR", p.name)
+			continue
+		}
+		if tzid := p.param("TZID"); tzid != "" {
+			if _, ok := usedTZIDs[tzid]; !ok {
+				usedTZIDs[tzid] = p.line
+			}
+		}
+
+		switch current := stack[len(stack)-1]; {
+		case current == "VCALENDAR":
+			switch p.name {
+			case "METHOD":
+				c.Method = strings.ToUpper(p.value)
+			case "PRODID":
+				c.ProdID = p.value
+			case "VERSION":
+				c.Version = p.value
+			}
+		case current == "VTIMEZONE" && p.name == "TZID":
+			c.TimeZones = append(c.TimeZones, p.value)
+		case current == "VEVENT" && event != nil:
+			seen[p.name] = true
+			parseEventProperty(event, p, add)
+		}
+	}
+
+	for _, component := range stack {
+		add(SeverityError, 0, "BEGIN:%s is never closed", component)
+	}
+	if !sawCalendar {
+		add(SeverityError, 0, "no VCALENDAR object")
+		return c
+	}
+
+	if c.Version != "2.0" {
+		add(SeverityError, 0, "VERSION must be 2.0, got %q", c.Version)
+	}
+	if c.ProdID == "" {
+		add(SeverityError, 0, "PRODID is missing")
+	}
+	if len(c.Events) == 0 {
+		add(SeverityWarning, 0, "calendar has no VEVENT")
+	}
+	checkMethod(c, source, add)
+
+	defined := make(map[string]bool)
+	for _, tz := range c.TimeZones {
+		defined[tz] = true
+	}
+	for tzid, line := range usedTZIDs {
+		if defined[tzid] {
+			continue
+		}
+		if _, err := time.LoadLocation(tzid); err != nil {
+			add(SeverityError, line, "unknown time zone %q has no VTIMEZONE", tzid)
+		} else {
+			add(SeverityWarning, line, "time zone %q has no VTIMEZONE; Outlook may show the wrong time", tzid)
+		}
+	}
+
+	sort.SliceStable(c.Problems, func(i, j int) bool {
+		return c.Problems[i].Line < c.Problems[j].Line
+	})
+	return c
+}
+
+type addFunc func(severity string, line int, format string, args ...interface{})
+
+func parseEventProperty(e *Event, p *property, add addFunc) {
+	switch p.name {
+	case "UID":
+		e.UID = p.value
+	case "SEQUENCE":
+		n, err := strconv.Atoi(strings.TrimSpace(p.value))
+		if err != nil || n < 0 {
+			add(SeverityError, p.line, "SEQUENCE %q is not a non-negative integer", p.value)
+		}
+		e.Sequence = n
+	case "SUMMARY":
+		e.Summary = unescape(p.value)
+	case "DESCRIPTION":
+		e.Description = unescape(p.value)
+	case "LOCATION":
+		e.Location = unescape(p.value)
+	case "STATUS":
+		e.Status = strings.ToUpper(p.value)
+	case "ORGANIZER":
+		person := parsePerson(p)
+		e.Organizer = &person
+	case "ATTENDEE":
+		e.Attendees = append(e.Attendees, parsePerson(p))
+	case "DTSTART", "DTEND", "RECURRENCE-ID":
+		t, err := parseTime(p)
+		if err != nil {
+			add(SeverityError, p.line, "%s: %v", p.name, err)
+			return
+		}
+		switch p.name {
+		case "DTSTART":
+			e.Start = t
+		case "DTEND":
+			e.End = t
+		default:
+			e.RecurrenceID = t
+		}
+	case "DURATION":
+		e.Duration = p.value
+		if !validDuration(p.value) {
+			add(SeverityError, p.line, "DURATION %q is not a valid duration", p.value)
+		}
+	case "RRULE":
+		r, err := parseRecurrence(p.value)
+		if err != nil {
+			add(SeverityError, p.line, "RRULE: %v", err)
+		}
+		e.Recurrence = append(e.Recurrence, r)
+	case "EXDATE":
+		e.Exceptions = append(e.Exceptions, strings.Split(p.value, ",")...)
+	}
+}
+
+func checkEvent(e *Event, seen map[string]bool, add addFunc) {
+	line := e.line
+	if e.UID == "" {
+		add(SeverityError, line, "VEVENT has no UID, so updates and cancellations can't be matched to it")
+	}
+	if !seen["DTSTAMP"] {
+		add(SeverityWarning, line, "VEVENT has no DTSTAMP")
+	}
+	if !seen["DTSTART"] {
+		add(SeverityError, line, "VEVENT has no DTSTART")
+	}
+	if seen["DTEND"] && seen["DURATION"] {
+		add(SeverityError, line, "VEVENT has both DTEND and DURATION")
+	}
+	if e.Start != nil && e.End != nil {
+		if e.Start.AllDay != e.End.AllDay {
+			add(SeverityError, line, "DTSTART and DTEND must both be dates or both be date-times")
+		} else if orderable(e.Start, e.End) && e.End.at.Before(e.Start.at) {
+			add(SeverityError, line, "DTEND is before DTSTART")
+		}
+	}
+}
+
+// checkMethod checks METHOD against what the event and the MIME part need
+// to be treated as an invite
+func checkMethod(c *Calendar, source Source, add addFunc) {
+	if source.MediaType == "text/calendar" {
+		switch {
+		case c.Method != "" && source.Method == "":
+			add(SeverityWarning, 0, "Content-Type has no method parameter; Outlook shows the invite as an attachment")
+		case source.Method != "" && !strings.EqualFold(source.Method, c.Method):
+			add(SeverityError, 0, "Content-Type method=%s doesn't match METHOD:%s", source.Method, c.Method)
+		}
+	}
+
+	switch c.Method {
+	case "REQUEST", "CANCEL", "ADD", "DECLINECOUNTER":
+		for _, e := range c.Events {
+			if e.Organizer == nil {
+				add(SeverityError, e.line, "METHOD:%s event has no ORGANIZER; Outlook and Google won't process it", c.Method)
+			}
+			if len(e.Attendees) == 0 && c.Method == "REQUEST" {
+				add(SeverityWarning, e.line, "METHOD:REQUEST event has no ATTENDEE")
+			}
+		}
+	}
+}
+
+func parsePerson(p *property) Person {
+	email := p.value
+	if strings.HasPrefix(strings.ToLower(email), "mailto:") {
+		email = email[len("mailto:"):]
+	}
+	return Person{
+		Email:  email,
+		Name:   p.param("CN"),
+		Role:   p.param("ROLE"),
+		Status: p.param("PARTSTAT"),
+		RSVP:   strings.EqualFold(p.param("RSVP"), "TRUE"),
+	}
+}
+
+// parseTime parses a DATE or DATE-TIME value with its TZID
+func parseTime(p *property) (*Time, error) {
+	t := &Time{Value: p.value, TZID: p.param("TZID")}
+	v := strings.TrimSpace(p.value)
+
+	if strings.EqualFold(p.param("VALUE"), "DATE") || len(v) == 8 {
+		d, err := time.Parse("20060102", v)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a valid DATE", p.value)
+		}
+		t.AllDay = true
+		t.Time = d.Format("2006-01-02")
+		t.at = d
+		return t, nil
+	}
+
+	loc := time.UTC
+	switch {
+	case strings.HasSuffix(v, "Z"):
+		t.UTC = true
+		v = strings.TrimSuffix(v, "Z")
+		if t.TZID != "" {
+			return nil, fmt.Errorf("UTC time %q can't have a TZID", p.value)
+		}
+	case t.TZID != "":
+		l, err := time.LoadLocation(t.TZID)
+		if err != nil {
+			// Defined by a VTIMEZONE we don't evaluate, like a Windows
+			// zone name
+			loc = nil
+		} else {
+			loc = l
+		}
+	default:
+		t.Floating = true
+		loc = nil
+	}
+
+	at, err := time.ParseInLocation("20060102T150405", v, time.UTC)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a valid DATE-TIME", p.value)
+	}
+	if loc != nil {
+		at = time.Date(at.Year(), at.Month(), at.Day(), at.Hour(), at.Minute(), at.Second(), 0, loc)
+		t.Time = at.Format(time.RFC3339)
+	}
+	t.at = at
+	return t, nil
+}
+
+// orderable reports whether two times can be ordered: both are resolved
+// to an instant or date, or both are floating
+func orderable(a, b *Time) bool {
+	return (a.Time != "" && b.Time != "") || (a.Floating && b.Floating)
+}
+
+func parseRecurrence(rule string) (Recurrence, error) {
+	r := Recurrence{Rule: rule, Interval: 1, ByDay: []string{}}
+	for _, part := range strings.Split(rule, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return r, fmt.Errorf("malformed rule part %q", part)
+		}
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.Freq = strings.ToUpper(value)
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return r, fmt.Errorf("INTERVAL %q is not a positive integer", value)
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return r, fmt.Errorf("COUNT %q is not a positive integer", value)
+			}
+			r.Count = n
+		case "UNTIL":
+			r.Until = value
+		case "BYDAY":
+			r.ByDay = strings.Split(strings.ToUpper(value), ",")
+		}
+	}
+
+	switch {
+	case r.Freq == "":
+		return r, fmt.Errorf("FREQ is missing")
+	case !frequencies[r.Freq]:
+		return r, fmt.Errorf("unknown FREQ %q", r.Freq)
+	case r.Count > 0 && r.Until != "":
+		return r, fmt.Errorf("COUNT and UNTIL can't both be set")
+	}
+	return r, nil
+}
+
+// validDuration checks the form of a DURATION value, e.g. PT1H30M or P1D
+func validDuration(s string) bool {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "+"), "-")
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return false
+	}
+	inTime, digits := false, false
+	for _, r := range s[1:] {
+		switch {
+		case r >= '0' && r <= '9':
+			digits = true
+		case r == 'T' && !inTime && !digits:
+			inTime = true
+		case digits && strings.ContainsRune("WD", r) && !inTime,
+			digits && strings.ContainsRune("HMS", r) && inTime:
+			digits = false
+		default:
+			return false
+		}
+	}
+	return !digits
+}
+
+func truncate(s string) string {
+	if len(s) > 60 {
+		return s[:57] + "..."
+	}
+	return s
+}
@@ -0,0 +1,129 @@
+package calendar
+
+import (
+	"strings"
+)
+
+// maxLineOctets is the longest line RFC 5545 allows before folding
+const maxLineOctets = 75
+
+// property is an unfolded content line
+type property struct {
+	name   string
+	params map[string][]string
+	value  string
+	// Line the property starts on, 1-based
+	line int
+}
+
+// param returns the first value of a parameter
+func (p *property) param(name string) string {
+	if v := p.params[name]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// unfold splits data into content lines, joining folded lines. It also
+// reports how many physical lines are too long and whether any end in a
+// bare LF.
+func unfold(data string) (lines []string, starts []int, long int, bareLF bool) {
+	physical := strings.Split(data, "\n")
+	for i, l := range physical {
+		if strings.HasSuffix(l, "\r") {
+			l = strings.TrimSuffix(l, "\r")
+		} else if i < len(physical)-1 {
+			bareLF = true
+		}
+		if len(l) > maxLineOctets {
+			long++
+		}
+
+		if (strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		if l == "" {
+			continue
+		}
+		lines = append(lines, l)
+		starts = append(starts, i+1)
+	}
+	return lines, starts, long, bareLF
+}
+
+// parseLine splits a content line into its name, parameters and value. It
+// returns false if the line has no value.
+func parseLine(s string, line int) (*property, bool) {
+	p := &property{params: make(map[string][]string), line: line}
+
+	// The name runs to the first ; or :
+	i := strings.IndexAny(s, ";:")
+	if i <= 0 {
+		return nil, false
+	}
+	p.name = strings.ToUpper(s[:i])
+	s = s[i:]
+
+	for strings.HasPrefix(s, ";") {
+		s = s[1:]
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			return nil, false
+		}
+		key := strings.ToUpper(s[:eq])
+		s = s[eq+1:]
+
+		// Values are separated by commas and may be quoted, in which case
+		// they can contain ; : and ,
+		for {
+			var v string
+			if strings.HasPrefix(s, `"`) {
+				end := strings.IndexByte(s[1:], '"')
+				if end < 0 {
+					return nil, false
+				}
+				v, s = s[1:end+1], s[end+2:]
+			} else {
+				end := strings.IndexAny(s, ",;:")
+				if end < 0 {
+					return nil, false
+				}
+				v, s = s[:end], s[end:]
+			}
+			p.params[key] = append(p.params[key], v)
+			if !strings.HasPrefix(s, ",") {
+				break
+			}
+			s = s[1:]
+		}
+	}
+
+	if !strings.HasPrefix(s, ":") {
+		return nil, false
+	}
+	p.value = s[1:]
+	return p, true
+}
+
+// unescape undoes the escaping of a TEXT value
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,404 @@
+package compat
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Package compat checks the HTML of a message against a bundled dataset of
+// how well email clients support HTML and CSS features, in the style of
+// caniemail.com. Nothing is fetched from the network.
+
+// Support levels in the dataset
+const (
+	Supported   = "y"
+	Partial     = "a"
+	Unsupported = "n"
+)
+
+//go:embed features.json
+var datasetJSON []byte
+
+// data is the parsed dataset, loaded once at startup
+var data = mustLoad(datasetJSON)
+
+// Client is an email client family
+type Client struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Feature is an HTML or CSS feature with its support in each client family
+type Feature struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	// The feature is used if any detector matches
+	Detect []Detector `json:"detect"`
+	// Support level by client ID: y, a (partial) or n
+	Support map[string]string `json:"support"`
+	// Explanation of partial support by client ID
+	Notes map[string]string `json:"notes"`
+}
+
+// Detector describes markup that uses a feature. Set exactly one of
+// Element, Property, Function, AtRule and Selector. Names and values are
+// case-insensitive regular expressions; names must match in full.
+type Detector struct {
+	// HTML element name, optionally with an Attribute whose value matches
+	// Value
+	Element   string `json:"element"`
+	Attribute string `json:"attribute"`
+	// Only match elements outside <head>
+	InBody bool `json:"inBody"`
+	// CSS property name, optionally with a value matching Value
+	Property string `json:"property"`
+	Value    string `json:"value"`
+	// CSS function name used in a property value, e.g. calc
+	Function string `json:"function"`
+	// CSS at-rule name without the @, e.g. media
+	AtRule string `json:"atRule"`
+	// Pattern found anywhere in a CSS selector, e.g. :hover
+	Selector string `json:"selector"`
+
+	element, attribute, property, value, function, atRule, selector *regexp.Regexp
+}
+
+// Report lists the features of a message that some of the checked clients
+// do not fully support
+type Report struct {
+	// Client families the message was checked against
+	Clients []Client `json:"clients"`
+	// Features that are not fully supported by at least one client, the
+	// most widely unsupported first
+	Issues []Issue `json:"issues"`
+	// Per-client view of Issues, in the order of Clients
+	Summary []ClientSummary `json:"summary"`
+	// Whether every used feature is at least partially supported by every
+	// checked client
+	Passed bool `json:"passed"`
+}
+
+// Issue is a feature used by a message that is not fully supported
+type Issue struct {
+	Feature string `json:"feature"`
+	Title   string `json:"title"`
+	// Lines of the HTML part the feature is used on, 1-based
+	Lines []int `json:"lines"`
+	// IDs of the checked clients that do not support the feature
+	Unsupported []string `json:"unsupported"`
+	// IDs of the checked clients that partially support the feature
+	Partial []string `json:"partial"`
+	// Explanation of partial support by client ID
+	Notes map[string]string `json:"notes,omitempty"`
+}
+
+// ClientSummary lists the issues that affect one client family
+type ClientSummary struct {
+	Client string `json:"client"`
+	Name   string `json:"name"`
+	// Feature IDs the client does not support
+	Unsupported []string `json:"unsupported"`
+	// Feature IDs the client partially supports
+	Partial []string `json:"partial"`
+}
+
+type dataset struct {
+	Clients  []Client  `json:"clients"`
+	Features []Feature `json:"features"`
+}
+
+// Clients returns the client families in the dataset
+func Clients() []Client {
+	return append([]Client(nil), data.Clients...)
+}
+
+// Check reports the features used by an HTML part that the given clients do
+// not fully support. An empty client list checks every client family.
+func Check(htmlPart string, clients []string) (*Report, error) {
+	selected, err := selectClients(clients)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := scan(htmlPart)
+
+	report := &Report{Clients: selected, Issues: []Issue{}, Passed: true}
+	for _, f := range data.Features {
+		used := lines[f.ID]
+		if len(used) == 0 {
+			continue
+		}
+
+		issue := Issue{
+			Feature:     f.ID,
+			Title:       f.Title,
+			Lines:       used,
+			Unsupported: []string{},
+			Partial:     []string{},
+		}
+		for _, c := range selected {
+			switch f.Support[c.ID] {
+			case Supported:
+			case Partial:
+				issue.Partial = append(issue.Partial, c.ID)
+				if note := f.Notes[c.ID]; note != "" {
+					if issue.Notes == nil {
+						issue.Notes = make(map[string]string)
+					}
+					issue.Notes[c.ID] = note
+				}
+			default:
+				issue.Unsupported = append(issue.Unsupported, c.ID)
+			}
+		}
+		if len(issue.Unsupported) == 0 && len(issue.Partial) == 0 {
+			continue
+		}
+		if len(issue.Unsupported) > 0 {
+			report.Passed = false
+		}
+		report.Issues = append(report.Issues, issue)
+	}
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		return len(report.Issues[i].Unsupported) > len(report.Issues[j].Unsupported)
+	})
+
+	for _, c := range selected {
+		summary := ClientSummary{Client: c.ID, Name: c.Name, Unsupported: []string{}, Partial: []string{}}
+		for _, issue := range report.Issues {
+			if contains(issue.Unsupported, c.ID) {
+				summary.Unsupported = append(summary.Unsupported, issue.Feature)
+			} else if contains(issue.Partial, c.ID) {
+				summary.Partial = append(summary.Partial, issue.Feature)
+			}
+		}
+		report.Summary = append(report.Summary, summary)
+	}
+
+	return report, nil
+}
+
+func selectClients(ids []string) ([]Client, error) {
+	if len(ids) == 0 {
+		return Clients(), nil
+	}
+
+	selected := []Client{}
+	for _, id := range ids {
+		id = strings.ToLower(strings.TrimSpace(id))
+		if id == "" {
+			continue
+		}
+		found := false
+		for _, c := range data.Clients {
+			if c.ID == id {
+				selected = append(selected, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown client %q", id)
+		}
+	}
+	if len(selected) == 0 {
+		return Clients(), nil
+	}
+	return selected, nil
+}
+
+// scan finds the features used by an HTML part and returns the sorted,
+// distinct lines each feature ID is used on
+func scan(s string) map[string][]int {
+	found := make(map[string]map[int]bool)
+	mark := func(f *Feature, line int) {
+		if found[f.ID] == nil {
+			found[f.ID] = make(map[int]bool)
+		}
+		found[f.ID][line] = true
+	}
+
+	checkCSS := func(css string, line int, inline bool) {
+		scanCSS(css, line, inline, func(item cssItem) {
+			for i := range data.Features {
+				f := &data.Features[i]
+				for _, d := range f.Detect {
+					if d.matchCSS(item) {
+						mark(f, item.line)
+						break
+					}
+				}
+			}
+		})
+	}
+
+	line := 1
+	inHead, inStyle := false, false
+	z := html.NewTokenizer(strings.NewReader(s))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		raw := string(z.Raw())
+		start := line
+		line += strings.Count(raw, "\n")
+
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			switch t.Data {
+			case "head":
+				inHead = true
+			case "body":
+				inHead = false
+			case "style":
+				inStyle = tt == html.StartTagToken
+			}
+
+			for i := range data.Features {
+				f := &data.Features[i]
+				for _, d := range f.Detect {
+					if d.matchElement(t, inHead) {
+						mark(f, start)
+						break
+					}
+				}
+			}
+
+			for _, a := range t.Attr {
+				if a.Key == "style" {
+					checkCSS(a.Val, start+attrLineOffset(raw, "style"), true)
+				}
+			}
+
+		case html.EndTagToken:
+			switch z.Token().Data {
+			case "head":
+				inHead = false
+			case "style":
+				inStyle = false
+			}
+
+		case html.TextToken:
+			if inStyle {
+				checkCSS(raw, start, false)
+			}
+		}
+	}
+
+	lines := make(map[string][]int, len(found))
+	for id, set := range found {
+		for l := range set {
+			lines[id] = append(lines[id], l)
+		}
+		sort.Ints(lines[id])
+	}
+	return lines
+}
+
+// attrLineOffset returns how many lines into a tag an attribute starts
+func attrLineOffset(raw, name string) int {
+	i := strings.Index(strings.ToLower(raw), name+"=")
+	if i < 0 {
+		return 0
+	}
+	return strings.Count(raw[:i], "\n")
+}
+
+func (d *Detector) matchElement(t html.Token, inHead bool) bool {
+	if d.element == nil || !d.element.MatchString(t.Data) {
+		return false
+	}
+	if d.InBody && inHead {
+		return false
+	}
+	if d.attribute == nil {
+		return true
+	}
+	for _, a := range t.Attr {
+		if d.attribute.MatchString(a.Key) {
+			return d.value == nil || d.value.MatchString(strings.TrimSpace(a.Val))
+		}
+	}
+	return false
+}
+
+func (d *Detector) matchCSS(item cssItem) bool {
+	switch item.kind {
+	case cssDeclaration:
+		if d.property != nil && d.property.MatchString(item.name) {
+			return d.value == nil || d.value.MatchString(item.value)
+		}
+		if d.function != nil {
+			for _, fn := range cssFunctions(item.value) {
+				if d.function.MatchString(fn) {
+					return true
+				}
+			}
+		}
+	case cssAtRule:
+		return d.atRule != nil && d.atRule.MatchString(item.name)
+	case cssSelector:
+		return d.selector != nil && d.selector.MatchString(item.name)
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func mustLoad(b []byte) *dataset {
+	var d dataset
+	if err := json.Unmarshal(b, &d); err != nil {
+		panic(fmt.Sprintf("compat: invalid dataset: %v", err))
+	}
+
+	for i := range d.Features {
+		f := &d.Features[i]
+		for j := range f.Detect {
+			if err := f.Detect[j].compile(); err != nil {
+				panic(fmt.Sprintf("compat: feature %s: %v", f.ID, err))
+			}
+		}
+	}
+	return &d
+}
+
+func (d *Detector) compile() error {
+	var err error
+	full := func(p string) (*regexp.Regexp, error) {
+		if p == "" || err != nil {
+			return nil, err
+		}
+		return regexp.Compile("(?i)^(?:" + p + ")$")
+	}
+	partial := func(p string) (*regexp.Regexp, error) {
+		if p == "" || err != nil {
+			return nil, err
+		}
+		return regexp.Compile("(?i)" + p)
+	}
+
+	d.element, err = full(d.Element)
+	d.attribute, err = full(d.Attribute)
+	d.property, err = full(d.Property)
+	d.function, err = full(d.Function)
+	d.atRule, err = full(d.AtRule)
+	d.value, err = partial(d.Value)
+	d.selector, err = partial(d.Selector)
+	return err
+}
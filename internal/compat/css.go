@@ -0,0 +1,156 @@
+package compat
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Kinds of CSS items found by scanCSS
+const (
+	cssDeclaration = iota
+	cssAtRule
+	cssSelector
+)
+
+// cssItem is a declaration, at-rule or selector list found in CSS
+type cssItem struct {
+	kind int
+	// Property name, at-rule name without the @, or the selector list
+	name string
+	// Declaration value without !important
+	value string
+	// Line the item starts on
+	line int
+}
+
+var cssFunctionPattern = regexp.MustCompile(`([a-zA-Z-]+)\(`)
+
+// scanCSS walks a style sheet, or the declarations of a style attribute if
+// inline is set, and calls visit for every item. It is deliberately
+// forgiving: anything it does not understand is skipped.
+func scanCSS(css string, line int, inline bool, visit func(cssItem)) {
+	css = stripCSSComments(css)
+
+	var buf strings.Builder
+	start := 0
+	depth, parens := 0, 0
+	var quote byte
+
+	flush := func(block bool) {
+		text := strings.TrimSpace(buf.String())
+		buf.Reset()
+		if text == "" {
+			return
+		}
+
+		switch {
+		case strings.HasPrefix(text, "@"):
+			name := strings.ToLower(strings.TrimPrefix(text, "@"))
+			if i := strings.IndexAny(name, " \t\r\n(\"'"); i >= 0 {
+				name = name[:i]
+			}
+			visit(cssItem{kind: cssAtRule, name: name, line: start})
+		case block:
+			visit(cssItem{kind: cssSelector, name: text, line: start})
+		case inline || depth > 0:
+			name, value, ok := strings.Cut(text, ":")
+			if !ok {
+				return
+			}
+			value = strings.TrimSpace(value)
+			if i := strings.LastIndex(strings.ToLower(value), "!important"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+			visit(cssItem{
+				kind:  cssDeclaration,
+				name:  strings.ToLower(strings.TrimSpace(name)),
+				value: value,
+				line:  start,
+			})
+		}
+	}
+
+	for i := 0; i < len(css); i++ {
+		c := css[i]
+		if c == '\n' {
+			line++
+		}
+
+		if buf.Len() == 0 && (c == ' ' || c == '\t' || c == '\r' || c == '\n') {
+			continue
+		}
+		if buf.Len() == 0 {
+			start = line
+		}
+
+		switch {
+		case quote != 0:
+			if c == '\\' && i+1 < len(css) {
+				buf.WriteByte(c)
+				i++
+				c = css[i]
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			parens++
+		case c == ')' && parens > 0:
+			parens--
+		case parens > 0:
+		case c == '{':
+			flush(true)
+			depth++
+			continue
+		case c == '}':
+			flush(false)
+			if depth > 0 {
+				depth--
+			}
+			continue
+		case c == ';':
+			flush(false)
+			continue
+		}
+		buf.WriteByte(c)
+	}
+	flush(false)
+}
+
+// cssFunctions returns the lower-case names of the functions called in a
+// declaration value
+func cssFunctions(value string) []string {
+	var names []string
+	for _, m := range cssFunctionPattern.FindAllStringSubmatch(value, -1) {
+		names = append(names, strings.ToLower(m[1]))
+	}
+	return names
+}
+
+// stripCSSComments blanks out comments, keeping their line breaks so line
+// numbers stay correct
+func stripCSSComments(css string) string {
+	if !strings.Contains(css, "/*") {
+		return css
+	}
+
+	var b strings.Builder
+	for {
+		i := strings.Index(css, "/*")
+		if i < 0 {
+			b.WriteString(css)
+			return b.String()
+		}
+		b.WriteString(css[:i])
+		end := strings.Index(css[i+2:], "*/")
+		if end < 0 {
+			b.WriteString(strings.Repeat("\n", strings.Count(css[i:], "\n")))
+			return b.String()
+		}
+		comment := css[i : i+2+end+2]
+		b.WriteString(strings.Repeat("\n", strings.Count(comment, "\n")))
+		b.WriteByte(' ')
+		css = css[i+2+end+2:]
+	}
+}
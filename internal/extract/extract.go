@@ -0,0 +1,317 @@
+package extract
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// Package extract pulls verification codes, sign-in links and other values
+// that end-to-end tests wait for out of captured messages
+
+// Names of the built-in extractors
+const (
+	ExtractorCode = "code"
+	ExtractorLink = "link"
+)
+
+// Sources a value can be found in
+const (
+	SourceSubject = "subject"
+	SourceText    = "text"
+	SourceHTML    = "html"
+)
+
+// DefaultLinkPattern matches sign-in, verification and reset links
+const DefaultLinkPattern = `(?i)(magic|verify|verification|confirm|log-?in|sign-?in|sign_in|auth|token|otp|reset|activate|invite|passwordless)`
+
+// excludedLinks are never taken for sign-in links by the default pattern,
+// even though they often carry a token
+var excludedLinks = regexp.MustCompile(`(?i)(unsubscribe|opt-?out|preferences|list-manage)`)
+
+var defaultLinkPattern = regexp.MustCompile(DefaultLinkPattern)
+
+// codePattern finds runs of digits, and 6 digit codes split in two groups
+// of three. findCodes checks their length and surroundings.
+var codePattern = regexp.MustCompile(`\d{3}[ -]\d{3}|\d+`)
+
+// codeKeywords mark text near a verification code
+var codeKeywords = regexp.MustCompile(`(?i)\b(code|otp|passcode|pin|verification|verify|one[- ]time|2fa|mfa|security|token|sign[- ]?in|log[- ]?in)\b`)
+
+// codeContext is how many bytes before a code are searched for a keyword
+const codeContext = 100
+
+// Config holds the extractors run on every message
+type Config struct {
+	// Regular expression sign-in links must match. Defaults to
+	// DefaultLinkPattern, ignoring unsubscribe and preference links.
+	LinkPattern string `json:"linkPattern"`
+	// Extractors run in addition to the built-in ones
+	Rules []Rule `json:"rules"`
+}
+
+// Rule is a configured extractor
+type Rule struct {
+	// Name the values are reported under, e.g. invite-id
+	Name string `json:"name"`
+	// Regular expression. The value is the first capture group if there is
+	// one, otherwise the whole match.
+	Pattern string `json:"pattern"`
+	// subject, text or html (the markup). Empty searches all three.
+	Target string `json:"target"`
+}
+
+// Message is the content values are extracted from
+type Message struct {
+	Subject string
+	Text    string
+	HTML    string
+	// URLs of the links in the message
+	Links []string
+}
+
+// Result holds the values found in a message
+type Result struct {
+	// Verification code found by the built-in extractor
+	Code string `json:"code"`
+	// Sign-in link found by the built-in extractor
+	Link string `json:"link"`
+	// Every value found, by the built-in and configured extractors, in the
+	// order they appear
+	Matches []Match `json:"matches"`
+}
+
+// Match is a value found by an extractor
+type Match struct {
+	// code, link or the name of a configured rule
+	Extractor string `json:"extractor"`
+	Value     string `json:"value"`
+	// subject, text or html
+	Source string `json:"source"`
+}
+
+// compiled is a Config ready for matching
+type compiled struct {
+	link  *regexp.Regexp
+	rules []compiledRule
+}
+
+type compiledRule struct {
+	Rule
+	pattern *regexp.Regexp
+}
+
+// Validate checks that the patterns compile and that rules are named
+func (c Config) Validate() error {
+	_, err := c.compile()
+	return err
+}
+
+func (c Config) compile() (*compiled, error) {
+	cc := &compiled{}
+	if c.LinkPattern != "" {
+		re, err := regexp.Compile(c.LinkPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid link pattern: %v", err)
+		}
+		cc.link = re
+	}
+
+	names := make(map[string]bool)
+	for _, r := range c.Rules {
+		if r.Name == "" {
+			return nil, errors.New("extractor rules need a name")
+		}
+		if r.Name == ExtractorCode || r.Name == ExtractorLink || names[r.Name] {
+			return nil, fmt.Errorf("duplicate extractor name %q", r.Name)
+		}
+		names[r.Name] = true
+
+		switch r.Target {
+		case "", SourceSubject, SourceText, SourceHTML:
+		default:
+			return nil, fmt.Errorf("extractor %s: unknown target %q", r.Name, r.Target)
+		}
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil || r.Pattern == "" {
+			return nil, fmt.Errorf("extractor %s: invalid pattern %q", r.Name, r.Pattern)
+		}
+		cc.rules = append(cc.rules, compiledRule{Rule: r, pattern: re})
+	}
+	return cc, nil
+}
+
+// Extract runs the built-in and configured extractors on a message. An
+// invalid configuration only disables the configured extractors.
+func Extract(config Config, m Message) *Result {
+	cc, err := config.compile()
+	if err != nil {
+		cc = &compiled{}
+	}
+
+	result := &Result{Matches: []Match{}}
+	add := func(extractor, value, source string) {
+		for _, existing := range result.Matches {
+			if existing.Extractor == extractor && existing.Value == value {
+				return
+			}
+		}
+		result.Matches = append(result.Matches, Match{Extractor: extractor, Value: value, Source: source})
+	}
+
+	sources := []struct {
+		name string
+		text string
+	}{
+		{SourceSubject, m.Subject},
+		{SourceText, m.Text},
+		{SourceHTML, visibleText(m.HTML)},
+	}
+
+	// A keyword in the subject, like "Your sign-in code", vouches for codes
+	// anywhere in the message
+	subjectHasKeyword := codeKeywords.MatchString(m.Subject)
+	for _, s := range sources {
+		for _, code := range findCodes(s.text, subjectHasKeyword) {
+			if result.Code == "" {
+				result.Code = code
+			}
+			add(ExtractorCode, code, s.name)
+		}
+	}
+
+	for _, u := range m.Links {
+		if !isSignInLink(cc.link, u) {
+			continue
+		}
+		if result.Link == "" {
+			result.Link = u
+		}
+		add(ExtractorLink, u, SourceHTML)
+	}
+
+	for _, r := range cc.rules {
+		for _, s := range []struct {
+			name string
+			text string
+		}{
+			{SourceSubject, m.Subject},
+			{SourceText, m.Text},
+			{SourceHTML, m.HTML},
+		} {
+			if r.Target != "" && r.Target != s.name {
+				continue
+			}
+			for _, sub := range r.pattern.FindAllStringSubmatch(s.text, -1) {
+				value := sub[0]
+				if len(sub) > 1 {
+					value = sub[1]
+				}
+				if value != "" {
+					add(r.Name, value, s.name)
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// findCodes returns the 4-8 digit codes in text that have a keyword
+// shortly before them, or all codes if anyCode is set. Numbers that are
+// part of amounts, dates, times, phone numbers or identifiers are skipped.
+// Spaces and dashes inside a code are removed.
+func findCodes(text string, anyCode bool) []string {
+	var codes []string
+	for _, loc := range codePattern.FindAllStringIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		code := strings.NewReplacer(" ", "", "-", "").Replace(text[start:end])
+		if len(code) < 4 || len(code) > 8 || isYear(code) {
+			continue
+		}
+
+		if r, _ := utf8.DecodeLastRuneInString(text[:start]); start > 0 &&
+			(isWordRune(r) || strings.ContainsRune("$€£#+./:-", r)) {
+			continue
+		}
+		if end < len(text) {
+			r, size := utf8.DecodeRuneInString(text[end:])
+			switch {
+			case isWordRune(r), strings.ContainsRune("%/-:", r):
+				continue
+			case r == '.' || r == ',':
+				// End of a sentence, not a decimal or thousands separator
+				if next := end + size; next < len(text) && !unicode.IsSpace(rune(text[next])) {
+					continue
+				}
+			}
+		}
+
+		if !anyCode && !codeKeywords.MatchString(text[max(start-codeContext, 0):start]) {
+			continue
+		}
+		codes = append(codes, code)
+	}
+	return codes
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isYear(code string) bool {
+	return len(code) == 4 && (strings.HasPrefix(code, "19") || strings.HasPrefix(code, "20"))
+}
+
+func isSignInLink(pattern *regexp.Regexp, u string) bool {
+	lower := strings.ToLower(u)
+	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
+		return false
+	}
+	if pattern != nil {
+		return pattern.MatchString(u)
+	}
+	return defaultLinkPattern.MatchString(u) && !excludedLinks.MatchString(u)
+}
+
+// visibleText returns the text of an HTML part without markup, styles and
+// scripts, one line per block of text
+func visibleText(s string) string {
+	if s == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	skip := 0
+	z := html.NewTokenizer(strings.NewReader(s))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return b.String()
+		case html.StartTagToken:
+			switch name, _ := z.TagName(); string(name) {
+			case "style", "script", "title":
+				skip++
+			}
+		case html.EndTagToken:
+			switch name, _ := z.TagName(); string(name) {
+			case "style", "script", "title":
+				if skip > 0 {
+					skip--
+				}
+			}
+		case html.TextToken:
+			if skip == 0 {
+				if text := strings.Join(strings.Fields(string(z.Text())), " "); text != "" {
+					b.WriteString(text)
+					b.WriteByte('\n')
+				}
+			}
+		}
+	}
+}
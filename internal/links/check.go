@@ -0,0 +1,115 @@
+package links
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// checkTimeout bounds each HEAD request
+const checkTimeout = 5 * time.Second
+
+// maxConcurrentChecks bounds the number of requests in flight
+const maxConcurrentChecks = 4
+
+// CheckResult is the outcome of a HEAD request for a link
+type CheckResult struct {
+	URL string `json:"url"`
+	// Whether the link was requested; links outside the base URL are not
+	Checked bool `json:"checked"`
+	// HTTP status code, 0 if the request failed or was skipped
+	Status int `json:"status"`
+	// Redirect target of a 3xx response. Redirects are not followed.
+	Location string `json:"location,omitempty"`
+	// Why the request failed or was skipped
+	Error string `json:"error,omitempty"`
+	// Whether the link resolved to a non-error status
+	OK bool `json:"ok"`
+}
+
+// Check sends a HEAD request for every distinct http(s) link under
+// config.CheckBaseURL. Other links are reported as skipped, so nothing is
+// ever requested outside the allowlisted base URL.
+func Check(ctx context.Context, config Config, links []Link) ([]CheckResult, error) {
+	if config.CheckBaseURL == "" {
+		return nil, errors.New("no link check base URL configured")
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	base, _ := url.Parse(config.CheckBaseURL)
+
+	client := &http.Client{
+		Timeout: checkTimeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	results := []CheckResult{}
+	seen := make(map[string]bool)
+	for _, l := range links {
+		if seen[l.URL] {
+			continue
+		}
+		seen[l.URL] = true
+		results = append(results, CheckResult{URL: l.URL})
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentChecks)
+	for i := range results {
+		r := &results[i]
+		u, err := url.Parse(r.URL)
+		if err != nil || !underBase(u, base) {
+			r.Error = fmt.Sprintf("not under %s", config.CheckBaseURL)
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			head(ctx, client, r)
+		}()
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+func head(ctx context.Context, client *http.Client, r *CheckResult) {
+	r.Checked = true
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, r.URL, nil)
+	if err != nil {
+		r.Error = err.Error()
+		return
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		r.Error = err.Error()
+		return
+	}
+	resp.Body.Close()
+
+	r.Status = resp.StatusCode
+	r.Location = resp.Header.Get("Location")
+	r.OK = resp.StatusCode < 400
+}
+
+// underBase reports whether u has the scheme and host of base and a path
+// under the base path
+func underBase(u, base *url.URL) bool {
+	if !strings.EqualFold(u.Scheme, base.Scheme) || !strings.EqualFold(u.Host, base.Host) {
+		return false
+	}
+	prefix := strings.TrimSuffix(base.Path, "/")
+	return u.Path == prefix || strings.HasPrefix(u.Path, prefix+"/") || prefix == ""
+}
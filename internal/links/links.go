@@ -0,0 +1,341 @@
+package links
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Package links extracts the links of a message and flags the ones that
+// are likely mistakes, like staging hosts or display text that points
+// somewhere else
+
+// Where a link was found
+const (
+	LocationHTML   = "html"
+	LocationText   = "text"
+	LocationHeader = "header"
+)
+
+// Kinds of links
+const (
+	KindLink        = "link"
+	KindImage       = "image"
+	KindUnsubscribe = "unsubscribe"
+)
+
+// Flags a link can carry
+const (
+	// The anchor text shows a URL or domain on another host
+	FlagTextMismatch = "text-mismatch"
+	// Plain http instead of https
+	FlagInsecure = "insecure"
+	// localhost, a private address or a staging-like host
+	FlagInternalHost = "internal-host"
+	// A link without the configured UTM parameters
+	FlagMissingUTM = "missing-utm"
+	// The URL does not parse
+	FlagInvalid = "invalid"
+)
+
+// defaultUTMParams are required when Config.UTMParams is empty
+var defaultUTMParams = []string{"utm_source", "utm_medium", "utm_campaign"}
+
+// internalLabels are host labels that suggest a non-production
+// environment, e.g. staging.example.com or app-dev.example.com
+var internalLabels = []string{"staging", "stage", "dev", "qa", "uat", "test", "preview", "sandbox"}
+
+// internalSuffixes are reserved or conventional non-public domains
+var internalSuffixes = []string{"localhost", "local", "test", "internal", "invalid", "lan"}
+
+// Config controls link flagging and checking
+type Config struct {
+	// Extra host patterns flagged as internal, e.g. *.corp.example.com
+	InternalHosts []string `json:"internalHosts"`
+	// Query parameters every link must carry. Defaults to utm_source,
+	// utm_medium and utm_campaign.
+	UTMParams []string `json:"utmParams"`
+	// Links under this URL can be checked with HEAD requests, e.g. a local
+	// dev server. Nothing is checked if it is empty.
+	CheckBaseURL string `json:"checkBaseUrl"`
+}
+
+// Link is a URL found in a message
+type Link struct {
+	URL string `json:"url"`
+	// Anchor text, or the alt text of an image
+	Text string `json:"text"`
+	// link, image or unsubscribe
+	Kind string `json:"kind"`
+	// html, text or header
+	Location string `json:"location"`
+	// Line of the HTML or text part, 0 for headers
+	Line int `json:"line"`
+	// Problems found with the link
+	Flags []string `json:"flags"`
+}
+
+// Message is the content links are extracted from
+type Message struct {
+	// Values of the List-Unsubscribe header
+	ListUnsubscribe []string
+	Text            string
+	HTML            string
+}
+
+var textURL = regexp.MustCompile(`https?://[^\s<>"')\]]+`)
+
+var domainLike = regexp.MustCompile(`^(?i)(https?://)?([a-z0-9-]+\.)+[a-z]{2,}(/\S*)?$`)
+
+// Validate checks the host patterns and the check base URL
+func (c Config) Validate() error {
+	for _, p := range c.InternalHosts {
+		if _, err := path.Match(strings.ToLower(p), ""); err != nil {
+			return fmt.Errorf("invalid internal host pattern %q", p)
+		}
+	}
+	if c.CheckBaseURL != "" {
+		u, err := url.Parse(c.CheckBaseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("link check base URL must be an absolute http or https URL")
+		}
+	}
+	return nil
+}
+
+// Extract returns the links of a message in the order they appear: the
+// List-Unsubscribe header first, then the HTML part, then the text part
+func Extract(config Config, m Message) []Link {
+	links := []Link{}
+
+	for _, v := range m.ListUnsubscribe {
+		for _, entry := range strings.Split(v, ",") {
+			entry = strings.Trim(strings.TrimSpace(entry), "<>")
+			if entry == "" {
+				continue
+			}
+			links = append(links, Link{URL: entry, Kind: KindUnsubscribe, Location: LocationHeader})
+		}
+	}
+
+	links = append(links, extractHTML(m.HTML)...)
+
+	for i, line := range strings.Split(m.Text, "\n") {
+		for _, u := range TextURLs(line) {
+			links = append(links, Link{
+				URL:      strings.TrimRight(u, ".,;:!?"),
+				Kind:     KindLink,
+				Location: LocationText,
+				Line:     i + 1,
+			})
+		}
+	}
+
+	for i := range links {
+		links[i].Flags = config.flags(&links[i])
+	}
+	return links
+}
+
+// extractHTML finds the href of every link and the src of every image
+func extractHTML(s string) []Link {
+	links := []Link{}
+	if s == "" {
+		return links
+	}
+
+	var anchor *Link
+	var anchorText strings.Builder
+	var anchorAlt string
+
+	line := 1
+	z := html.NewTokenizer(strings.NewReader(s))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		start := line
+		line += strings.Count(string(z.Raw()), "\n")
+
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			switch t.Data {
+			case "a", "area":
+				href, ok := attr(t, "href")
+				if !ok {
+					continue
+				}
+				link := Link{URL: href, Kind: KindLink, Location: LocationHTML, Line: start}
+				if t.Data == "area" || tt == html.SelfClosingTagToken {
+					link.Text, _ = attr(t, "alt")
+					links = append(links, link)
+					continue
+				}
+				anchor = &link
+				anchorText.Reset()
+				anchorAlt = ""
+			case "img":
+				src, ok := attr(t, "src")
+				if !ok || strings.HasPrefix(strings.ToLower(src), "data:") {
+					continue
+				}
+				alt, _ := attr(t, "alt")
+				links = append(links, Link{URL: src, Text: alt, Kind: KindImage, Location: LocationHTML, Line: start})
+				if anchor != nil && anchorAlt == "" {
+					anchorAlt = alt
+				}
+			}
+
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == "a" && anchor != nil {
+				anchor.Text = strings.Join(strings.Fields(anchorText.String()), " ")
+				if anchor.Text == "" {
+					anchor.Text = anchorAlt
+				}
+				links = append(links, *anchor)
+				anchor = nil
+			}
+
+		case html.TextToken:
+			if anchor != nil {
+				anchorText.Write(z.Text())
+				anchorText.WriteByte(' ')
+			}
+		}
+	}
+
+	if anchor != nil {
+		anchor.Text = strings.Join(strings.Fields(anchorText.String()), " ")
+		links = append(links, *anchor)
+	}
+	return links
+}
+
+func (c Config) flags(l *Link) []string {
+	flags := []string{}
+
+	u, err := url.Parse(l.URL)
+	if err != nil {
+		return append(flags, FlagInvalid)
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" {
+		// mailto:, tel:, cid:, fragments and relative links
+		return flags
+	}
+	if u.Host == "" {
+		return append(flags, FlagInvalid)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if l.Kind == KindLink && TextMismatch(l.Text, l.URL) {
+		flags = append(flags, FlagTextMismatch)
+	}
+	if scheme == "http" {
+		flags = append(flags, FlagInsecure)
+	}
+	if c.isInternal(host) {
+		flags = append(flags, FlagInternalHost)
+	}
+	if l.Kind == KindLink {
+		required := c.UTMParams
+		if len(required) == 0 {
+			required = defaultUTMParams
+		}
+		q := u.Query()
+		for _, p := range required {
+			if q.Get(p) == "" {
+				flags = append(flags, FlagMissingUTM)
+				break
+			}
+		}
+	}
+	return flags
+}
+
+// isInternal reports whether host is loopback, private, a reserved
+// non-public domain, looks like a staging host or matches a configured
+// pattern
+func (c Config) isInternal(host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast()
+	}
+
+	for _, p := range c.InternalHosts {
+		if ok, _ := path.Match(strings.ToLower(p), host); ok {
+			return true
+		}
+	}
+
+	labels := strings.Split(host, ".")
+	last := labels[len(labels)-1]
+	for _, s := range internalSuffixes {
+		if last == s {
+			return true
+		}
+	}
+	// The registered domain itself doesn't count: dev.to is production
+	for _, label := range labels[:max(len(labels)-2, 0)] {
+		for _, word := range internalLabels {
+			if label == word || strings.HasPrefix(label, word+"-") || strings.HasSuffix(label, "-"+word) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// TextURLs returns the http(s) URLs written out in plain text
+func TextURLs(text string) []string {
+	return textURL.FindAllString(text, -1)
+}
+
+// TextMismatch reports whether link text that looks like a URL or domain
+// shows a different host than the link goes to. Subdomains of the shown
+// host and a leading www. don't count as different.
+func TextMismatch(text, href string) bool {
+	shown := displayedHost(text)
+	if shown == "" {
+		return false
+	}
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return false
+	}
+	target := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	return target != "" && shown != target && !strings.HasSuffix(target, "."+shown)
+}
+
+// displayedHost returns the host shown by link text that looks like a URL
+// or domain, or "" for ordinary link text
+func displayedHost(text string) string {
+	text = strings.TrimSpace(text)
+	if !domainLike.MatchString(text) {
+		return ""
+	}
+	if !strings.Contains(text, "://") {
+		text = "http://" + text
+	}
+	u, err := url.Parse(text)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+func attr(t html.Token, name string) (string, bool) {
+	for _, a := range t.Attr {
+		if a.Key == name {
+			return strings.TrimSpace(a.Val), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,242 @@
+package parity
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Package parity compares the plain text and HTML parts of a message, to
+// catch text alternatives that have fallen out of date
+
+// Diff operations
+const (
+	OpEqual  = "equal"
+	OpDelete = "delete"
+	OpInsert = "insert"
+)
+
+// SimilarityThreshold is the word similarity below which the parts are
+// considered out of sync
+const SimilarityThreshold = 0.85
+
+// maxDiffEdits bounds the work spent diffing very different parts
+const maxDiffEdits = 500
+
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"')\]]+`)
+
+// Report compares the text part of a message with its HTML part
+type Report struct {
+	// The message has HTML but no text alternative, or an empty one
+	MissingText bool `json:"missingText"`
+	// The parts differ in content or links
+	Mismatch bool `json:"mismatch"`
+	// Share of words the two parts have in common, 0-1
+	Similarity float64 `json:"similarity"`
+	// The HTML part rendered as text
+	HTMLText string `json:"htmlText"`
+	// Word diff from the text part to the rendered HTML part. Empty if the
+	// parts are too different to diff.
+	Diff []Chunk `json:"diff"`
+	// Links in the HTML part that the text part lacks
+	LinksOnlyInHTML []string `json:"linksOnlyInHtml"`
+	// Links in the text part that the HTML part lacks
+	LinksOnlyInText []string `json:"linksOnlyInText"`
+}
+
+// Chunk is a run of words that is in both parts, only in the text part
+// (delete) or only in the HTML part (insert)
+type Chunk struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// Compare compares a text part with an HTML part and the http(s) links
+// found in each. Returns nil if there is no HTML part to compare against.
+func Compare(text, htmlPart string, textLinks, htmlLinks []string) *Report {
+	if strings.TrimSpace(htmlPart) == "" {
+		return nil
+	}
+
+	report := &Report{
+		HTMLText:        HTMLToText(htmlPart),
+		Diff:            []Chunk{},
+		LinksOnlyInHTML: missingLinks(htmlLinks, textLinks),
+		LinksOnlyInText: missingLinks(textLinks, htmlLinks),
+	}
+
+	if strings.TrimSpace(text) == "" {
+		report.MissingText = true
+		report.Mismatch = true
+		return report
+	}
+
+	a, b := words(text), words(report.HTMLText)
+	report.Similarity = similarity(a, b)
+	if chunks, ok := diffWords(a, b); ok {
+		report.Diff = chunks
+	}
+
+	report.Mismatch = report.Similarity < SimilarityThreshold ||
+		len(report.LinksOnlyInHTML) > 0 || len(report.LinksOnlyInText) > 0
+	return report
+}
+
+// word is a word as written and the key it is compared by
+type word struct {
+	text string
+	key  string
+}
+
+// words splits text into words, leaving out URLs, which are compared as
+// links, and tokens that are only punctuation, like separators or
+// markdown-style emphasis
+func words(text string) []word {
+	text = urlPattern.ReplaceAllString(text, " ")
+
+	var out []word
+	for _, f := range strings.Fields(text) {
+		key := strings.ToLower(strings.TrimFunc(f, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}))
+		if key == "" {
+			continue
+		}
+		out = append(out, word{text: f, key: key})
+	}
+	return out
+}
+
+// similarity is the Dice coefficient of the word multisets
+func similarity(a, b []word) float64 {
+	if len(a)+len(b) == 0 {
+		return 1
+	}
+	counts := make(map[string]int)
+	for _, w := range a {
+		counts[w.key]++
+	}
+	common := 0
+	for _, w := range b {
+		if counts[w.key] > 0 {
+			counts[w.key]--
+			common++
+		}
+	}
+	return float64(2*common) / float64(len(a)+len(b))
+}
+
+// missingLinks returns the links of from that are not in to, ignoring
+// trailing slashes
+func missingLinks(from, to []string) []string {
+	have := make(map[string]bool, len(to))
+	for _, l := range to {
+		have[normalizeLink(l)] = true
+	}
+
+	missing := []string{}
+	seen := make(map[string]bool)
+	for _, l := range from {
+		n := normalizeLink(l)
+		if have[n] || seen[n] {
+			continue
+		}
+		seen[n] = true
+		missing = append(missing, l)
+	}
+	return missing
+}
+
+func normalizeLink(l string) string {
+	return strings.TrimRight(strings.TrimSpace(l), "/")
+}
+
+// diffWords computes a word diff with Myers' algorithm. It gives up if the
+// parts need more than maxDiffEdits edits.
+func diffWords(a, b []word) ([]Chunk, bool) {
+	n, m := len(a), len(b)
+	if n+m == 0 {
+		return []Chunk{}, true
+	}
+	limit := min(maxDiffEdits, n+m)
+
+	// v[k+offset] is the furthest x on diagonal k; trace keeps a copy of v
+	// for each edit count to walk the path back
+	offset := limit + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[k-1+offset] < v[k+1+offset]) {
+				x = v[k+1+offset]
+			} else {
+				x = v[k-1+offset] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x].key == b[y].key {
+				x++
+				y++
+			}
+			v[k+offset] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, offset, d), true
+			}
+		}
+	}
+	return nil, false
+}
+
+func backtrack(a, b []word, trace [][]int, offset, d int) []Chunk {
+	type edit struct {
+		op string
+		w  word
+	}
+	var edits []edit
+
+	x, y := len(a), len(b)
+	for ; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[k-1+offset] < v[k+1+offset]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[prevK+offset]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, edit{OpEqual, b[y]})
+		}
+		if x == prevX {
+			y--
+			edits = append(edits, edit{OpInsert, b[y]})
+		} else {
+			x--
+			edits = append(edits, edit{OpDelete, a[x]})
+		}
+	}
+	for x > 0 && y > 0 {
+		x--
+		y--
+		edits = append(edits, edit{OpEqual, b[y]})
+	}
+
+	// Edits were collected back to front; group them into chunks
+	chunks := []Chunk{}
+	for i := len(edits) - 1; i >= 0; i-- {
+		e := edits[i]
+		if n := len(chunks); n > 0 && chunks[n-1].Op == e.op {
+			chunks[n-1].Text += " " + e.w.text
+			continue
+		}
+		chunks = append(chunks, Chunk{Op: e.op, Text: e.w.text})
+	}
+	return chunks
+}
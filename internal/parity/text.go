@@ -0,0 +1,86 @@
+package parity
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// blockElements start a new line when rendered as text
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "center": true,
+	"div": true, "dl": true, "dt": true, "dd": true, "footer": true, "form": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"header": true, "hr": true, "li": true, "main": true, "nav": true, "ol": true,
+	"p": true, "pre": true, "section": true, "table": true, "tr": true, "ul": true,
+}
+
+// HTMLToText renders an HTML part as plain text: one line per block, with
+// list items marked and styles, scripts and the head left out
+func HTMLToText(s string) string {
+	var lines []string
+	var cur strings.Builder
+	skip := 0
+	pre := 0
+
+	flush := func() {
+		line := cur.String()
+		if pre == 0 {
+			line = strings.Join(strings.Fields(line), " ")
+		}
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+		cur.Reset()
+	}
+
+	z := html.NewTokenizer(strings.NewReader(s))
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			flush()
+			return strings.Join(lines, "\n")
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			switch tag := string(name); {
+			case tag == "head" || tag == "style" || tag == "script" || tag == "title":
+				if tt == html.StartTagToken {
+					skip++
+				}
+			case tag == "br":
+				flush()
+			case tag == "td" || tag == "th":
+				cur.WriteByte(' ')
+			case blockElements[tag]:
+				flush()
+				if tag == "li" {
+					cur.WriteString("- ")
+				}
+				if tag == "pre" {
+					pre++
+				}
+			}
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch tag := string(name); {
+			case tag == "head" || tag == "style" || tag == "script" || tag == "title":
+				if skip > 0 {
+					skip--
+				}
+			case blockElements[tag]:
+				flush()
+				if tag == "pre" && pre > 0 {
+					pre--
+				}
+			}
+
+		case html.TextToken:
+			if skip == 0 {
+				cur.Write(z.Text())
+			}
+		}
+	}
+}
@@ -0,0 +1,527 @@
+package preview
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/watzon/postpilot/internal/tracking"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Package preview prepares the HTML part of a message for display inside
+// the app: active content is removed and remote resources are either
+// blocked or routed through a local proxy
+
+// RemotePath is the path of the proxy that serves remote images once
+// remote content is loaded. The URL is passed in the url parameter, signed
+// in the sig parameter.
+const RemotePath = "/preview/remote"
+
+// InlinePath is the path inline parts are served under, followed by the
+// email ID and the Content-ID
+const InlinePath = "/preview/inline/"
+
+// Options controls how a message is prepared for preview
+type Options struct {
+	// Load remote images through the proxy instead of blocking them
+	LoadRemote bool `json:"loadRemote"`
+	// Remove tracking pixels, unwrap click-tracking redirects where the
+	// destination is known and strip utm_* and similar parameters
+	RemoveTracking bool `json:"removeTracking"`
+}
+
+// Result is an HTML part ready to be displayed
+type Result struct {
+	HTML string `json:"html"`
+	// Distinct remote URLs the HTML loads resources from, in document order.
+	// They were blocked unless remote content was loaded.
+	Remote []string `json:"remote"`
+	// Whether remote resources were routed through the proxy
+	RemoteLoaded bool `json:"remoteLoaded"`
+	// What was removed, e.g. "2 script elements"
+	Removed []string `json:"removed"`
+}
+
+// removedElements are dropped together with their content. Besides active
+// content this includes noscript, which the preview shows as markup since
+// it runs no scripts, and SVG and MathML, whose foreign content can load
+// resources in ways HTML can't.
+var removedElements = map[string]bool{
+	"script": true, "iframe": true, "frame": true, "frameset": true,
+	"object": true, "embed": true, "applet": true, "portal": true,
+	"noscript": true, "svg": true, "math": true,
+}
+
+// droppedTags are dropped but their content is kept. Forms can't be
+// submitted from the preview, <base> would redirect relative URLs and
+// <link> loads remote stylesheets.
+var droppedTags = map[string]bool{
+	"form": true, "base": true, "link": true,
+}
+
+// urlAttributes hold a single URL
+var urlAttributes = map[string]bool{
+	"href": true, "src": true, "action": true, "background": true,
+	"poster": true, "xlink:href": true, "lowsrc": true, "dynsrc": true,
+	"cite": true, "longdesc": true, "usemap": true, "data": true,
+}
+
+// resourceAttributes are URL attributes the client loads when rendering,
+// as opposed to links that are only followed when clicked
+var resourceAttributes = map[string]bool{
+	"src": true, "background": true, "poster": true, "lowsrc": true, "dynsrc": true,
+}
+
+// resourceHrefs are elements whose href loads a resource rather than
+// linking to it, as SVG images and references do
+var resourceHrefs = map[string]bool{
+	"image": true, "use": true, "feimage": true,
+}
+
+// cssURL matches url() in style sheets and style attributes
+var cssURL = regexp.MustCompile(`(?i)url\(\s*(?:"([^"]*)"|'([^']*)'|([^)'"]*?))\s*\)`)
+
+// cssImport matches @import rules, which load remote style sheets
+var cssImport = regexp.MustCompile(`(?i)@import[^;]*;?`)
+
+// cssImageSet matches the start of image-set(), whose candidates can be
+// plain strings rather than url()
+var cssImageSet = regexp.MustCompile(`(?i)image-set\(`)
+
+// cssString matches a quoted CSS string
+var cssString = regexp.MustCompile(`"[^"]*"|'[^']*'`)
+
+// cssEscape matches a CSS escape: up to six hex digits and an optional
+// whitespace, or any other character
+var cssEscape = regexp.MustCompile(`\\(?:([0-9a-fA-F]{1,6})[ \t\n\r\f]?|([^0-9a-fA-F\n\r\f]))`)
+
+// dangerousCSS matches CSS that can run script in old engines
+var dangerousCSS = regexp.MustCompile(`(?i)(expression\s*\(|behavior\s*:|-moz-binding\s*:|javascript:)`)
+
+// policy is the Content-Security-Policy of rendered previews, in case
+// something gets past sanitizing: only images from the app itself, which
+// serves inline parts and the proxy, data: images and fonts, and inline
+// styles can load
+const policy = "default-src 'none'; img-src 'self' data:; style-src 'unsafe-inline'; font-src data:; form-action 'none'"
+
+// Render sanitizes an HTML part for preview. Scripts, embedded frames and
+// objects, noscript, SVG and MathML, event handlers and javascript: URLs are
+// always removed. Remote images and backgrounds are blocked unless
+// options.LoadRemote is set, in which case they are rewritten to go through
+// RemotePath. cid: URLs are rewritten to inlineBase followed by the
+// Content-ID, unless inlineBase is empty. The result carries a restrictive
+// Content-Security-Policy.
+func Render(htmlPart, inlineBase string, options Options) *Result {
+	r := &renderer{
+		options:    options,
+		inlineBase: inlineBase,
+		seen:       make(map[string]bool),
+		removed:    make(map[string]int),
+	}
+	// Sanitize the tree the browser will build rather than the tokens, so
+	// content that only becomes markup once parsed is covered too
+	doc, err := html.Parse(strings.NewReader(htmlPart))
+	if err != nil {
+		doc = &html.Node{Type: html.DocumentNode}
+	}
+	r.sanitize(doc)
+	addPolicy(doc)
+	var out strings.Builder
+	html.Render(&out, doc)
+
+	result := &Result{
+		HTML:         out.String(),
+		Remote:       r.remote,
+		RemoteLoaded: options.LoadRemote,
+		Removed:      []string{},
+	}
+	if result.Remote == nil {
+		result.Remote = []string{}
+	}
+	kinds := make([]string, 0, len(r.removed))
+	for what := range r.removed {
+		kinds = append(kinds, what)
+	}
+	sort.Strings(kinds)
+	for _, what := range kinds {
+		result.Removed = append(result.Removed, fmt.Sprintf("%d %s", r.removed[what], what))
+	}
+	return result
+}
+
+type renderer struct {
+	options    Options
+	inlineBase string
+	remote     []string
+	seen       map[string]bool
+	// Counts of removed content by description
+	removed map[string]int
+}
+
+// addPolicy adds policy as the first element of the document's head
+func addPolicy(doc *html.Node) {
+	for n := doc.FirstChild; n != nil; n = n.NextSibling {
+		if n.Type != html.ElementNode || n.Data != "html" {
+			continue
+		}
+		for head := n.FirstChild; head != nil; head = head.NextSibling {
+			if head.Type == html.ElementNode && head.Data == "head" {
+				head.InsertBefore(&html.Node{
+					Type:     html.ElementNode,
+					Data:     "meta",
+					DataAtom: atom.Meta,
+					Attr: []html.Attribute{
+						{Key: "http-equiv", Val: "Content-Security-Policy"},
+						{Key: "content", Val: policy},
+					},
+				}, head.FirstChild)
+				return
+			}
+		}
+	}
+}
+
+// sanitize removes active content from the children of n and rewrites the
+// resources they load
+func (r *renderer) sanitize(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type != html.ElementNode {
+			c = next
+			continue
+		}
+
+		switch {
+		case removedElements[c.Data]:
+			r.removed[c.Data+" elements"]++
+			n.RemoveChild(c)
+		case droppedTags[c.Data] || (c.Data == "meta" && isActiveMeta(c.Attr)):
+			r.removed[c.Data+" elements"]++
+			// Keep the content in place of the element and sanitize it next
+			if c.FirstChild != nil {
+				next = c.FirstChild
+			}
+			for child := c.FirstChild; child != nil; child = c.FirstChild {
+				c.RemoveChild(child)
+				n.InsertBefore(child, c)
+			}
+			n.RemoveChild(c)
+		case r.options.RemoveTracking && !r.untrack(c):
+			n.RemoveChild(c)
+		default:
+			c.Attr = r.attributes(c.Data, c.Attr)
+			if c.Data == "style" {
+				for text := c.FirstChild; text != nil; text = text.NextSibling {
+					if text.Type == html.TextNode {
+						text.Data = r.css(text.Data)
+					}
+				}
+			}
+			r.sanitize(c)
+		}
+		c = next
+	}
+}
+
+// attributes filters and rewrites the attributes of an element
+func (r *renderer) attributes(tag string, attrs []html.Attribute) []html.Attribute {
+	out := attrs[:0]
+	for _, a := range attrs {
+		key := strings.ToLower(a.Key)
+		switch {
+		case strings.HasPrefix(key, "on"):
+			r.removed["event handlers"]++
+			continue
+		case key == "srcdoc" || key == "formaction" || key == "ping":
+			r.removed[key+" attributes"]++
+			continue
+		case key == "style":
+			a.Val = r.css(a.Val)
+		case key == "srcset":
+			val, ok := r.srcset(a.Val)
+			if !ok {
+				continue
+			}
+			a.Val = val
+		case urlAttributes[key]:
+			if isScriptURL(a.Val) {
+				r.removed["javascript: URLs"]++
+				continue
+			}
+			if resourceAttributes[key] || (tag == "input" && key == "src") ||
+				((key == "href" || key == "xlink:href") && resourceHrefs[tag]) {
+				val, ok := r.resource(a.Val)
+				if !ok {
+					continue
+				}
+				a.Val = val
+			}
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// untrack removes the tracking from an element. It returns false if the
+// element is a tracking pixel and should be dropped.
+func (r *renderer) untrack(n *html.Node) bool {
+	attrs := make(map[string]string, len(n.Attr))
+	for _, a := range n.Attr {
+		attrs[strings.ToLower(a.Key)] = a.Val
+	}
+
+	switch n.Data {
+	case "img":
+		if tracking.CheckImage(attrs) != nil {
+			r.removed["tracking pixels"]++
+			return false
+		}
+	case "a", "area":
+		for i := 0; i < len(n.Attr); i++ {
+			a := &n.Attr[i]
+			if strings.ToLower(a.Key) != "href" {
+				continue
+			}
+			if l := tracking.CheckLink(a.Val); l != nil {
+				r.removed["tracked links"]++
+				if l.Destination == "" {
+					// Following the link would still be tracked
+					n.Attr = append(n.Attr[:i], n.Attr[i+1:]...)
+					i--
+					continue
+				}
+				a.Val = l.Destination
+			}
+			if stripped := tracking.StripParams(a.Val); stripped != a.Val {
+				r.removed["tracking parameters"]++
+				a.Val = stripped
+			}
+		}
+	}
+	return true
+}
+
+// resource rewrites the URL of a resource loaded when rendering. It
+// returns false if the resource is blocked.
+func (r *renderer) resource(raw string) (string, bool) {
+	u := strings.TrimSpace(raw)
+	lower := strings.ToLower(u)
+	switch {
+	case strings.HasPrefix(lower, "data:"):
+		// Only images can be embedded; other data URLs can hold documents
+		if !strings.HasPrefix(lower, "data:image/") {
+			r.removed["data: URLs"]++
+			return "", false
+		}
+		return raw, true
+	case strings.HasPrefix(lower, "cid:"):
+		if r.inlineBase == "" {
+			return raw, true
+		}
+		id := u[len("cid:"):]
+		if unescaped, err := url.PathUnescape(id); err == nil {
+			id = unescaped
+		}
+		return r.inlineBase + url.PathEscape(id), true
+	case strings.HasPrefix(lower, "//"):
+		u = "https:" + u
+	case strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://"):
+	default:
+		// Relative URLs resolve against the app, not the sender, and can't
+		// reach anything remote. Other schemes, like file:, are blocked.
+		if parsed, err := url.Parse(u); err != nil || parsed.Scheme != "" {
+			r.removed["unsupported URLs"]++
+			return "", false
+		}
+		return raw, true
+	}
+
+	if !r.seen[u] {
+		r.seen[u] = true
+		r.remote = append(r.remote, u)
+	}
+	if !r.options.LoadRemote {
+		return "", false
+	}
+	return proxyURL(u), true
+}
+
+// srcset rewrites each candidate of a srcset attribute, dropping blocked
+// ones. It returns false if none is left.
+func (r *renderer) srcset(val string) (string, bool) {
+	var kept []string
+	for rest := val; ; {
+		rest = strings.TrimLeft(rest, " \t\n\r\f,")
+		if rest == "" {
+			break
+		}
+		// The URL runs to the next whitespace, so it may contain commas, as
+		// data URLs do; a trailing comma ends the candidate
+		end := strings.IndexAny(rest, " \t\n\r\f")
+		if end < 0 {
+			end = len(rest)
+		}
+		candidate := rest[:end]
+		u, descriptors := strings.TrimRight(candidate, ","), ""
+		rest = rest[end:]
+		if u == candidate {
+			if i := strings.IndexByte(rest, ','); i >= 0 {
+				descriptors, rest = strings.TrimSpace(rest[:i]), rest[i+1:]
+			} else {
+				descriptors, rest = strings.TrimSpace(rest), ""
+			}
+		}
+
+		rewritten, ok := r.resource(u)
+		if !ok {
+			continue
+		}
+		if descriptors != "" {
+			rewritten += " " + descriptors
+		}
+		kept = append(kept, rewritten)
+	}
+	return strings.Join(kept, ", "), len(kept) > 0
+}
+
+// css rewrites the URLs in a style sheet or style attribute and removes
+// imports and script
+func (r *renderer) css(s string) string {
+	s = unescapeCSS(s)
+	if n := len(cssImport.FindAllStringIndex(s, -1)); n > 0 {
+		r.removed["@import rules"] += n
+		s = cssImport.ReplaceAllString(s, "")
+	}
+	if n := len(dangerousCSS.FindAllStringIndex(s, -1)); n > 0 {
+		r.removed["CSS expressions"] += n
+		s = dangerousCSS.ReplaceAllString(s, "x-removed:")
+	}
+	s = cssURL.ReplaceAllStringFunc(s, func(m string) string {
+		sub := cssURL.FindStringSubmatch(m)
+		u := sub[1] + sub[2] + sub[3]
+		if strings.Contains(u, `\`) {
+			r.removed["escaped CSS URLs"]++
+			return "none"
+		}
+		rewritten, ok := r.resource(u)
+		if !ok {
+			return "none"
+		}
+		if rewritten == u {
+			return m
+		}
+		return "url('" + rewritten + "')"
+	})
+	return r.imageSets(s)
+}
+
+// unescapeCSS decodes the escapes that stand for letters or -, so url(),
+// image-set() and @import are recognized however they are written. Other
+// escapes are kept, since decoding them could change how the CSS is
+// tokenized; URLs that still contain one are blocked.
+func unescapeCSS(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	return cssEscape.ReplaceAllStringFunc(s, func(m string) string {
+		sub := cssEscape.FindStringSubmatch(m)
+		c := sub[2]
+		if sub[1] != "" {
+			n, _ := strconv.ParseUint(sub[1], 16, 32)
+			c = string(rune(n))
+		}
+		if len(c) == 1 && (c[0] == '-' || 'a' <= c[0]|0x20 && c[0]|0x20 <= 'z') {
+			return c
+		}
+		return m
+	})
+}
+
+// imageSets rewrites the string candidates of image-set(). A blocked one
+// becomes none, which invalidates the declaration so nothing is loaded.
+// url() candidates were already rewritten by css.
+func (r *renderer) imageSets(s string) string {
+	var out strings.Builder
+	for {
+		loc := cssImageSet.FindStringIndex(s)
+		if loc == nil {
+			out.WriteString(s)
+			return out.String()
+		}
+		out.WriteString(s[:loc[1]])
+		s = s[loc[1]:]
+
+		end := closingParen(s)
+		out.WriteString(cssString.ReplaceAllStringFunc(s[:end], func(m string) string {
+			u := m[1 : len(m)-1]
+			if strings.Contains(u, `\`) {
+				r.removed["escaped CSS URLs"]++
+				return "none"
+			}
+			rewritten, ok := r.resource(u)
+			if !ok {
+				return "none"
+			}
+			if rewritten == u {
+				return m
+			}
+			return "'" + rewritten + "'"
+		}))
+		s = s[end:]
+	}
+}
+
+// closingParen returns the index of the parenthesis closing a function
+// whose arguments s starts with, or len(s)
+func closingParen(s string) int {
+	depth := 1
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"', '\'':
+			if j := strings.IndexByte(s[i+1:], s[i]); j >= 0 {
+				i += j + 1
+			}
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(s)
+}
+
+// isScriptURL reports whether a URL runs script when followed, ignoring
+// the whitespace and control characters browsers strip from schemes
+func isScriptURL(u string) bool {
+	u = strings.ToLower(strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, u))
+	return strings.HasPrefix(u, "javascript:") || strings.HasPrefix(u, "vbscript:") ||
+		strings.HasPrefix(u, "data:text/html")
+}
+
+// isActiveMeta reports whether a meta element with the given attributes
+// does more than describe the document, e.g. a refresh that navigates away
+func isActiveMeta(attrs []html.Attribute) bool {
+	for _, a := range attrs {
+		if strings.EqualFold(a.Key, "http-equiv") {
+			switch strings.ToLower(strings.TrimSpace(a.Val)) {
+			case "content-type", "x-ua-compatible":
+				return false
+			}
+			return true
+		}
+	}
+	return false
+}
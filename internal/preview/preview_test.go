@@ -0,0 +1,153 @@
+package preview
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRenderBlocksRemoteContent(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		// Remote URL that must not be loaded
+		url string
+	}{
+		{"image", `<img src="https://t/a.gif">`, "https://t/a.gif"},
+		{"protocol-relative image", `<img src="//t/a.gif">`, "t/a.gif"},
+		{"srcset", `<img srcset="https://t/a.png 1x, https://t/b.png 2x">`, "https://t/b.png"},
+		{"background attribute", `<table background="https://t/a.png"></table>`, "https://t/a.png"},
+		{"style url", `<div style="background:url(https://t/a.png)"></div>`, "https://t/a.png"},
+		{"style sheet url", `<style>div { background: url("https://t/a.png") }</style>`, "https://t/a.png"},
+		{"style import", `<style>@import "https://t/a.css";</style>`, "https://t/a.css"},
+		{"link", `<link rel="stylesheet" href="https://t/a.css">`, "https://t/a.css"},
+		{"noscript", `<noscript><img src="https://t/p.gif"></noscript>`, "https://t/p.gif"},
+		{"escaped noscript", `<noscript>&lt;img src="https://t/p.gif"&gt;</noscript>`, "https://t/p.gif"},
+		{"svg style", `<svg><style><img src="https://t/q.gif"></style></svg>`, "https://t/q.gif"},
+		{"svg image", `<svg><image href="https://t/r.png"/>`, "https://t/r.png"},
+		{"svg xlink image", `<svg><image xlink:href="https://t/r.png"/></svg>`, "https://t/r.png"},
+		{"svg use", `<svg><use href="https://t/r.svg#a"/></svg>`, "https://t/r.svg"},
+		{"svg feImage", `<svg><filter><feImage href="https://t/r.png"/></filter></svg>`, "https://t/r.png"},
+		{"math", `<math><mglyph src="https://t/m.png"/></math>`, "https://t/m.png"},
+		{"image-set string", `<div style="background-image:image-set('https://t/s.png' 1x)"></div>`, "https://t/s.png"},
+		{"webkit image-set string", `<div style='background-image:-webkit-image-set("https://t/s.png" 1x, "https://t/s2.png" 2x)'></div>`, "https://t/s2.png"},
+		{"image-set url", `<style>p { background: image-set(url(https://t/s.png) 1x) }</style>`, "https://t/s.png"},
+		{"template", `<template><img src="https://t/v.png"></template>`, "https://t/v.png"},
+		{"import without space", `<style>@import"https://t/e.css";</style>`, "https://t/e.css"},
+		{"escaped url", `<div style="background:\75 rl(https://t/e.png)"></div>`, "https://t/e.png"},
+		{"long escaped url", `<div style="background:\000075\000072\00006c(https://t/e.png)"></div>`, "https://t/e.png"},
+		{"escaped letter in url", `<div style="background:u\rl(https://t/e.png)"></div>`, "https://t/e.png"},
+		{"escaped import", `<style>@\69mport "https://t/e.css";</style>`, "https://t/e.css"},
+		{"escaped image-set", `<div style="background-image:image\2d set('https://t/e.png' 1x)"></div>`, "https://t/e.png"},
+		{"escape inside url", `<div style="background:url(https\3a //t/e.png)"></div>`, "//t/e.png"},
+		{"ping", `<a href="https://example.com/" ping="https://t/ping">x</a>`, "https://t/ping"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Render(tt.html, "", Options{})
+			if strings.Contains(result.HTML, tt.url) {
+				t.Errorf("remote URL %s left in %s", tt.url, result.HTML)
+			}
+
+			loaded := Render(tt.html, "", Options{LoadRemote: true})
+			if strings.Contains(loaded.HTML, tt.url) {
+				t.Errorf("remote URL %s not proxied in %s", tt.url, loaded.HTML)
+			}
+		})
+	}
+}
+
+func TestRenderProxiesRemoteContent(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		url  string
+	}{
+		{"image", `<img src="https://t/a.gif">`, "https://t/a.gif"},
+		{"style url", `<div style="background:url('https://t/a.png')"></div>`, "https://t/a.png"},
+		{"image-set string", `<div style="background-image:image-set('https://t/s.png' 1x)"></div>`, "https://t/s.png"},
+		{"image outside svg", `<svg><style><img src="https://t/q.gif"></style></svg>`, "https://t/q.gif"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocked := Render(tt.html, "", Options{})
+			if len(blocked.Remote) != 1 || blocked.Remote[0] != tt.url {
+				t.Errorf("Remote = %q, want [%s]", blocked.Remote, tt.url)
+			}
+
+			loaded := Render(tt.html, "", Options{LoadRemote: true})
+			proxied := RemotePath + "?url=" + url.QueryEscape(tt.url)
+			if !strings.Contains(loaded.HTML, proxied) {
+				t.Errorf("%s not in %s", proxied, loaded.HTML)
+			}
+		})
+	}
+}
+
+func TestRenderRemovesActiveContent(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		// Text that must not be left
+		active  string
+		removed string
+	}{
+		{"script", `<p>hi</p><script>alert(1)</script>`, "alert", "1 script elements"},
+		{"event handler", `<p onclick="alert(1)">hi</p>`, "alert", "1 event handlers"},
+		{"javascript link", `<a href="javascript:alert(1)">hi</a>`, "alert", "1 javascript: URLs"},
+		{"iframe", `<iframe src="https://t/"></iframe>`, "iframe", "1 iframe elements"},
+		{"refresh", `<meta http-equiv="refresh" content="0;url=https://t/">`, "refresh", "1 meta elements"},
+		{"css expression", `<p style="width:expression(alert(1))">hi</p>`, "expression", "1 CSS expressions"},
+		{"noscript", `<noscript><p>on</p></noscript>`, "noscript", "1 noscript elements"},
+		{"svg", `<svg><a href="https://t/">x</a></svg>`, "svg", "1 svg elements"},
+		{"escaped css expression", `<p style="width:\65xpression(alert(1))">hi</p>`, "expression", "1 CSS expressions"},
+		{"ping", `<a href="https://example.com/" ping="https://t/">x</a>`, "ping", "1 ping attributes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Render(tt.html, "", Options{})
+			if strings.Contains(result.HTML, tt.active) {
+				t.Errorf("%q left in %s", tt.active, result.HTML)
+			}
+			if len(result.Removed) != 1 || result.Removed[0] != tt.removed {
+				t.Errorf("Removed = %q, want [%s]", result.Removed, tt.removed)
+			}
+		})
+	}
+}
+
+func TestRenderKeepsContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		html    string
+		options Options
+		want    string
+	}{
+		{"text in dropped tag", `<form action="https://t/"><p>kept</p></form>`, Options{}, "<p>kept</p>"},
+		{"inline image", `<img src="cid:logo@example.com">`, Options{}, `src="/inline/logo@example.com"`},
+		{"data image", `<img src="data:image/png;base64,AAAA">`, Options{}, `src="data:image/png;base64,AAAA"`},
+		{"link", `<a href="https://example.com/">x</a>`, Options{}, `href="https://example.com/"`},
+		{"tracking parameters", `<a href="https://example.com/?a=1&amp;utm_source=x">x</a>`, Options{RemoveTracking: true}, `href="https://example.com/?a=1"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Render(tt.html, "/inline/", tt.options)
+			if !strings.Contains(result.HTML, tt.want) {
+				t.Errorf("%s not in %s", tt.want, result.HTML)
+			}
+		})
+	}
+}
+
+func TestRenderAddsPolicy(t *testing.T) {
+	for _, html := range []string{`<p>hi</p>`, `<html><head><title>x</title></head><body>hi</body></html>`} {
+		result := Render(html, "", Options{})
+		if !strings.Contains(result.HTML, `<head><meta http-equiv="Content-Security-Policy" content="default-src &#39;none&#39;;`) {
+			t.Errorf("no Content-Security-Policy at the start of the head in %s", result.HTML)
+		}
+	}
+}
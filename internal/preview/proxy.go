@@ -0,0 +1,167 @@
+package preview
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// proxyTimeout bounds each request for a remote resource
+const proxyTimeout = 10 * time.Second
+
+// maxProxySize is the largest remote resource the proxy serves
+const maxProxySize = 10 * 1024 * 1024
+
+// signingKey authenticates the proxy URLs written by Render, so the proxy
+// only fetches what a preview asked for. It changes on every start.
+var signingKey = func() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+// errPrivateAddress is returned for remote resources that resolve to an
+// address on this machine or the local network
+var errPrivateAddress = errors.New("destination is not a public address")
+
+// nonPublicPrefixes are special-purpose ranges that netip doesn't classify
+// as private but that don't reach the public internet either
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// Proxy serves remote images referenced by previews, so the preview never
+// loads anything from outside the app itself. Only images are served and
+// no cookies or referrer are sent. URLs must be signed by Render and
+// resolve to public addresses, so the proxy can't be used to reach the
+// local machine or network.
+type Proxy struct {
+	client *http.Client
+}
+
+// NewProxy creates a proxy for remote preview content
+func NewProxy() *Proxy {
+	dialer := &net.Dialer{Timeout: proxyTimeout, Control: publicOnly}
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: proxyTimeout,
+	}
+	return &Proxy{client: &http.Client{Timeout: proxyTimeout, Transport: transport}}
+}
+
+// proxyURL returns the signed proxy URL serving the remote resource u
+func proxyURL(u string) string {
+	return RemotePath + "?url=" + url.QueryEscape(u) + "&sig=" + sign(u)
+}
+
+func sign(u string) string {
+	mac := hmac.New(sha256.New, signingKey)
+	mac.Write([]byte(u))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// publicOnly refuses connections to loopback, private, link-local and
+// other non-public addresses. It runs after DNS resolution, for every
+// connection including those of redirects.
+func publicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return errPrivateAddress
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(ip) {
+			return errPrivateAddress
+		}
+	}
+	return nil
+}
+
+// ServeHTTP fetches the URL in the url parameter and serves it if it is an
+// image
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	raw := r.URL.Query().Get("url")
+	if !hmac.Equal([]byte(sign(raw)), []byte(r.URL.Query().Get("sig"))) {
+		http.Error(w, "url was not issued by a preview", http.StatusForbidden)
+		return
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		http.Error(w, "invalid url", http.StatusBadRequest)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, u.String(), nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Header.Set("User-Agent", "PostPilot")
+	req.Header.Set("Accept", "image/*")
+
+	resp, err := p.client.Do(req)
+	if errors.Is(err, errPrivateAddress) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		http.Error(w, fmt.Sprintf("remote server returned %s", resp.Status), http.StatusBadGateway)
+		return
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !strings.HasPrefix(mediaType, "image/") {
+		http.Error(w, fmt.Sprintf("not an image: %q", mediaType), http.StatusUnsupportedMediaType)
+		return
+	}
+	if resp.ContentLength > maxProxySize {
+		http.Error(w, "image too large", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// SVG images can hold script, which must not run if one is opened
+	// directly
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+	if r.Method == http.MethodHead {
+		return
+	}
+	io.Copy(w, io.LimitReader(resp.Body, maxProxySize))
+}
@@ -0,0 +1,92 @@
+package preview
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestProxyRefusesUnsignedURLs(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"no signature", "url=" + url.QueryEscape("https://example.com/a.png")},
+		{"wrong signature", "url=" + url.QueryEscape("https://example.com/a.png") + "&sig=" + sign("https://example.com/b.png")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			NewProxy().ServeHTTP(w, httptest.NewRequest(http.MethodGet, RemotePath+"?"+tt.query, nil))
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("got status %d, want %d", w.Code, http.StatusForbidden)
+			}
+		})
+	}
+}
+
+func TestProxyRefusesPrivateDestinations(t *testing.T) {
+	fetched := false
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched = true
+		w.Header().Set("Content-Type", "image/png")
+	}))
+	defer local.Close()
+
+	for _, u := range []string{
+		local.URL + "/a.png",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.1/a.png",
+		"http://[::1]:1/a.png",
+	} {
+		t.Run(u, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			NewProxy().ServeHTTP(w, httptest.NewRequest(http.MethodGet, proxyURL(u), nil))
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("got status %d, want %d", w.Code, http.StatusForbidden)
+			}
+		})
+	}
+	if fetched {
+		t.Fatal("proxy fetched from a loopback address")
+	}
+}
+
+func TestPublicOnly(t *testing.T) {
+	tests := []struct {
+		address string
+		public  bool
+	}{
+		{"93.184.215.14:80", true},
+		{"[2606:2800:21f:cb07:6820:80da:af6b:8b2c]:443", true},
+		{"127.0.0.1:80", false},
+		{"[::1]:80", false},
+		{"10.1.2.3:80", false},
+		{"172.16.0.1:80", false},
+		{"192.168.1.1:80", false},
+		{"169.254.169.254:80", false},
+		{"100.64.0.1:80", false},
+		{"0.0.0.0:80", false},
+		{"[fd00::1]:80", false},
+		{"[fe80::1]:80", false},
+		{"[::ffff:127.0.0.1]:80", false},
+	}
+
+	for _, tt := range tests {
+		err := publicOnly("tcp", tt.address, nil)
+		if (err == nil) != tt.public {
+			t.Errorf("%s: got %v, want public %v", tt.address, err, tt.public)
+		}
+	}
+}
+
+func TestRenderSignsProxyURLs(t *testing.T) {
+	result := Render(`<img src="https://example.com/a.png">`, "", Options{LoadRemote: true})
+	want := "sig=" + sign("https://example.com/a.png")
+	if !strings.Contains(result.HTML, want) {
+		t.Fatalf("proxy URL is not signed:\n%s", result.HTML)
+	}
+}
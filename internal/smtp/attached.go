@@ -0,0 +1,79 @@
+package smtp
+
+import (
+	"bytes"
+	"net/mail"
+
+	"github.com/google/uuid"
+)
+
+// maxAttachedDepth limits how deeply attached messages are parsed, e.g. a
+// bounce of a forwarded message
+const maxAttachedDepth = 5
+
+// Attachment is a part of a message meant to be saved rather than shown
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	// Decoded size in bytes
+	Size int `json:"size"`
+}
+
+// parseContent fills in what is found by walking the parts of the raw
+// message: inline parts, calendars, attachments and attached messages,
+// which are parsed into child emails. depth is the nesting of email in
+// attached messages.
+func parseContent(email *Email, depth int) {
+	raw := []byte(email.Raw)
+	email.Inline = inlineParts(raw)
+	email.Calendars = calendarParts(raw)
+	email.Attachments = []Attachment{}
+	email.Attached = []*Email{}
+
+	walkParts(raw, func(p *mimePart) {
+		disposition, filename := p.disposition()
+		isMessage := p.mediaType == "message/rfc822" || p.mediaType == "message/global"
+		if !isMessage && disposition != "attachment" && (filename == "" || p.contentID() != "") {
+			return
+		}
+
+		data, err := p.decode()
+		if err != nil {
+			data = p.body
+		}
+		email.Attachments = append(email.Attachments, Attachment{
+			Filename:    filename,
+			ContentType: p.mediaType,
+			Size:        len(data),
+		})
+
+		if isMessage && depth < maxAttachedDepth {
+			if child := parseAttached(email, data, depth+1); child != nil {
+				email.Attached = append(email.Attached, child)
+			}
+		}
+	})
+}
+
+// parseAttached parses an attached message into a child email of parent.
+// It returns nil if the message can't be parsed.
+func parseAttached(parent *Email, raw []byte, depth int) *Email {
+	child := &Email{
+		ID:        uuid.New().String(),
+		ParentID:  parent.ID,
+		Timestamp: parent.Timestamp,
+		Raw:       string(raw),
+	}
+	if err := parseEmail(child, bytes.NewReader(raw)); err != nil {
+		return nil
+	}
+	// Show when the attached message was sent, not when its parent arrived
+	if msg, err := mail.ReadMessage(bytes.NewReader(raw)); err == nil {
+		if date, err := msg.Header.Date(); err == nil {
+			child.Timestamp = date
+		}
+	}
+
+	parseContent(child, depth)
+	return child
+}
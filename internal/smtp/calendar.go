@@ -0,0 +1,78 @@
+package smtp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/watzon/postpilot/internal/calendar"
+)
+
+// defaultCalendarFilename names calendar parts that don't have a file name
+const defaultCalendarFilename = "invite.ics"
+
+// isCalendarPart reports whether a part is an iCalendar object, sent as a
+// text/calendar invite or an .ics attachment
+func isCalendarPart(p *mimePart) bool {
+	switch p.mediaType {
+	case "text/calendar", "application/ics":
+		return true
+	}
+	_, filename := p.disposition()
+	return strings.HasSuffix(strings.ToLower(filename), ".ics")
+}
+
+// calendarParts parses the calendar parts of a raw message, in order
+func calendarParts(raw []byte) []calendar.Calendar {
+	calendars := []calendar.Calendar{}
+	walkParts(raw, func(p *mimePart) {
+		if !isCalendarPart(p) {
+			return
+		}
+		data, err := p.decode()
+		if err != nil {
+			data = p.body
+		}
+		calendars = append(calendars, *calendar.Parse(data, calendar.Source{
+			MediaType: p.mediaType,
+			Method:    p.params["method"],
+			Filename:  calendarFilename(p),
+		}))
+	})
+	return calendars
+}
+
+// ReadCalendarPart returns the file name and decoded content of the
+// calendar part of a raw message at index, counting from 0 in the order
+// of Email.Calendars
+func ReadCalendarPart(raw []byte, index int) (string, []byte, error) {
+	var found *mimePart
+	n := 0
+	err := walkParts(raw, func(p *mimePart) {
+		if !isCalendarPart(p) {
+			return
+		}
+		if n == index {
+			found = p
+		}
+		n++
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if found == nil {
+		return "", nil, fmt.Errorf("no calendar part %d", index)
+	}
+
+	data, err := found.decode()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decode calendar part %d: %w", index, err)
+	}
+	return calendarFilename(found), data, nil
+}
+
+func calendarFilename(p *mimePart) string {
+	if _, filename := p.disposition(); filename != "" {
+		return filename
+	}
+	return defaultCalendarFilename
+}
@@ -0,0 +1,462 @@
+package smtp
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/emersion/go-smtp"
+	"github.com/google/uuid"
+)
+
+// recordingListener wraps accepted connections so that every conversation
+// is captured in a transcript
+type recordingListener struct {
+	net.Listener
+	server *Server
+}
+
+func (l *recordingListener) Accept() (net.Conn, error) {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		rc := l.record(c)
+		if max := l.server.limits.MaxConnections; max > 0 && atomic.AddInt64(&l.server.active, 1) > int64(max) {
+			// Turn the client away ourselves, go-smtp has no connection limit
+			atomic.AddInt64(&l.server.active, -1)
+			rc.rejected = true
+			rc.SetWriteDeadline(time.Now().Add(time.Second))
+			rc.Write([]byte(tooManyConnectionsReply))
+			rc.rec.error(limitPrefix+"more than %d simultaneous connections", max)
+			rc.Close()
+			continue
+		}
+
+		return rc, nil
+	}
+}
+
+// record starts the transcript of a newly accepted connection
+func (l *recordingListener) record(c net.Conn) *recordingConn {
+
+	rc := &recordingConn{
+		Conn:   c,
+		server: l.server,
+		rec: &transcriptRecorder{t: Transcript{
+			ID:         uuid.New().String(),
+			RemoteAddr: c.RemoteAddr().String(),
+			StartedAt:  time.Now(),
+		}},
+	}
+	l.server.transcripts.add(rc.rec)
+
+	return rc
+}
+
+// recordingConn splits the bytes flowing through a connection into SMTP
+// lines and records them. Message data is summarized rather than stored
+// and SASL secrets are redacted. After STARTTLS the connection carries TLS
+// records, so only their sizes are added to the byte counts; the session
+// notes the MAIL, RCPT and DATA commands it handles over TLS instead.
+type recordingConn struct {
+	net.Conn
+	server *Server
+	rec    *transcriptRecorder
+
+	mu        sync.Mutex
+	closeOnce sync.Once
+	clientBuf []byte
+	serverBuf []byte
+
+	// Protocol state tracked from the conversation
+	greeted       bool
+	pending       []string // commands awaiting their final reply, oldest first
+	ehloLines     int
+	inData        bool
+	dataBytes     int64
+	bdatRemaining int64
+	redactNext    bool
+	encrypted     bool
+	quit          bool
+	timedOut      bool
+
+	// Read deadline requested by go-smtp while waiting for a command
+	idleDeadline time.Time
+	// Whether message data is being received, with DATA or BDAT. It stays
+	// set after Session.Data returns until go-smtp waits for the next
+	// command, since go-smtp first discards whatever data is left.
+	receiving bool
+	// Whether Session.Data is running
+	dataRunning bool
+	// Whether a SASL challenge was sent and the client's response is due
+	saslPending bool
+	// Whether the last read deadline came from the read timeout rather
+	// than the idle timeout
+	readDeadline bool
+	// Whether the connection was turned away for exceeding MaxConnections
+	rejected bool
+}
+
+// SetReadDeadline remembers the idle deadline go-smtp sets before reading a
+// command; Read combines it with the per-read timeout
+func (c *recordingConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.idleDeadline = t
+	if !c.dataRunning {
+		c.receiving = false
+	}
+	c.mu.Unlock()
+	return c.Conn.SetReadDeadline(t)
+}
+
+// armReadDeadline applies the idle deadline between commands. While
+// message data or a SASL response is expected, the read timeout applies
+// too if it expires first.
+func (c *recordingConn) armReadDeadline() {
+	timeout := c.server.limits.readTimeout()
+
+	c.mu.Lock()
+	deadline := c.idleDeadline
+	c.readDeadline = false
+	if timeout > 0 && (c.receiving || c.saslPending) {
+		if d := time.Now().Add(timeout); deadline.IsZero() || d.Before(deadline) {
+			deadline = d
+			c.readDeadline = true
+		}
+	}
+	c.mu.Unlock()
+
+	c.Conn.SetReadDeadline(deadline)
+}
+
+func (c *recordingConn) Read(p []byte) (int, error) {
+	c.armReadDeadline()
+
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.mu.Lock()
+		c.saslPending = false
+		c.mu.Unlock()
+
+		c.rec.update(func(t *Transcript) {
+			t.BytesIn += int64(n)
+		})
+		c.recordClient(p[:n])
+	}
+	if err != nil && err != io.EOF && !errors.Is(err, net.ErrClosed) {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			c.mu.Lock()
+			c.timedOut = true
+			readDeadline := c.readDeadline
+			c.mu.Unlock()
+
+			if readDeadline {
+				c.rec.error(limitPrefix+"no data from client within the %s read timeout", c.server.limits.readTimeout())
+			} else {
+				c.rec.error(limitPrefix+"no command from client within the %s idle timeout", c.server.limits.idleTimeout())
+			}
+			return n, err
+		}
+		c.rec.error("read error: %v", err)
+	}
+	return n, err
+}
+
+func (c *recordingConn) Write(p []byte) (int, error) {
+	out := filterCapabilities(p, c.server.extensions.hiddenCapabilities())
+
+	n, err := c.Conn.Write(out)
+	if n > 0 {
+		c.rec.update(func(t *Transcript) {
+			t.BytesOut += int64(n)
+		})
+		c.recordServer(out[:n])
+	}
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		c.rec.error("write error: %v", err)
+	}
+	if err == nil {
+		// Hidden lines count as written
+		n = len(p)
+	}
+	return n, err
+}
+
+func (c *recordingConn) Close() error {
+	err := c.Conn.Close()
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		quit, timedOut, rejected := c.quit, c.timedOut, c.rejected
+		c.mu.Unlock()
+
+		if !rejected && c.server.limits.MaxConnections > 0 {
+			atomic.AddInt64(&c.server.active, -1)
+		}
+
+		c.rec.finish(quit, timedOut)
+		c.server.notifySession(c.rec.entry())
+	})
+	return err
+}
+
+func (c *recordingConn) recordClient(p []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.encrypted {
+		return
+	}
+
+	for len(p) > 0 {
+		// BDAT chunks are raw bytes, not lines
+		if c.bdatRemaining > 0 {
+			n := int64(len(p))
+			if n > c.bdatRemaining {
+				n = c.bdatRemaining
+			}
+			c.bdatRemaining -= n
+			c.dataBytes += n
+			p = p[n:]
+			if c.bdatRemaining == 0 {
+				c.rec.add(DirectionClient, fmt.Sprintf("[%d bytes of message data]", c.dataBytes))
+			}
+			continue
+		}
+
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			c.clientBuf = append(c.clientBuf, p...)
+			return
+		}
+		line := string(append(c.clientBuf, p[:i+1]...))
+		c.clientBuf = c.clientBuf[:0]
+		p = p[i+1:]
+
+		c.clientLine(strings.TrimRight(line, "\r\n"))
+	}
+}
+
+func (c *recordingConn) clientLine(line string) {
+	if c.inData {
+		if line == "." {
+			c.rec.add(DirectionClient, fmt.Sprintf("[%d bytes of message data]", c.dataBytes))
+			c.rec.add(DirectionClient, ".")
+			c.inData = false
+			// The end of data is answered like a command
+			c.pending = append(c.pending, ".")
+		} else {
+			c.dataBytes += int64(len(line)) + 2
+		}
+		return
+	}
+
+	if c.redactNext {
+		// SASL responses continue the pending AUTH command
+		c.redactNext = false
+		if line != "*" {
+			line = "[redacted]"
+		}
+		c.rec.add(DirectionClient, line)
+		return
+	}
+
+	fields := strings.Fields(line)
+	cmd := ""
+	if len(fields) > 0 {
+		cmd = strings.ToUpper(fields[0])
+	}
+
+	switch cmd {
+	case "EHLO", "HELO", "LHLO":
+		helo := ""
+		if len(fields) > 1 {
+			helo = fields[1]
+		}
+		c.rec.update(func(t *Transcript) {
+			t.Helo = helo
+		})
+	case "AUTH":
+		if len(fields) > 1 {
+			mech := strings.ToUpper(fields[1])
+			c.rec.update(func(t *Transcript) {
+				t.AuthMechanism = mech
+			})
+		}
+		if len(fields) > 2 {
+			line = fields[0] + " " + fields[1] + " [redacted]"
+		}
+	case "BDAT":
+		if len(fields) > 1 {
+			if size, err := strconv.ParseInt(fields[1], 10, 64); err == nil && size > 0 {
+				c.bdatRemaining = size
+				c.dataBytes = 0
+			}
+		}
+	}
+
+	c.rec.add(DirectionClient, line)
+	c.pending = append(c.pending, cmd)
+}
+
+func (c *recordingConn) recordServer(p []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.encrypted {
+		return
+	}
+
+	c.serverBuf = append(c.serverBuf, p...)
+	for {
+		i := bytes.IndexByte(c.serverBuf, '\n')
+		if i < 0 {
+			return
+		}
+		line := strings.TrimRight(string(c.serverBuf[:i+1]), "\r\n")
+		c.serverBuf = c.serverBuf[i+1:]
+
+		c.serverLine(line)
+	}
+}
+
+func (c *recordingConn) serverLine(line string) {
+	c.rec.add(DirectionServer, line)
+
+	code := 0
+	if len(line) >= 3 {
+		code, _ = strconv.Atoi(line[:3])
+	}
+	last := len(line) < 4 || line[3] != '-'
+
+	if code >= 400 {
+		c.rec.update(func(t *Transcript) {
+			t.Errors = append(t.Errors, line)
+		})
+		if reason := c.server.limits.limitReason(line); reason != "" {
+			c.rec.error("%s", reason)
+		}
+	}
+
+	// The greeting is not a reply to any command
+	if !c.greeted {
+		c.greeted = last
+		return
+	}
+
+	cmd := ""
+	if len(c.pending) > 0 {
+		cmd = c.pending[0]
+	}
+
+	if (cmd == "EHLO" || cmd == "LHLO") && code == 250 {
+		// The first line of the reply is the greeting, the rest are capabilities
+		if c.ehloLines == 0 {
+			c.rec.update(func(t *Transcript) {
+				t.Capabilities = nil
+			})
+		} else if len(line) > 4 {
+			capability := line[4:]
+			c.rec.update(func(t *Transcript) {
+				t.Capabilities = append(t.Capabilities, capability)
+			})
+		}
+		c.ehloLines++
+	}
+
+	if !last {
+		return
+	}
+	c.ehloLines = 0
+
+	// A SASL challenge keeps AUTH pending and is answered with a secret
+	if code == 334 {
+		c.redactNext = true
+		return
+	}
+
+	if len(c.pending) > 0 {
+		c.pending = c.pending[1:]
+	}
+
+	switch {
+	case cmd == "DATA" && code == 354:
+		c.inData = true
+		c.dataBytes = 0
+		// Message data is bounded by the read timeout, not the idle timeout
+		c.idleDeadline = time.Time{}
+	case cmd == "QUIT" && code == 221:
+		c.quit = true
+	case cmd == "STARTTLS" && code == 220:
+		c.encrypted = true
+		c.rec.update(func(t *Transcript) {
+			t.TLS = true
+		})
+		c.rec.add(DirectionEvent, "TLS handshake started, further traffic is encrypted")
+	}
+}
+
+// recordingConnFor returns the recordingConn under a go-smtp connection,
+// looking through the TLS layer added by STARTTLS, or nil
+func recordingConnFor(c *smtp.Conn) *recordingConn {
+	conn := c.Conn()
+	if tc, ok := conn.(*tls.Conn); ok {
+		conn = tc.NetConn()
+	}
+	rc, _ := conn.(*recordingConn)
+	return rc
+}
+
+// setDataRunning marks the start and end of Session.Data. Message data is
+// read under the read timeout from the start until the next command.
+// c may be nil.
+func (c *recordingConn) setDataRunning(running bool) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.dataRunning = running
+	if running {
+		c.receiving = true
+	}
+	c.mu.Unlock()
+}
+
+// expectSASLResponse puts the next read under the read timeout, after a
+// SASL challenge was sent. c may be nil.
+func (c *recordingConn) expectSASLResponse() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.saslPending = true
+	c.mu.Unlock()
+}
+
+// recorderFor returns the transcript recorder of a go-smtp connection,
+// looking through the TLS layer added by STARTTLS
+func recorderFor(c *smtp.Conn) *transcriptRecorder {
+	if rc := recordingConnFor(c); rc != nil {
+		return rc.rec
+	}
+	conn := c.Conn()
+
+	// Connections that did not come through a recordingListener still
+	// get a transcript, it just won't contain the wire conversation
+	return &transcriptRecorder{t: Transcript{
+		ID:         uuid.New().String(),
+		RemoteAddr: conn.RemoteAddr().String(),
+		StartedAt:  time.Now(),
+	}}
+}
@@ -0,0 +1,623 @@
+package smtp
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DKIM verification results, as used in Authentication-Results (RFC 8601)
+const (
+	DKIMPass      = "pass"
+	DKIMFail      = "fail"
+	DKIMPermError = "permerror"
+	DKIMTempError = "temperror"
+)
+
+// Canonicalization algorithms (RFC 6376 section 3.4)
+const (
+	CanonicalizationSimple  = "simple"
+	CanonicalizationRelaxed = "relaxed"
+)
+
+// DKIMKey maps a signing domain and selector to a local public key
+type DKIMKey struct {
+	// Signing domain (d= tag)
+	Domain string `json:"domain"`
+	// Selector (s= tag)
+	Selector string `json:"selector"`
+	// File holding the key as a PEM public or private key, or as the
+	// TXT record that would be published in DNS
+	KeyFile string `json:"keyFile"`
+}
+
+// DKIMConfig controls where DKIM public keys are looked up
+type DKIMConfig struct {
+	// Local key registry, consulted first
+	Keys []DKIMKey `json:"keys"`
+	// host:port of a DNS server queried for keys missing from the
+	// registry. Empty keeps verification offline.
+	Resolver string `json:"resolver"`
+	// Key used by SignDKIM to preview signed messages
+	Signing DKIMSigning `json:"signing"`
+}
+
+// DKIMResult is the outcome of verifying one DKIM-Signature header
+type DKIMResult struct {
+	// pass, fail, permerror or temperror
+	Status string `json:"status"`
+	// Why the signature did not pass, empty on pass
+	Reason string `json:"reason"`
+	// Signing domain (d= tag)
+	Domain string `json:"domain"`
+	// Selector (s= tag)
+	Selector string `json:"selector"`
+	// Agent or user identifier (i= tag)
+	Identity string `json:"identity"`
+	// Signing algorithm, e.g. rsa-sha256
+	Algorithm string `json:"algorithm"`
+	// Header canonicalization, simple or relaxed
+	HeaderCanonicalization string `json:"headerCanonicalization"`
+	// Body canonicalization, simple or relaxed
+	BodyCanonicalization string `json:"bodyCanonicalization"`
+	// Signed header fields (h= tag), in signing order
+	SignedHeaders []string `json:"signedHeaders"`
+	// Number of body bytes covered (l= tag), -1 for the whole body
+	BodyLength int64 `json:"bodyLength"`
+	// Where the public key came from: registry or dns
+	KeySource string `json:"keySource"`
+}
+
+// Validate checks that every registry entry is complete, the resolver
+// address is usable and the signing key is fully described
+func (c DKIMConfig) Validate() error {
+	for _, k := range c.Keys {
+		if k.Domain == "" || k.Selector == "" || k.KeyFile == "" {
+			return errors.New("DKIM keys need a domain, selector and key file")
+		}
+	}
+	if c.Resolver != "" {
+		if _, _, err := net.SplitHostPort(c.Resolver); err != nil {
+			return fmt.Errorf("DKIM resolver %q is not in host:port form", c.Resolver)
+		}
+	}
+	return c.Signing.Validate()
+}
+
+// dnsTimeout bounds a key lookup through the configured resolver
+const dnsTimeout = 5 * time.Second
+
+// SetDKIM replaces the DKIM key registry and resolver used to verify
+// incoming messages
+func (s *Server) SetDKIM(config DKIMConfig) error {
+	return s.dkim.set(config)
+}
+
+// verifyDKIM checks every DKIM-Signature header of a raw message, in
+// header order
+func verifyDKIM(config DKIMConfig, raw []byte) []DKIMResult {
+	header, body := splitMessage(raw)
+	fields := splitHeaderFields(header)
+
+	results := []DKIMResult{}
+	for i, f := range fields {
+		if !strings.EqualFold(f.name, "DKIM-Signature") {
+			continue
+		}
+		results = append(results, verifySignature(config, fields, i, body))
+	}
+	return results
+}
+
+// headerField is a single header field as it appeared in the message,
+// including folding and the trailing CRLF
+type headerField struct {
+	name string
+	raw  string
+}
+
+// value returns the unfolded-as-is text after the colon
+func (f headerField) value() string {
+	_, v, _ := strings.Cut(f.raw, ":")
+	return v
+}
+
+// splitMessage separates the header section from the body and converts
+// bare LF line endings to CRLF
+func splitMessage(raw []byte) (header, body []byte) {
+	raw = toCRLF(raw)
+	if i := bytes.Index(raw, []byte("\r\n\r\n")); i >= 0 {
+		return raw[:i+2], raw[i+4:]
+	}
+	return raw, nil
+}
+
+func toCRLF(b []byte) []byte {
+	if !bytes.Contains(b, []byte("\n")) || bytes.Count(b, []byte("\r\n")) == bytes.Count(b, []byte("\n")) {
+		return b
+	}
+	out := make([]byte, 0, len(b)+bytes.Count(b, []byte("\n")))
+	for i, c := range b {
+		if c == '\n' && (i == 0 || b[i-1] != '\r') {
+			out = append(out, '\r')
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// splitHeaderFields splits a CRLF header section into fields, keeping
+// continuation lines with the field they belong to
+func splitHeaderFields(header []byte) []headerField {
+	var fields []headerField
+	for _, line := range strings.SplitAfter(string(header), "\r\n") {
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(fields) > 0 {
+			fields[len(fields)-1].raw += line
+			continue
+		}
+		name, _, _ := strings.Cut(line, ":")
+		fields = append(fields, headerField{name: strings.TrimRight(name, " \t"), raw: line})
+	}
+	return fields
+}
+
+// parseTagList parses a DKIM tag=value list (RFC 6376 section 3.2). Folding
+// whitespace is removed from values.
+func parseTagList(s string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, part := range strings.Split(s, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("malformed tag %q", strings.TrimSpace(part))
+		}
+		name = strings.TrimSpace(name)
+		if _, dup := tags[name]; dup {
+			return nil, fmt.Errorf("duplicate tag %q", name)
+		}
+		tags[name] = removeFWS(value)
+	}
+	return tags, nil
+}
+
+func removeFWS(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\r', '\n':
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func verifySignature(config DKIMConfig, fields []headerField, index int, body []byte) DKIMResult {
+	result := DKIMResult{BodyLength: -1, SignedHeaders: []string{}}
+	fail := func(status, format string, args ...interface{}) DKIMResult {
+		result.Status = status
+		result.Reason = fmt.Sprintf(format, args...)
+		return result
+	}
+
+	sig := fields[index]
+	tags, err := parseTagList(sig.value())
+	if err != nil {
+		return fail(DKIMPermError, "invalid signature: %v", err)
+	}
+
+	result.Domain = tags["d"]
+	result.Selector = tags["s"]
+	result.Identity = tags["i"]
+	result.Algorithm = strings.ToLower(tags["a"])
+	result.HeaderCanonicalization, result.BodyCanonicalization = parseCanonicalization(tags["c"])
+	if h := tags["h"]; h != "" {
+		result.SignedHeaders = strings.Split(h, ":")
+	}
+
+	if tags["v"] != "1" {
+		return fail(DKIMPermError, "unsupported signature version %q", tags["v"])
+	}
+	for _, name := range []string{"a", "b", "bh", "d", "h", "s"} {
+		if tags[name] == "" {
+			return fail(DKIMPermError, "signature is missing the %s= tag", name)
+		}
+	}
+	if !containsFold(result.SignedHeaders, "From") {
+		return fail(DKIMPermError, "From header is not signed")
+	}
+	if result.HeaderCanonicalization == "" || result.BodyCanonicalization == "" {
+		return fail(DKIMPermError, "unknown canonicalization %q", tags["c"])
+	}
+	if l := tags["l"]; l != "" {
+		n, err := strconv.ParseInt(l, 10, 64)
+		if err != nil || n < 0 {
+			return fail(DKIMPermError, "invalid body length %q", l)
+		}
+		result.BodyLength = n
+	}
+	if x := tags["x"]; x != "" {
+		expires, err := strconv.ParseInt(x, 10, 64)
+		if err != nil {
+			return fail(DKIMPermError, "invalid expiration %q", x)
+		}
+		if time.Now().Unix() > expires {
+			return fail(DKIMFail, "signature expired at %s", time.Unix(expires, 0).UTC().Format(time.RFC3339))
+		}
+	}
+
+	hash, keyType, err := parseAlgorithm(result.Algorithm)
+	if err != nil {
+		return fail(DKIMPermError, "%v", err)
+	}
+
+	// Body hash
+	canonBody := canonicalizeBody(body, result.BodyCanonicalization)
+	if result.BodyLength >= 0 {
+		if result.BodyLength > int64(len(canonBody)) {
+			return fail(DKIMPermError, "body length %d exceeds the %d byte canonicalized body", result.BodyLength, len(canonBody))
+		}
+		canonBody = canonBody[:result.BodyLength]
+	}
+	bodyHash, err := base64.StdEncoding.DecodeString(tags["bh"])
+	if err != nil {
+		return fail(DKIMPermError, "invalid body hash: %v", err)
+	}
+	h := hash.New()
+	h.Write(canonBody)
+	if !bytes.Equal(h.Sum(nil), bodyHash) {
+		return fail(DKIMFail, "body hash mismatch: the body was changed after signing")
+	}
+
+	// Header hash
+	signature, err := base64.StdEncoding.DecodeString(tags["b"])
+	if err != nil {
+		return fail(DKIMPermError, "invalid signature data: %v", err)
+	}
+	data := signedHeaderData(fields, result.SignedHeaders, sig, result.HeaderCanonicalization)
+
+	key, source, err := lookupDKIMKey(config, result.Domain, result.Selector)
+	if err != nil {
+		var temp *dkimTempError
+		if errors.As(err, &temp) {
+			return fail(DKIMTempError, "%v", err)
+		}
+		return fail(DKIMPermError, "%v", err)
+	}
+	result.KeySource = source
+
+	if err := verifyWithKey(key, keyType, hash, data, signature); err != nil {
+		return fail(DKIMFail, "%v", err)
+	}
+
+	result.Status = DKIMPass
+	return result
+}
+
+// parseCanonicalization splits a c= tag into its header and body parts,
+// returning empty strings for unknown algorithms
+func parseCanonicalization(c string) (header, body string) {
+	header, body = CanonicalizationSimple, CanonicalizationSimple
+	if c != "" {
+		var ok bool
+		header, body, ok = strings.Cut(strings.ToLower(c), "/")
+		if !ok {
+			body = CanonicalizationSimple
+		}
+	}
+	if header != CanonicalizationSimple && header != CanonicalizationRelaxed {
+		header = ""
+	}
+	if body != CanonicalizationSimple && body != CanonicalizationRelaxed {
+		body = ""
+	}
+	return header, body
+}
+
+func parseAlgorithm(a string) (crypto.Hash, string, error) {
+	switch a {
+	case "rsa-sha256":
+		return crypto.SHA256, "rsa", nil
+	case "rsa-sha1":
+		return crypto.SHA1, "rsa", nil
+	case "ed25519-sha256":
+		return crypto.SHA256, "ed25519", nil
+	}
+	return 0, "", fmt.Errorf("unsupported algorithm %q", a)
+}
+
+// signedHeaderData builds the data covered by the header hash: the signed
+// fields, each taken from the bottom of the header up, followed by the
+// DKIM-Signature field itself with an empty b= tag and no trailing CRLF
+func signedHeaderData(fields []headerField, names []string, sig headerField, canon string) []byte {
+	var buf bytes.Buffer
+	for _, f := range selectSignedFields(fields, names) {
+		buf.WriteString(canonicalizeHeader(f.raw, canon))
+	}
+
+	unsigned := canonicalizeHeader(stripSignatureData(sig.raw), canon)
+	buf.WriteString(strings.TrimSuffix(unsigned, "\r\n"))
+	return buf.Bytes()
+}
+
+// selectSignedFields picks the field each h= name refers to. Repeated names
+// refer to earlier instances, and names without a matching field are
+// skipped.
+func selectSignedFields(fields []headerField, names []string) []headerField {
+	var selected []headerField
+	used := make(map[int]bool)
+	for _, name := range names {
+		for i := len(fields) - 1; i >= 0; i-- {
+			if used[i] || !strings.EqualFold(fields[i].name, strings.TrimSpace(name)) {
+				continue
+			}
+			used[i] = true
+			selected = append(selected, fields[i])
+			break
+		}
+	}
+	return selected
+}
+
+// stripSignatureData empties the b= tag of a raw DKIM-Signature field,
+// leaving everything else, including the bh= tag, untouched
+func stripSignatureData(raw string) string {
+	name, value, _ := strings.Cut(raw, ":")
+	parts := strings.Split(value, ";")
+	for i, part := range parts {
+		tag, _, ok := strings.Cut(part, "=")
+		if ok && strings.TrimSpace(tag) == "b" {
+			parts[i] = tag + "="
+			if strings.HasSuffix(part, "\r\n") && i == len(parts)-1 {
+				parts[i] += "\r\n"
+			}
+		}
+	}
+	return name + ":" + strings.Join(parts, ";")
+}
+
+// canonicalizeHeader applies header canonicalization to a raw field
+func canonicalizeHeader(raw, canon string) string {
+	if canon == CanonicalizationSimple {
+		return raw
+	}
+
+	name, value, _ := strings.Cut(raw, ":")
+	value = strings.ReplaceAll(value, "\r\n", "")
+	value = collapseWSP(value)
+	return strings.ToLower(strings.TrimRight(name, " \t")) + ":" + strings.TrimSpace(value) + "\r\n"
+}
+
+// canonicalizeBody applies body canonicalization to a CRLF body
+func canonicalizeBody(body []byte, canon string) []byte {
+	lines := strings.SplitAfter(string(body), "\r\n")
+
+	var buf bytes.Buffer
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		if canon == CanonicalizationRelaxed {
+			line = strings.TrimSuffix(line, "\r\n")
+			line = strings.TrimRight(collapseWSP(line), " ") + "\r\n"
+		} else if !strings.HasSuffix(line, "\r\n") {
+			line += "\r\n"
+		}
+		buf.WriteString(line)
+	}
+
+	// Trailing empty lines are ignored
+	out := buf.Bytes()
+	for bytes.HasSuffix(out, []byte("\r\n\r\n")) {
+		out = out[:len(out)-2]
+	}
+	if len(out) == 0 && canon == CanonicalizationSimple {
+		return []byte("\r\n")
+	}
+	if bytes.Equal(out, []byte("\r\n")) && canon == CanonicalizationRelaxed {
+		return nil
+	}
+	return out
+}
+
+// collapseWSP replaces runs of spaces and tabs with a single space
+func collapseWSP(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		if r == ' ' || r == '\t' {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	if space {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(strings.TrimSpace(v), s) {
+			return true
+		}
+	}
+	return false
+}
+
+// dkimTempError marks key lookup failures that may go away on retry
+type dkimTempError struct {
+	err error
+}
+
+func (e *dkimTempError) Error() string {
+	return e.err.Error()
+}
+
+// lookupDKIMKey finds the public key for a domain and selector in the
+// registry, then through the resolver if one is configured
+func lookupDKIMKey(config DKIMConfig, domain, selector string) (crypto.PublicKey, string, error) {
+	for _, k := range config.Keys {
+		if !strings.EqualFold(k.Domain, domain) || !strings.EqualFold(k.Selector, selector) {
+			continue
+		}
+		data, err := os.ReadFile(k.KeyFile)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read key file: %v", err)
+		}
+		key, err := parseDKIMKey(data)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid key in %s: %v", k.KeyFile, err)
+		}
+		return key, "registry", nil
+	}
+
+	if config.Resolver == "" {
+		return nil, "", fmt.Errorf("no key for %s._domainkey.%s in the local registry", selector, domain)
+	}
+
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, config.Resolver)
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), dnsTimeout)
+	defer cancel()
+
+	name := selector + "._domainkey." + domain
+	records, err := resolver.LookupTXT(ctx, name)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return nil, "", fmt.Errorf("no key record at %s", name)
+		}
+		return nil, "", &dkimTempError{fmt.Errorf("key lookup for %s failed: %v", name, err)}
+	}
+	if len(records) != 1 {
+		return nil, "", fmt.Errorf("expected one key record at %s, found %d", name, len(records))
+	}
+
+	key, err := parseDKIMKey([]byte(records[0]))
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid key record at %s: %v", name, err)
+	}
+	return key, "dns", nil
+}
+
+// parseDKIMKey parses a PEM encoded key or a DKIM key record (RFC 6376
+// section 3.6.1). Private keys yield their public half.
+func parseDKIMKey(data []byte) (crypto.PublicKey, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		switch block.Type {
+		case "PUBLIC KEY":
+			return x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			return x509.ParsePKCS1PublicKey(block.Bytes)
+		case "RSA PRIVATE KEY", "PRIVATE KEY":
+			key, err := parsePrivateKey(block)
+			if err != nil {
+				return nil, err
+			}
+			return key.Public(), nil
+		}
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+
+	tags, err := parseTagList(string(data))
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := tags["v"]; ok && v != "DKIM1" {
+		return nil, fmt.Errorf("unsupported key version %q", v)
+	}
+	p, ok := tags["p"]
+	if !ok {
+		return nil, errors.New("record has no p= tag")
+	}
+	if p == "" {
+		return nil, errors.New("key has been revoked")
+	}
+	der, err := base64.StdEncoding.DecodeString(p)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key data: %v", err)
+	}
+
+	switch k := strings.ToLower(tags["k"]); k {
+	case "", "rsa":
+		if key, err := x509.ParsePKIXPublicKey(der); err == nil {
+			return key, nil
+		}
+		return x509.ParsePKCS1PublicKey(der)
+	case "ed25519":
+		if len(der) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("ed25519 key is %d bytes, want %d", len(der), ed25519.PublicKeySize)
+		}
+		return ed25519.PublicKey(der), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k)
+	}
+}
+
+// parsePrivateKey parses a PKCS #1 or PKCS #8 private key block
+func parsePrivateKey(block *pem.Block) (crypto.Signer, error) {
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+func verifyWithKey(key crypto.PublicKey, keyType string, hash crypto.Hash, data, signature []byte) error {
+	h := hash.New()
+	h.Write(data)
+	digest := h.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if keyType != "rsa" {
+			return fmt.Errorf("signature algorithm needs an %s key, got RSA", keyType)
+		}
+		if err := rsa.VerifyPKCS1v15(k, hash, digest, signature); err != nil {
+			return errors.New("signature mismatch: signed headers were changed after signing")
+		}
+	case ed25519.PublicKey:
+		if keyType != "ed25519" {
+			return fmt.Errorf("signature algorithm needs an %s key, got ed25519", keyType)
+		}
+		if !ed25519.Verify(k, digest, signature) {
+			return errors.New("signature mismatch: signed headers were changed after signing")
+		}
+	default:
+		return fmt.Errorf("unsupported key type %T", key)
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package smtp
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The example of RFC 6376 section 3.4.6
+const (
+	canonExampleHeader = "A: X\r\nB : Y\t\r\n\tZ  \r\n"
+	canonExampleBody   = " C \r\nD \t E\r\n\r\n\r\n"
+)
+
+func TestCanonicalizeHeader(t *testing.T) {
+	tests := []struct {
+		canon string
+		want  string
+	}{
+		{CanonicalizationSimple, canonExampleHeader},
+		{CanonicalizationRelaxed, "a:X\r\nb:Y Z\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.canon, func(t *testing.T) {
+			var got strings.Builder
+			for _, f := range splitHeaderFields([]byte(canonExampleHeader)) {
+				got.WriteString(canonicalizeHeader(f.raw, tt.canon))
+			}
+			if got.String() != tt.want {
+				t.Errorf("got %q, want %q", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalizeBody(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		canon string
+		want  string
+	}{
+		{"simple", canonExampleBody, CanonicalizationSimple, " C \r\nD \t E\r\n"},
+		{"relaxed", canonExampleBody, CanonicalizationRelaxed, " C\r\nD E\r\n"},
+		{"simple empty", "", CanonicalizationSimple, "\r\n"},
+		{"relaxed empty", "", CanonicalizationRelaxed, ""},
+		{"simple only empty lines", "\r\n\r\n", CanonicalizationSimple, "\r\n"},
+		{"relaxed only empty lines", "\r\n\r\n", CanonicalizationRelaxed, ""},
+		{"simple missing final CRLF", "a", CanonicalizationSimple, "a\r\n"},
+		{"relaxed missing final CRLF", "a \t", CanonicalizationRelaxed, "a\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(canonicalizeBody([]byte(tt.body), tt.canon))
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// writeTestKey writes key as a PEM file in a temporary directory and
+// returns its path
+func writeTestKey(t *testing.T, key interface{}) string {
+	t.Helper()
+
+	var block *pem.Block
+	switch k := key.(type) {
+	case crypto.Signer:
+		der, err := x509.MarshalPKCS8PrivateKey(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	default:
+		der, err := x509.MarshalPKIXPublicKey(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	}
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// signTestMessage signs msg for example.com with selector test, adding tags
+// to the signature. tags must include c= and h=.
+func signTestMessage(t *testing.T, key ed25519.PrivateKey, tags, msg string) string {
+	t.Helper()
+
+	parsed, err := parseTagList(tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	headerCanon, bodyCanon := parseCanonicalization(parsed["c"])
+	header, body := splitMessage([]byte(msg))
+
+	bodyHash := sha256.Sum256(canonicalizeBody(body, bodyCanon))
+	sig := headerField{
+		name: "DKIM-Signature",
+		raw: fmt.Sprintf("DKIM-Signature: v=1; a=ed25519-sha256; d=example.com; s=test; %s;\r\n\tbh=%s; b=\r\n",
+			tags, base64.StdEncoding.EncodeToString(bodyHash[:])),
+	}
+	data := signedHeaderData(splitHeaderFields(header), strings.Split(parsed["h"], ":"), sig, headerCanon)
+	digest := sha256.Sum256(data)
+	signature := ed25519.Sign(key, digest[:])
+
+	return strings.TrimSuffix(sig.raw, "\r\n") + base64.StdEncoding.EncodeToString(signature) + "\r\n" + msg
+}
+
+func TestVerifyDKIM(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := DKIMConfig{Keys: []DKIMKey{{Domain: "example.com", Selector: "test", KeyFile: writeTestKey(t, pub)}}}
+
+	msg := "From: a@example.com\r\nTo: b@example.net\r\nSubject: Hello\r\n\r\nHello there\r\n"
+	past := time.Now().Add(-time.Hour).Unix()
+	future := time.Now().Add(time.Hour).Unix()
+	addFrom := func(s string) string {
+		return "From: evil@example.org\r\n" + s
+	}
+
+	tests := []struct {
+		name   string
+		tags   string
+		config DKIMConfig
+		// Applied to the signed message before verifying
+		change func(string) string
+		status string
+		// Part of the failure reason
+		reason string
+	}{
+		{"pass", "c=relaxed/relaxed; h=from:to:subject", registry, nil, DKIMPass, ""},
+		{"changed body", "c=relaxed/relaxed; h=from:to:subject", registry, func(s string) string {
+			return strings.Replace(s, "Hello there", "Hello here", 1)
+		}, DKIMFail, "body hash mismatch"},
+		{"changed header", "c=relaxed/relaxed; h=from:to:subject", registry, func(s string) string {
+			return strings.Replace(s, "Subject: Hello", "Subject: Goodbye", 1)
+		}, DKIMFail, "signature mismatch"},
+		{"expired", fmt.Sprintf("c=relaxed/relaxed; h=from:to:subject; x=%d", past), registry, nil, DKIMFail, "signature expired"},
+		{"not expired", fmt.Sprintf("c=relaxed/relaxed; h=from:to:subject; x=%d", future), registry, nil, DKIMPass, ""},
+		{"missing key", "c=relaxed/relaxed; h=from:to:subject", DKIMConfig{}, nil, DKIMPermError, "no key for test._domainkey.example.com"},
+		{"from not signed", "c=relaxed/relaxed; h=to:subject", registry, nil, DKIMPermError, "From header is not signed"},
+		{"over-signed", "c=relaxed/relaxed; h=from:from:to:subject", registry, nil, DKIMPass, ""},
+		// Signed fields are taken from the bottom up, so a From added above
+		// the signed one is not covered...
+		{"added header", "c=relaxed/relaxed; h=from:to:subject", registry, addFrom, DKIMPass, ""},
+		// ...unless From is over-signed: the extra h= entry covers the absent
+		// second instance, so adding one breaks the signature
+		{"over-signed with added header", "c=relaxed/relaxed; h=from:from:to:subject", registry, addFrom, DKIMFail, "signature mismatch"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signed := signTestMessage(t, key, tt.tags, msg)
+			if tt.change != nil {
+				signed = tt.change(signed)
+			}
+
+			results := verifyDKIM(tt.config, []byte(signed))
+			if len(results) != 1 {
+				t.Fatalf("got %d results, want 1", len(results))
+			}
+			if results[0].Status != tt.status || !strings.Contains(results[0].Reason, tt.reason) {
+				t.Errorf("got %s (%s), want %s (%s)", results[0].Status, results[0].Reason, tt.status, tt.reason)
+			}
+		})
+	}
+}
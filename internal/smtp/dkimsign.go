@@ -0,0 +1,204 @@
+package smtp
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultSignedHeaders are signed when DKIMSigning.Headers is empty, if the
+// message has them
+var defaultSignedHeaders = []string{
+	"From", "Reply-To", "Subject", "Date", "To", "Cc", "Message-ID",
+	"In-Reply-To", "References", "MIME-Version", "Content-Type",
+	"Content-Transfer-Encoding",
+}
+
+// DKIMSigning configures the key used to preview how a relay would sign a
+// captured message
+type DKIMSigning struct {
+	// Signing domain (d= tag)
+	Domain string `json:"domain"`
+	// Selector (s= tag)
+	Selector string `json:"selector"`
+	// PEM encoded RSA or Ed25519 private key
+	KeyFile string `json:"keyFile"`
+	// Canonicalization as header/body, e.g. relaxed/relaxed. Defaults to
+	// relaxed/relaxed.
+	Canonicalization string `json:"canonicalization"`
+	// Header fields to sign. Every instance of a field present in the
+	// message is signed. Defaults to the usual set of content headers.
+	Headers []string `json:"headers"`
+}
+
+// DKIMSignature is a signed copy of a captured message
+type DKIMSignature struct {
+	// The message source with the DKIM-Signature field prepended
+	Signed string `json:"signed"`
+	// The DKIM-Signature field that was added
+	Header string `json:"header"`
+	// Canonicalization that was used, as header/body
+	Canonicalization string `json:"canonicalization"`
+	// Header names in the h= tag, in signing order
+	SignedHeaders []string `json:"signedHeaders"`
+	// Canonicalized header fields that went into the signature, in hash
+	// order, ending with the DKIM-Signature field itself
+	CoveredHeaders []string `json:"coveredHeaders"`
+}
+
+// Validate checks that a signing key is fully described if one is set
+func (c DKIMSigning) Validate() error {
+	if c.Domain == "" && c.Selector == "" && c.KeyFile == "" {
+		return nil
+	}
+	if c.Domain == "" || c.Selector == "" || c.KeyFile == "" {
+		return errors.New("DKIM signing needs a domain, selector and key file")
+	}
+	if c.Canonicalization != "" {
+		if h, b := parseCanonicalization(c.Canonicalization); h == "" || b == "" {
+			return fmt.Errorf("unknown canonicalization %q", c.Canonicalization)
+		}
+	}
+	return nil
+}
+
+// SignDKIM signs a raw message the way a relay configured with config
+// would. canonicalization overrides the configured one if not empty.
+func SignDKIM(raw []byte, config DKIMSigning, canonicalization string) (*DKIMSignature, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.KeyFile == "" {
+		return nil, errors.New("no DKIM signing key configured")
+	}
+
+	if canonicalization == "" {
+		canonicalization = config.Canonicalization
+	}
+	if canonicalization == "" {
+		canonicalization = CanonicalizationRelaxed + "/" + CanonicalizationRelaxed
+	}
+	headerCanon, bodyCanon := parseCanonicalization(canonicalization)
+	if headerCanon == "" || bodyCanon == "" {
+		return nil, fmt.Errorf("unknown canonicalization %q", canonicalization)
+	}
+
+	key, err := readSigningKey(config.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	algorithm := "rsa-sha256"
+	if _, ok := key.(ed25519.PrivateKey); ok {
+		algorithm = "ed25519-sha256"
+	}
+
+	header, body := splitMessage(raw)
+	fields := splitHeaderFields(header)
+
+	headers := config.Headers
+	if len(headers) == 0 {
+		headers = defaultSignedHeaders
+	}
+	names := signedHeaderNames(fields, headers)
+	if !containsFold(names, "From") {
+		return nil, errors.New("message has no From header to sign")
+	}
+
+	h := crypto.SHA256.New()
+	h.Write(canonicalizeBody(body, bodyCanon))
+	bodyHash := base64.StdEncoding.EncodeToString(h.Sum(nil))
+
+	sig := headerField{
+		name: "DKIM-Signature",
+		raw: fmt.Sprintf("DKIM-Signature: v=1; a=%s; c=%s/%s; d=%s; s=%s;\r\n\tt=%d; h=%s;\r\n\tbh=%s;\r\n\tb=\r\n",
+			algorithm, headerCanon, bodyCanon, config.Domain, config.Selector,
+			time.Now().Unix(), strings.Join(names, ":"), bodyHash),
+	}
+
+	data := signedHeaderData(fields, names, sig, headerCanon)
+	digest := crypto.SHA256.New()
+	digest.Write(data)
+
+	var signature []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest.Sum(nil))
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(k, digest.Sum(nil))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %v", err)
+	}
+
+	field := strings.TrimSuffix(sig.raw, "\r\n") + foldBase64(base64.StdEncoding.EncodeToString(signature)) + "\r\n"
+
+	covered := []string{}
+	for _, f := range selectSignedFields(fields, names) {
+		covered = append(covered, strings.TrimSuffix(canonicalizeHeader(f.raw, headerCanon), "\r\n"))
+	}
+	covered = append(covered, strings.TrimSuffix(canonicalizeHeader(sig.raw, headerCanon), "\r\n"))
+
+	return &DKIMSignature{
+		Signed:           field + string(raw),
+		Header:           field,
+		Canonicalization: headerCanon + "/" + bodyCanon,
+		SignedHeaders:    names,
+		CoveredHeaders:   covered,
+	}, nil
+}
+
+// signedHeaderNames lists each wanted header once per instance in the
+// message, so that every instance is covered
+func signedHeaderNames(fields []headerField, wanted []string) []string {
+	names := []string{}
+	for _, w := range wanted {
+		w = strings.TrimSpace(w)
+		for _, f := range fields {
+			if strings.EqualFold(f.name, w) {
+				names = append(names, w)
+			}
+		}
+	}
+	return names
+}
+
+// foldBase64 folds signature data into lines short enough for a header
+func foldBase64(s string) string {
+	const width = 72
+	var b strings.Builder
+	for len(s) > width {
+		b.WriteString(s[:width])
+		b.WriteString("\r\n\t ")
+		s = s[width:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+func readSigningKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM encoded key", path)
+	}
+	key, err := parsePrivateKey(block)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing key in %s: %v", path, err)
+	}
+	switch key.(type) {
+	case *rsa.PrivateKey, ed25519.PrivateKey:
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported signing key type %T", key)
+}
@@ -0,0 +1,89 @@
+package smtp
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+)
+
+func TestSignDKIMRoundTrip(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []struct {
+		name string
+		key  crypto.Signer
+	}{
+		{"ed25519", edKey},
+		{"rsa", rsaKey},
+	}
+
+	msg := "From: a@example.com\r\nTo: b@example.net\r\nSubject:  Hello\r\n there\r\n\r\nHello  world\r\n\r\n"
+	// Whitespace changes that relaxed canonicalization ignores and simple
+	// doesn't
+	changeHeader := func(s string) string {
+		return strings.Replace(s, "Subject:  Hello\r\n there", "Subject: Hello there", 1)
+	}
+	changeBody := func(s string) string {
+		return strings.Replace(s, "Hello  world", "Hello world \t", 1)
+	}
+	statusAfterChange := func(canon string) string {
+		if canon == CanonicalizationRelaxed {
+			return DKIMPass
+		}
+		return DKIMFail
+	}
+
+	for _, k := range keys {
+		keyFile := writeTestKey(t, k.key)
+		signing := DKIMSigning{Domain: "example.com", Selector: "test", KeyFile: keyFile}
+		// The registry takes the private key and verifies with its public half
+		verifying := DKIMConfig{Keys: []DKIMKey{{Domain: "example.com", Selector: "test", KeyFile: keyFile}}}
+
+		for _, headerCanon := range []string{CanonicalizationSimple, CanonicalizationRelaxed} {
+			for _, bodyCanon := range []string{CanonicalizationSimple, CanonicalizationRelaxed} {
+				canon := headerCanon + "/" + bodyCanon
+				t.Run(k.name+" "+canon, func(t *testing.T) {
+					sig, err := SignDKIM([]byte(msg), signing, canon)
+					if err != nil {
+						t.Fatalf("SignDKIM: %v", err)
+					}
+					if sig.Canonicalization != canon {
+						t.Errorf("Canonicalization = %s, want %s", sig.Canonicalization, canon)
+					}
+
+					tests := []struct {
+						name   string
+						change func(string) string
+						status string
+					}{
+						{"unchanged", nil, DKIMPass},
+						{"header whitespace", changeHeader, statusAfterChange(headerCanon)},
+						{"body whitespace", changeBody, statusAfterChange(bodyCanon)},
+					}
+					for _, tt := range tests {
+						signed := sig.Signed
+						if tt.change != nil {
+							signed = tt.change(signed)
+						}
+						results := verifyDKIM(verifying, []byte(signed))
+						if len(results) != 1 {
+							t.Fatalf("%s: got %d results, want 1", tt.name, len(results))
+						}
+						if results[0].Status != tt.status {
+							t.Errorf("%s: got %s (%s), want %s", tt.name, results[0].Status, results[0].Reason, tt.status)
+						}
+					}
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package smtp
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// DMARC results and dispositions (RFC 7489)
+const (
+	DMARCNone      = "none"
+	DMARCPass      = "pass"
+	DMARCFail      = "fail"
+	DMARCPermError = "permerror"
+
+	DispositionNone       = "none"
+	DispositionQuarantine = "quarantine"
+	DispositionReject     = "reject"
+)
+
+// AuthResults is the offline SPF and DMARC evaluation of a message
+type AuthResults struct {
+	// SPF result for the envelope sender
+	SPF SPFResult `json:"spf"`
+	// DMARC result for the header From domain, including alignment
+	DMARC DMARCResult `json:"dmarc"`
+}
+
+// DMARCResult is the outcome of evaluating the DMARC policy of the header
+// From domain
+type DMARCResult struct {
+	// none, pass, fail or permerror
+	Result string `json:"result"`
+	// Domain of the header From address
+	Domain string `json:"domain"`
+	// Domain the DMARC record was found at: the From domain or its
+	// organizational domain
+	PolicyDomain string `json:"policyDomain"`
+	// DMARC record of PolicyDomain
+	Record string `json:"record"`
+	// Policy that applies (p= or sp= tag)
+	Policy string `json:"policy"`
+	// What a receiver following the policy would do: none, quarantine or
+	// reject
+	Disposition string `json:"disposition"`
+	// Percentage of failing messages the policy applies to (pct= tag)
+	Percent int `json:"percent"`
+	// Whether SPF passed for a domain aligned with the From domain
+	SPFAligned bool `json:"spfAligned"`
+	// Whether a DKIM signature passed for a domain aligned with the From
+	// domain
+	DKIMAligned bool `json:"dkimAligned"`
+	// Signing domain of the first aligned, passing DKIM signature
+	DKIMDomain string `json:"dkimDomain"`
+	// Explanation of the result
+	Reason string `json:"reason"`
+}
+
+// EvaluateAuth checks SPF for the envelope sender and HELO name, and DMARC
+// for the header From address, using only the DNS data in config
+func EvaluateAuth(config ZoneConfig, mailFrom, helo, headerFrom, clientIP string, dkim []DKIMResult) *AuthResults {
+	z, err := config.load()
+	if err != nil {
+		reason := fmt.Sprintf("zone unavailable: %v", err)
+		return &AuthResults{
+			SPF:   SPFResult{Result: SPFTempError, Reason: reason},
+			DMARC: DMARCResult{Result: DMARCNone, Disposition: DispositionNone, Reason: reason},
+		}
+	}
+
+	spf := checkSPF(z, net.ParseIP(clientIP), mailFrom, helo)
+	return &AuthResults{
+		SPF:   spf,
+		DMARC: checkDMARC(z, headerFrom, spf, dkim),
+	}
+}
+
+func checkDMARC(z *zone, headerFrom string, spf SPFResult, dkim []DKIMResult) DMARCResult {
+	result := DMARCResult{Result: DMARCNone, Disposition: DispositionNone}
+
+	domain, err := fromDomain(headerFrom)
+	if err != nil {
+		result.Result = DMARCPermError
+		result.Reason = err.Error()
+		return result
+	}
+	result.Domain = domain
+
+	// Look for a record at the From domain, then at its organizational domain
+	org := organizationalDomain(domain)
+	record, tags := dmarcRecord(z, domain)
+	result.PolicyDomain = domain
+	if record == "" && org != domain {
+		record, tags = dmarcRecord(z, org)
+		result.PolicyDomain = org
+	}
+
+	strictSPF, strictDKIM := false, false
+	if record != "" {
+		result.Record = record
+		strictSPF = strings.EqualFold(tags["aspf"], "s")
+		strictDKIM = strings.EqualFold(tags["adkim"], "s")
+	}
+
+	result.SPFAligned = spf.Result == SPFPass && aligned(spf.Domain, domain, strictSPF)
+	for _, r := range dkim {
+		if r.Status == DKIMPass && aligned(r.Domain, domain, strictDKIM) {
+			result.DKIMAligned = true
+			result.DKIMDomain = r.Domain
+			break
+		}
+	}
+
+	if record == "" {
+		result.PolicyDomain = ""
+		result.Reason = fmt.Sprintf("%s has no DMARC record", domain)
+		return result
+	}
+	if tags == nil {
+		result.Result = DMARCPermError
+		result.Reason = "invalid DMARC record"
+		return result
+	}
+
+	result.Policy = strings.ToLower(tags["p"])
+	if sp := tags["sp"]; sp != "" && result.PolicyDomain != domain {
+		result.Policy = strings.ToLower(sp)
+	}
+	switch result.Policy {
+	case DispositionNone, DispositionQuarantine, DispositionReject:
+	default:
+		result.Result = DMARCPermError
+		result.Reason = fmt.Sprintf("invalid policy %q", result.Policy)
+		return result
+	}
+
+	result.Percent = 100
+	if pct := tags["pct"]; pct != "" {
+		n, err := strconv.Atoi(pct)
+		if err != nil || n < 0 || n > 100 {
+			result.Result = DMARCPermError
+			result.Reason = fmt.Sprintf("invalid pct %q", pct)
+			return result
+		}
+		result.Percent = n
+	}
+
+	if result.SPFAligned || result.DKIMAligned {
+		result.Result = DMARCPass
+		switch {
+		case result.DKIMAligned:
+			result.Reason = fmt.Sprintf("DKIM passed for aligned domain %s", result.DKIMDomain)
+		default:
+			result.Reason = fmt.Sprintf("SPF passed for aligned domain %s", spf.Domain)
+		}
+		return result
+	}
+
+	result.Result = DMARCFail
+	result.Disposition = result.Policy
+	result.Reason = fmt.Sprintf("neither SPF (%s for %s) nor DKIM passed for a domain aligned with %s",
+		spf.Result, spf.Domain, domain)
+	if result.Percent < 100 {
+		result.Reason += fmt.Sprintf("; the policy applies to %d%% of failing messages", result.Percent)
+	}
+	return result
+}
+
+// dmarcRecord returns the DMARC record published for domain and its tags.
+// Tags are nil if the record does not parse.
+func dmarcRecord(z *zone, domain string) (string, map[string]string) {
+	for _, txt := range z.lookup("_dmarc."+domain, "TXT") {
+		if !strings.HasPrefix(strings.ToLower(strings.ReplaceAll(txt, " ", "")), "v=dmarc1") {
+			continue
+		}
+		tags, err := parseTagList(txt)
+		if err != nil || tags["p"] == "" {
+			return txt, nil
+		}
+		return txt, tags
+	}
+	return "", nil
+}
+
+// fromDomain returns the domain of the first header From address
+func fromDomain(from string) (string, error) {
+	if from == "" {
+		return "", fmt.Errorf("message has no From header")
+	}
+	addrs, err := mail.ParseAddressList(from)
+	if err != nil || len(addrs) == 0 {
+		return "", fmt.Errorf("invalid From header %q", from)
+	}
+	_, domain, ok := strings.Cut(addrs[0].Address, "@")
+	if !ok || domain == "" {
+		return "", fmt.Errorf("From address %q has no domain", addrs[0].Address)
+	}
+	return normalizeName(domain), nil
+}
+
+// organizationalDomain returns the registered domain of name, using the
+// public suffix list
+func organizationalDomain(name string) string {
+	if org, err := publicsuffix.EffectiveTLDPlusOne(name); err == nil {
+		return org
+	}
+	return name
+}
+
+// aligned compares two domains in strict or relaxed mode (RFC 7489
+// section 3.1)
+func aligned(a, b string, strict bool) bool {
+	a, b = normalizeName(a), normalizeName(b)
+	if a == "" || b == "" {
+		return false
+	}
+	if strict {
+		return a == b
+	}
+	return organizationalDomain(a) == organizationalDomain(b)
+}
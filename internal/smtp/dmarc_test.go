@@ -0,0 +1,84 @@
+package smtp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckDMARC(t *testing.T) {
+	spfPass := func(domain string) SPFResult { return SPFResult{Result: SPFPass, Domain: domain} }
+	spfFail := SPFResult{Result: SPFFail, Domain: "example.com"}
+	dkimPass := func(domain string) []DKIMResult { return []DKIMResult{{Status: DKIMPass, Domain: domain}} }
+
+	tests := []struct {
+		name string
+		zone []string
+		from string
+		spf  SPFResult
+		dkim []DKIMResult
+		// Expected result, disposition and policy domain
+		result       string
+		disposition  string
+		policyDomain string
+	}{
+		{"no record", nil, "a@example.com", spfPass("example.com"), nil, DMARCNone, DispositionNone, ""},
+		{"no From", []string{`_dmarc.example.com. TXT "v=DMARC1; p=reject"`}, "", spfPass("example.com"), nil, DMARCPermError, DispositionNone, ""},
+		{"spf aligned", []string{`_dmarc.example.com. TXT "v=DMARC1; p=reject"`}, "a@example.com", spfPass("example.com"), nil, DMARCPass, DispositionNone, "example.com"},
+		{"dkim aligned", []string{`_dmarc.example.com. TXT "v=DMARC1; p=reject"`}, "a@example.com", spfFail, dkimPass("example.com"), DMARCPass, DispositionNone, "example.com"},
+		{"nothing aligned", []string{`_dmarc.example.com. TXT "v=DMARC1; p=quarantine"`}, "a@example.com", spfPass("example.net"), dkimPass("example.net"), DMARCFail, DispositionQuarantine, "example.com"},
+		{"failing dkim", []string{`_dmarc.example.com. TXT "v=DMARC1; p=reject"`}, "a@example.com", spfFail, []DKIMResult{{Status: DKIMFail, Domain: "example.com"}}, DMARCFail, DispositionReject, "example.com"},
+
+		// Relaxed alignment compares organizational domains, strict ones
+		// the exact domain
+		{"relaxed spf", []string{`_dmarc.example.com. TXT "v=DMARC1; p=reject"`}, "a@example.com", spfPass("bounces.example.com"), nil, DMARCPass, DispositionNone, "example.com"},
+		{"strict spf", []string{`_dmarc.example.com. TXT "v=DMARC1; p=reject; aspf=s"`}, "a@example.com", spfPass("bounces.example.com"), nil, DMARCFail, DispositionReject, "example.com"},
+		{"strict spf exact", []string{`_dmarc.example.com. TXT "v=DMARC1; p=reject; aspf=s"`}, "a@example.com", spfPass("example.com"), nil, DMARCPass, DispositionNone, "example.com"},
+		{"relaxed dkim", []string{`_dmarc.news.example.com. TXT "v=DMARC1; p=reject"`}, "a@news.example.com", spfFail, dkimPass("example.com"), DMARCPass, DispositionNone, "news.example.com"},
+		{"strict dkim", []string{`_dmarc.news.example.com. TXT "v=DMARC1; p=reject; adkim=s"`}, "a@news.example.com", spfFail, dkimPass("example.com"), DMARCFail, DispositionReject, "news.example.com"},
+		// co.uk is a public suffix, so these are different organizations
+		{"public suffix", []string{`_dmarc.example.co.uk. TXT "v=DMARC1; p=reject"`}, "a@example.co.uk", spfPass("other.co.uk"), nil, DMARCFail, DispositionReject, "example.co.uk"},
+
+		// Without a record at the From domain, the organizational domain's
+		// applies, with its subdomain policy
+		{"organizational domain", []string{`_dmarc.example.co.uk. TXT "v=DMARC1; p=quarantine"`}, "a@mail.news.example.co.uk", spfFail, nil, DMARCFail, DispositionQuarantine, "example.co.uk"},
+		{"subdomain policy", []string{`_dmarc.example.co.uk. TXT "v=DMARC1; p=none; sp=reject"`}, "a@news.example.co.uk", spfFail, nil, DMARCFail, DispositionReject, "example.co.uk"},
+		{"subdomain policy at the domain", []string{`_dmarc.example.co.uk. TXT "v=DMARC1; p=none; sp=reject"`}, "a@example.co.uk", spfFail, nil, DMARCFail, DispositionNone, "example.co.uk"},
+		{"record at the From domain first", []string{
+			`_dmarc.example.com. TXT "v=DMARC1; p=reject"`,
+			`_dmarc.news.example.com. TXT "v=DMARC1; p=none"`,
+		}, "a@news.example.com", spfFail, nil, DMARCFail, DispositionNone, "news.example.com"},
+
+		{"invalid policy", []string{`_dmarc.example.com. TXT "v=DMARC1; p=drop"`}, "a@example.com", spfFail, nil, DMARCPermError, DispositionNone, "example.com"},
+		{"no policy", []string{`_dmarc.example.com. TXT "v=DMARC1; rua=mailto:d@example.com"`}, "a@example.com", spfFail, nil, DMARCPermError, DispositionNone, "example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkDMARC(testZone(t, tt.zone...), tt.from, tt.spf, tt.dkim)
+			if got.Result != tt.result || got.Disposition != tt.disposition || got.PolicyDomain != tt.policyDomain {
+				t.Errorf("got %s/%s at %q (%s), want %s/%s at %q",
+					got.Result, got.Disposition, got.PolicyDomain, got.Reason, tt.result, tt.disposition, tt.policyDomain)
+			}
+		})
+	}
+}
+
+func TestEvaluateAuth(t *testing.T) {
+	config := ZoneConfig{Records: []ZoneRecord{
+		{Name: "bounces.example.com", Type: "TXT", Value: "v=spf1 ip4:192.0.2.0/24 -all"},
+		{Name: "_dmarc.example.com", Type: "TXT", Value: "v=DMARC1; p=reject"},
+	}}
+
+	got := EvaluateAuth(config, "b@bounces.example.com", "mail.example.com", `"A" <a@example.com>`, "192.0.2.10", nil)
+	if got.SPF.Result != SPFPass || got.DMARC.Result != DMARCPass || !got.DMARC.SPFAligned {
+		t.Errorf("got SPF %s, DMARC %s (%s), want both to pass with SPF aligned", got.SPF.Result, got.DMARC.Result, got.DMARC.Reason)
+	}
+
+	got = EvaluateAuth(config, "b@bounces.example.com", "mail.example.com", `"A" <a@example.com>`, "198.51.100.1", nil)
+	if got.SPF.Result != SPFFail || got.DMARC.Disposition != DispositionReject {
+		t.Errorf("got SPF %s, DMARC disposition %s, want fail and reject", got.SPF.Result, got.DMARC.Disposition)
+	}
+	if !strings.Contains(got.DMARC.Reason, "neither SPF") {
+		t.Errorf("reason %q doesn't explain the failure", got.DMARC.Reason)
+	}
+}
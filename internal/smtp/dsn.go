@@ -0,0 +1,375 @@
+package smtp
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	netsmtp "net/smtp"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-smtp"
+	"github.com/google/uuid"
+)
+
+// DSNParams holds the Delivery Status Notification parameters (RFC 3461)
+// a message was submitted with
+type DSNParams struct {
+	// RET parameter of MAIL: FULL or HDRS, empty if not given
+	Return string `json:"ret"`
+	// ENVID parameter of MAIL, empty if not given
+	EnvelopeID string `json:"envid"`
+	// Per-recipient parameters, in RCPT order
+	Recipients []DSNRecipient `json:"recipients"`
+}
+
+// DSNRecipient holds the DSN parameters of a single RCPT command
+type DSNRecipient struct {
+	// Envelope recipient address
+	Address string `json:"address"`
+	// NOTIFY parameter: NEVER or any of SUCCESS, FAILURE and DELAY
+	Notify []string `json:"notify"`
+	// ORCPT parameter in "type;address" form, empty if not given
+	OriginalRecipient string `json:"orcpt"`
+}
+
+// BounceConfig controls the generation of RFC 3464 bounce messages
+type BounceConfig struct {
+	// Whether recipients matching Recipients bounce. Bounce fault rules
+	// work regardless of this setting.
+	Enabled bool `json:"enabled"`
+	// Case-insensitive glob patterns of recipients that always bounce
+	Recipients []string `json:"recipients"`
+	// SMTP reply code reported for pattern bounces; 4xx reports a delay,
+	// 5xx a failure. Defaults to 550.
+	Code int `json:"code"`
+	// Enhanced status code reported for pattern bounces, defaults to 5.1.1
+	// or 4.4.1 depending on Code
+	Status string `json:"status"`
+	// Diagnostic text reported for pattern bounces
+	Message string `json:"message"`
+	// Address bounces are sent to instead of being delivered back into
+	// PostPilot addressed to the envelope sender
+	SendTo string `json:"sendTo"`
+	// host:port of the SMTP server used to reach SendTo
+	Relay string `json:"relay"`
+}
+
+// bounceReason explains why a recipient bounced
+type bounceReason struct {
+	code    int
+	status  string
+	message string
+}
+
+// bounceRules is a BounceConfig with its recipient patterns compiled
+type bounceRules struct {
+	BounceConfig
+	recipients []*regexp.Regexp
+}
+
+var enhancedStatusPattern = regexp.MustCompile(`^[245]\.\d{1,3}\.\d{1,3}$`)
+
+// Validate checks that the configuration is usable
+func (c BounceConfig) Validate() error {
+	if c.Code != 0 && (c.Code < 400 || c.Code > 599) {
+		return fmt.Errorf("bounce code %d is not a 4xx or 5xx code", c.Code)
+	}
+	if c.Status != "" && !enhancedStatusPattern.MatchString(c.Status) {
+		return fmt.Errorf("invalid enhanced status code %q", c.Status)
+	}
+	if c.SendTo != "" {
+		if _, err := mail.ParseAddress(c.SendTo); err != nil {
+			return fmt.Errorf("invalid bounce address %q: %w", c.SendTo, err)
+		}
+		if _, _, err := net.SplitHostPort(c.Relay); err != nil {
+			return fmt.Errorf("bounces sent to %s need a relay in host:port form", c.SendTo)
+		}
+	}
+	return nil
+}
+
+func newBounceRules(config BounceConfig) bounceRules {
+	patterns := make([]*regexp.Regexp, 0, len(config.Recipients))
+	for _, p := range config.Recipients {
+		if re := compileGlob(strings.TrimSpace(p)); re != nil {
+			patterns = append(patterns, re)
+		}
+	}
+	return bounceRules{BounceConfig: config, recipients: patterns}
+}
+
+// match returns the reason recipient bounces because of a configured
+// pattern, or nil
+func (r bounceRules) match(recipient string) *bounceReason {
+	if !r.Enabled {
+		return nil
+	}
+	for _, re := range r.recipients {
+		if re.MatchString(recipient) {
+			return newBounceReason(r.Code, r.Status, r.Message)
+		}
+	}
+	return nil
+}
+
+func newBounceReason(code int, status, message string) *bounceReason {
+	if code == 0 {
+		code = 550
+	}
+	if status == "" {
+		status = "5.1.1"
+		if code < 500 {
+			status = "4.4.1"
+		}
+	}
+	if message == "" {
+		message = "Simulated delivery failure"
+	}
+	return &bounceReason{code: code, status: status, message: message}
+}
+
+// faultBounceReason returns the bounce reason of a bounce fault rule
+func faultBounceReason(rule *FaultRule) *bounceReason {
+	msg := rule.Message
+	if msg == "" {
+		msg = fmt.Sprintf("Simulated failure (%s)", rule.Name)
+	}
+	return newBounceReason(rule.Code, "", msg)
+}
+
+// action is the DSN Action reported for the reason
+func (r *bounceReason) action() string {
+	if r.code < 500 {
+		return "delayed"
+	}
+	return "failed"
+}
+
+// wanted reports whether notify, the recipient's NOTIFY parameter, asks
+// for a DSN with action. Without NOTIFY, failures and delays are reported.
+func (r *bounceReason) wanted(notify []string) bool {
+	if len(notify) == 0 {
+		return true
+	}
+	want := "FAILURE"
+	if r.action() == "delayed" {
+		want = "DELAY"
+	}
+	for _, n := range notify {
+		if n == want {
+			return true
+		}
+	}
+	return false
+}
+
+// recordDSN remembers the RCPT parameters of recipient
+func (s *Session) recordDSN(to string, opts *smtp.RcptOptions) {
+	r := DSNRecipient{Address: to, Notify: []string{}}
+	if opts != nil {
+		for _, n := range opts.Notify {
+			r.Notify = append(r.Notify, string(n))
+		}
+		if opts.OriginalRecipient != "" {
+			r.OriginalRecipient = string(opts.OriginalRecipientType) + ";" + opts.OriginalRecipient
+		}
+	}
+	s.dsn.Recipients = append(s.dsn.Recipients, r)
+}
+
+// usedDSN reports whether the transaction carried any DSN parameter
+func (s *Session) usedDSN() bool {
+	if s.dsn.Return != "" || s.dsn.EnvelopeID != "" {
+		return true
+	}
+	for _, r := range s.dsn.Recipients {
+		if len(r.Notify) > 0 || r.OriginalRecipient != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// markBounce records that recipient is accepted but will bounce
+func (s *Session) markBounce(recipient string, reason *bounceReason) {
+	if s.bounces == nil {
+		s.bounces = make(map[string]*bounceReason)
+	}
+	if _, ok := s.bounces[recipient]; !ok {
+		s.bounces[recipient] = reason
+	}
+}
+
+// sendBounces generates and delivers a bounce for the recipients of email
+// marked to bounce, honoring their NOTIFY parameters
+func (s *Session) sendBounces(email *Email) {
+	if len(s.bounces) == 0 {
+		return
+	}
+
+	var failed []DSNRecipient
+	reasons := make(map[string]*bounceReason)
+	for _, r := range s.dsn.Recipients {
+		reason, ok := s.bounces[r.Address]
+		if !ok {
+			continue
+		}
+		if !reason.wanted(r.Notify) {
+			s.rec.event("No DSN for <%s>: NOTIFY=%s", r.Address, strings.Join(r.Notify, ","))
+			continue
+		}
+		failed = append(failed, r)
+		reasons[r.Address] = reason
+	}
+	if len(failed) == 0 {
+		return
+	}
+
+	// Bouncing a bounce could loop forever (RFC 5321 section 4.5.5)
+	if s.from == "" {
+		s.rec.event("No bounce generated for a message with a null reverse-path")
+		return
+	}
+
+	config := s.server.bounces.get().BounceConfig
+	to := s.from
+	if config.SendTo != "" {
+		to = config.SendTo
+	}
+
+	raw, err := buildBounce(s.server.host, to, s.dsn, failed, reasons, email)
+	if err != nil {
+		log.Printf("Session %s: failed to build bounce: %v", s.id, err)
+		s.rec.error("failed to build bounce: %v", err)
+		return
+	}
+
+	addrs := make([]string, len(failed))
+	for i, r := range failed {
+		addrs[i] = "<" + r.Address + ">"
+	}
+	s.rec.event("Generated bounce for %s, sending it to <%s>", strings.Join(addrs, ", "), to)
+
+	if config.SendTo != "" {
+		go func() {
+			if err := netsmtp.SendMail(config.Relay, nil, "", []string{to}, raw); err != nil {
+				log.Printf("Failed to send bounce for %s to %s via %s: %v", email.ID, to, config.Relay, err)
+			}
+		}()
+		return
+	}
+
+	bounce := &Email{
+		ID:        uuid.New().String(),
+		From:      "",
+		To:        []string{to},
+		Timestamp: time.Now(),
+		Raw:       string(raw),
+		BounceOf:  email.ID,
+	}
+	if err := parseEmail(bounce, bytes.NewReader(raw)); err != nil {
+		log.Printf("Failed to parse generated bounce: %v", err)
+		return
+	}
+	parseContent(bounce, 0)
+	go s.server.store(bounce)
+}
+
+// buildBounce renders an RFC 3464 multipart/report for the failed recipients
+func buildBounce(host, to string, params DSNParams, failed []DSNRecipient, reasons map[string]*bounceReason, original *Email) ([]byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	delayedOnly := true
+	for _, r := range failed {
+		delayedOnly = delayedOnly && reasons[r.Address].action() == "delayed"
+	}
+
+	// Human readable explanation
+	var text strings.Builder
+	fmt.Fprintf(&text, "This is the mail system at host %s.\r\n\r\n", host)
+	if delayedOnly {
+		text.WriteString("Delivery of your message to the following recipients is delayed.\r\n\r\n")
+	} else {
+		text.WriteString("Your message could not be delivered to one or more recipients.\r\n\r\n")
+	}
+	for _, r := range failed {
+		reason := reasons[r.Address]
+		fmt.Fprintf(&text, "<%s>: %d %s %s\r\n", r.Address, reason.code, reason.status, reason.message)
+	}
+	if err := writePart(mw, "text/plain; charset=utf-8", text.String()); err != nil {
+		return nil, err
+	}
+
+	// Machine readable delivery status
+	var status strings.Builder
+	fmt.Fprintf(&status, "Reporting-MTA: dns; %s\r\n", host)
+	if params.EnvelopeID != "" {
+		fmt.Fprintf(&status, "Original-Envelope-Id: %s\r\n", params.EnvelopeID)
+	}
+	fmt.Fprintf(&status, "Arrival-Date: %s\r\n", original.Timestamp.Format(time.RFC1123Z))
+	for _, r := range failed {
+		reason := reasons[r.Address]
+		status.WriteString("\r\n")
+		if r.OriginalRecipient != "" {
+			fmt.Fprintf(&status, "Original-Recipient: %s\r\n", r.OriginalRecipient)
+		}
+		fmt.Fprintf(&status, "Final-Recipient: rfc822; %s\r\n", r.Address)
+		fmt.Fprintf(&status, "Action: %s\r\n", reason.action())
+		fmt.Fprintf(&status, "Status: %s\r\n", reason.status)
+		fmt.Fprintf(&status, "Diagnostic-Code: smtp; %d %s %s\r\n", reason.code, reason.status, reason.message)
+	}
+	if err := writePart(mw, "message/delivery-status", status.String()); err != nil {
+		return nil, err
+	}
+
+	// The returned message, or only its header with RET=HDRS
+	if params.Return == string(smtp.DSNReturnHeaders) {
+		header, _, _ := strings.Cut(original.Raw, "\r\n\r\n")
+		err := writePart(mw, "text/rfc822-headers", header+"\r\n")
+		if err != nil {
+			return nil, err
+		}
+	} else if err := writePart(mw, "message/rfc822", original.Raw); err != nil {
+		return nil, err
+	}
+
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	subject := "Undelivered Mail Returned to Sender"
+	if delayedOnly {
+		subject = "Delayed Mail (still being retried)"
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: Mail Delivery System <MAILER-DAEMON@%s>\r\n", host)
+	fmt.Fprintf(&msg, "To: <%s>\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: <%s@%s>\r\n", uuid.New().String(), host)
+	msg.WriteString("Auto-Submitted: auto-replied\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/report; report-type=delivery-status; boundary=%q\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+func writePart(mw *multipart.Writer, contentType, content string) error {
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", contentType)
+	w, err := mw.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write([]byte(content))
+	return err
+}
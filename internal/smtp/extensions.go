@@ -0,0 +1,176 @@
+package smtp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"unicode/utf8"
+
+	"github.com/emersion/go-smtp"
+)
+
+// Extension names as advertised in the EHLO reply
+const (
+	ExtensionSMTPUTF8   = "SMTPUTF8"
+	Extension8BitMIME   = "8BITMIME"
+	ExtensionBinaryMIME = "BINARYMIME"
+	ExtensionChunking   = "CHUNKING"
+	ExtensionDSN        = "DSN"
+)
+
+// Extensions toggles the optional ESMTP extensions a listener offers, so
+// that clients can be tested both with and without them
+type Extensions struct {
+	// Internationalized addresses and headers (RFC 6531)
+	SMTPUTF8 bool `json:"smtputf8"`
+	// 8-bit message bodies with BODY=8BITMIME (RFC 6152)
+	EightBitMIME bool `json:"eightBitMime"`
+	// Binary message bodies with BODY=BINARYMIME (RFC 3030), requires chunking
+	BinaryMIME bool `json:"binaryMime"`
+	// Message transfer with BDAT instead of DATA (RFC 3030)
+	Chunking bool `json:"chunking"`
+	// Delivery Status Notification parameters (RFC 3461)
+	DSN bool `json:"dsn"`
+}
+
+// DefaultExtensions returns the extensions offered when none are configured
+func DefaultExtensions() Extensions {
+	return Extensions{
+		SMTPUTF8:     true,
+		EightBitMIME: true,
+		BinaryMIME:   true,
+		Chunking:     true,
+		DSN:          true,
+	}
+}
+
+// Validate checks that the combination of extensions is valid
+func (e Extensions) Validate() error {
+	if e.BinaryMIME && !e.Chunking {
+		return errors.New("BINARYMIME requires CHUNKING")
+	}
+	return nil
+}
+
+// SetExtensions replaces the offered extensions. Like SetLimits it must be
+// called before Start.
+func (s *Server) SetExtensions(ext Extensions) error {
+	if err := ext.Validate(); err != nil {
+		return err
+	}
+	s.applyExtensions(ext)
+	return nil
+}
+
+func (s *Server) applyExtensions(ext Extensions) {
+	s.extensions = ext
+	s.server.EnableSMTPUTF8 = ext.SMTPUTF8
+	s.server.EnableBINARYMIME = ext.BinaryMIME
+	s.server.EnableDSN = ext.DSN
+}
+
+var (
+	errUTF8Required = &smtp.SMTPError{
+		Code:         553,
+		EnhancedCode: smtp.EnhancedCode{5, 6, 7},
+		Message:      "Non-ASCII addresses require SMTPUTF8",
+	}
+	err8BitMIMEDisabled = &smtp.SMTPError{
+		Code:         555,
+		EnhancedCode: smtp.EnhancedCode{5, 5, 4},
+		Message:      "BODY=8BITMIME is not supported",
+	}
+	errChunkingDisabled = &smtp.SMTPError{
+		Code:         502,
+		EnhancedCode: smtp.EnhancedCode{5, 5, 1},
+		Message:      "BDAT is not supported",
+	}
+)
+
+// checkAddress rejects non-ASCII addresses unless the transaction was
+// started with SMTPUTF8. go-smtp accepts UTF-8 local parts either way.
+func (s *Session) checkAddress(addr string) error {
+	if !s.utf8 && !isASCII(addr) {
+		s.rec.error("non-ASCII address <%s> without SMTPUTF8", addr)
+		return errUTF8Required
+	}
+	return nil
+}
+
+// usedExtensions lists the extensions the current transaction relied on
+func (s *Session) usedExtensions(chunked bool) []string {
+	used := []string{}
+	if s.utf8 {
+		used = append(used, ExtensionSMTPUTF8)
+	}
+	switch s.body {
+	case smtp.Body8BitMIME:
+		used = append(used, Extension8BitMIME)
+	case smtp.BodyBinaryMIME:
+		used = append(used, ExtensionBinaryMIME)
+	}
+	if chunked {
+		used = append(used, ExtensionChunking)
+	}
+	if s.usedDSN() {
+		used = append(used, ExtensionDSN)
+	}
+	return used
+}
+
+// isChunked reports whether the message reader passed to Data is fed by
+// BDAT commands. go-smtp pipes chunks to the session, while DATA gets its
+// own dot-unstuffing reader.
+func isChunked(r io.Reader) bool {
+	_, ok := r.(*io.PipeReader)
+	return ok
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// hiddenCapabilities returns the EHLO reply lines go-smtp always sends but
+// the listener was configured not to offer
+func (e Extensions) hiddenCapabilities() [][]byte {
+	var hidden [][]byte
+	if !e.EightBitMIME {
+		hidden = append(hidden, []byte("250-"+Extension8BitMIME+"\r\n"))
+	}
+	if !e.Chunking {
+		hidden = append(hidden, []byte("250-"+ExtensionChunking+"\r\n"))
+	}
+	return hidden
+}
+
+// filterCapabilities removes hidden capability lines from server output.
+// go-smtp flushes every reply line separately and never ends the EHLO
+// reply with one of these, so dropping whole lines keeps it well formed.
+func filterCapabilities(p []byte, hidden [][]byte) []byte {
+	if len(hidden) == 0 {
+		return p
+	}
+
+	var out []byte
+	for len(p) > 0 {
+		line := p
+		if i := bytes.IndexByte(p, '\n'); i >= 0 {
+			line = p[:i+1]
+		}
+		p = p[len(line):]
+
+		drop := false
+		for _, h := range hidden {
+			drop = drop || bytes.Equal(line, h)
+		}
+		if !drop {
+			out = append(out, line...)
+		}
+	}
+	return out
+}
@@ -0,0 +1,29 @@
+package smtp
+
+import (
+	"github.com/watzon/postpilot/internal/extract"
+	"github.com/watzon/postpilot/internal/links"
+)
+
+// extractValues finds verification codes, sign-in links and configured
+// values in email. Links must already be extracted.
+func extractValues(config extract.Config, email *Email) *extract.Result {
+	var urls []string
+	for _, l := range email.Links {
+		if l.Kind == links.KindLink {
+			urls = append(urls, l.URL)
+		}
+	}
+
+	return extract.Extract(config, extract.Message{
+		Subject: email.Subject,
+		Text:    email.Body,
+		HTML:    email.HTML,
+		Links:   urls,
+	})
+}
+
+// SetExtractors replaces the configured code and link extractors
+func (s *Server) SetExtractors(config extract.Config) error {
+	return s.extract.set(config)
+}
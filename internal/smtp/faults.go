@@ -0,0 +1,274 @@
+package smtp
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emersion/go-smtp"
+)
+
+// FaultStage identifies the SMTP command at which a fault is injected
+type FaultStage string
+
+const (
+	FaultStageMail FaultStage = "mail"
+	FaultStageRcpt FaultStage = "rcpt"
+	FaultStageData FaultStage = "data"
+)
+
+// FaultAction identifies how the server misbehaves when a rule fires
+type FaultAction string
+
+const (
+	// FaultActionReply rejects the command with the rule's code and message
+	FaultActionReply FaultAction = "reply"
+	// FaultActionDelay waits for the rule's delay before handling the command normally
+	FaultActionDelay FaultAction = "delay"
+	// FaultActionDrop closes the connection while the message is being transferred
+	FaultActionDrop FaultAction = "drop"
+	// FaultActionTruncate sends only part of the reply and closes the connection
+	FaultActionTruncate FaultAction = "truncate"
+	// FaultActionBounce accepts the message and then generates a bounce for
+	// the matching recipients, reporting the rule's code and message
+	FaultActionBounce FaultAction = "bounce"
+)
+
+// FaultRule describes a condition under which the server deliberately fails.
+// Sender, Recipient and Subject are case-insensitive glob patterns where
+// '*' matches any run of characters and '?' a single character. Empty
+// patterns match everything. All conditions of a rule must match for it
+// to fire.
+type FaultRule struct {
+	// Unique identifier for the rule
+	ID string `json:"id"`
+	// Human readable name used in logs
+	Name string `json:"name"`
+	// Whether the rule is currently active
+	Enabled bool `json:"enabled"`
+	// Pattern matched against the envelope sender
+	Sender string `json:"sender"`
+	// Pattern matched against envelope recipients
+	Recipient string `json:"recipient"`
+	// Pattern matched against the decoded Subject header (data stage only)
+	Subject string `json:"subject"`
+	// Probability in percent that a matching rule fires; 0 means always
+	Percent float64 `json:"percent"`
+	// Command at which the fault is injected
+	Stage FaultStage `json:"stage"`
+	// What the server does when the rule fires
+	Action FaultAction `json:"action"`
+	// SMTP reply code for reply faults (4xx or 5xx), or the code reported
+	// in the bounce for bounce faults (defaults to 550)
+	Code int `json:"code"`
+	// Reply text for reply faults, or the bounce's diagnostic text
+	Message string `json:"message"`
+	// Delay in milliseconds for delay faults
+	DelayMs int `json:"delayMs"`
+}
+
+// faultMatcher is a FaultRule with its patterns compiled
+type faultMatcher struct {
+	rule      FaultRule
+	sender    *regexp.Regexp
+	recipient *regexp.Regexp
+	subject   *regexp.Regexp
+}
+
+// faultInjector holds the active fault rules of a server. Rules can be
+// replaced at any time while sessions are running.
+type faultInjector struct {
+	mu       sync.RWMutex
+	enabled  bool
+	matchers []*faultMatcher
+}
+
+// errConnectionDropped is returned by session handlers after a fault
+// closed the client connection
+var errConnectionDropped = errors.New("connection dropped by fault injection")
+
+// ValidateFaultRules checks that every rule is well formed
+func ValidateFaultRules(rules []FaultRule) error {
+	_, err := compileFaultRules(rules)
+	return err
+}
+
+func compileFaultRules(rules []FaultRule) ([]*faultMatcher, error) {
+	matchers := make([]*faultMatcher, 0, len(rules))
+	for _, rule := range rules {
+		m, err := compileFaultRule(rule)
+		if err != nil {
+			name := rule.Name
+			if name == "" {
+				name = rule.ID
+			}
+			return nil, fmt.Errorf("fault rule %q: %w", name, err)
+		}
+		matchers = append(matchers, m)
+	}
+	return matchers, nil
+}
+
+func compileFaultRule(rule FaultRule) (*faultMatcher, error) {
+	switch rule.Stage {
+	case FaultStageMail:
+		if rule.Recipient != "" {
+			return nil, errors.New("recipient can only be matched at the rcpt or data stage")
+		}
+		fallthrough
+	case FaultStageRcpt:
+		if rule.Subject != "" {
+			return nil, errors.New("subject can only be matched at the data stage")
+		}
+		if rule.Action == FaultActionDrop {
+			return nil, errors.New("connections can only be dropped at the data stage")
+		}
+		if rule.Stage == FaultStageMail && rule.Action == FaultActionBounce {
+			return nil, errors.New("bounces can only be triggered at the rcpt or data stage")
+		}
+	case FaultStageData:
+	default:
+		return nil, fmt.Errorf("unknown stage %q", rule.Stage)
+	}
+
+	switch rule.Action {
+	case FaultActionReply:
+		if rule.Code < 400 || rule.Code > 599 {
+			return nil, fmt.Errorf("reply code %d is not a 4xx or 5xx code", rule.Code)
+		}
+	case FaultActionDelay:
+		if rule.DelayMs <= 0 {
+			return nil, errors.New("delay must be positive")
+		}
+	case FaultActionBounce:
+		if rule.Code != 0 && (rule.Code < 400 || rule.Code > 599) {
+			return nil, fmt.Errorf("bounce code %d is not a 4xx or 5xx code", rule.Code)
+		}
+	case FaultActionDrop, FaultActionTruncate:
+	default:
+		return nil, fmt.Errorf("unknown action %q", rule.Action)
+	}
+
+	if rule.Percent < 0 || rule.Percent > 100 {
+		return nil, errors.New("percent must be between 0 and 100")
+	}
+
+	m := &faultMatcher{rule: rule}
+	m.sender = compileGlob(rule.Sender)
+	m.recipient = compileGlob(rule.Recipient)
+	m.subject = compileGlob(rule.Subject)
+	return m, nil
+}
+
+// compileGlob converts a case-insensitive glob pattern into a regular
+// expression matching the whole input. An empty pattern yields nil.
+func compileGlob(pattern string) *regexp.Regexp {
+	if pattern == "" {
+		return nil
+	}
+
+	var b strings.Builder
+	b.WriteString("(?i)^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+func (f *faultInjector) set(enabled bool, rules []FaultRule) error {
+	matchers, err := compileFaultRules(rules)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	f.enabled = enabled
+	f.matchers = matchers
+	f.mu.Unlock()
+	return nil
+}
+
+// match returns the first enabled rule for stage whose conditions hold.
+// Recipients match if any of them matches the recipient pattern.
+func (f *faultInjector) match(stage FaultStage, from string, to []string, subject string) *FaultRule {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if !f.enabled {
+		return nil
+	}
+
+	for _, m := range f.matchers {
+		if !m.rule.Enabled || m.rule.Stage != stage {
+			continue
+		}
+		if m.sender != nil && !m.sender.MatchString(from) {
+			continue
+		}
+		if m.recipient != nil && !matchAny(m.recipient, to) {
+			continue
+		}
+		if m.subject != nil && !m.subject.MatchString(subject) {
+			continue
+		}
+		if m.rule.Percent > 0 && rand.Float64()*100 >= m.rule.Percent {
+			continue
+		}
+
+		rule := m.rule
+		return &rule
+	}
+
+	return nil
+}
+
+func matchAny(re *regexp.Regexp, values []string) bool {
+	for _, v := range values {
+		if re.MatchString(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// replyError builds the SMTP error returned for a reply fault
+func (r *FaultRule) replyError() *smtp.SMTPError {
+	msg := r.Message
+	if msg == "" {
+		msg = fmt.Sprintf("Simulated failure (%s)", r.Name)
+	}
+	class := r.Code / 100
+	return &smtp.SMTPError{
+		Code:         r.Code,
+		EnhancedCode: smtp.EnhancedCode{class, 0, 0},
+		Message:      msg,
+	}
+}
+
+// truncatedReply returns the first few bytes of the reply the server would
+// have sent for stage, without the terminating CRLF
+func truncatedReply(stage FaultStage) string {
+	switch stage {
+	case FaultStageData:
+		return "250 2.0.0 OK: qu"
+	default:
+		return "250 2.0"
+	}
+}
+
+// delay returns the rule's delay as a duration
+func (r *FaultRule) delay() time.Duration {
+	return time.Duration(r.DelayMs) * time.Millisecond
+}
@@ -0,0 +1,168 @@
+package smtp
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/emersion/go-smtp"
+)
+
+// GreylistEntry tracks delivery attempts for a single
+// (client IP, sender, recipient) triplet
+type GreylistEntry struct {
+	// IP address of the connecting client
+	ClientIP string `json:"clientIp"`
+	// Envelope sender
+	Sender string `json:"sender"`
+	// Envelope recipient
+	Recipient string `json:"recipient"`
+	// Time of the first delivery attempt
+	FirstSeen time.Time `json:"firstSeen"`
+	// Time of the most recent delivery attempt
+	LastSeen time.Time `json:"lastSeen"`
+	// Number of attempts that were temporarily rejected
+	Rejected int `json:"rejected"`
+	// Whether a retry has been accepted after the delay
+	Passed bool `json:"passed"`
+}
+
+type greylistKey struct {
+	ip, sender, recipient string
+}
+
+// Greylisting forgets triplets that are no longer used, so clients that
+// keep sending with new triplets can't grow the table without bound
+const (
+	// How long after the delay a rejected triplet is kept for its retry
+	greylistRetryWindow = 4 * time.Hour
+	// How long a triplet that passed is kept after its last attempt
+	greylistPassLifetime = 24 * time.Hour
+	// Most triplets kept; beyond it the least recently seen are dropped
+	greylistMaxEntries = 10000
+	// Minimum time between scans for expired triplets
+	greylistPruneInterval = time.Minute
+)
+
+// greylist temporarily rejects the first delivery attempt of every new
+// triplet and accepts retries once the configured delay has passed
+type greylist struct {
+	mu      sync.Mutex
+	enabled bool
+	delay   time.Duration
+	entries map[greylistKey]*GreylistEntry
+	// Time of the last scan for expired triplets
+	pruned time.Time
+}
+
+// errGreylisted is returned for RCPT commands of triplets still in their delay
+var errGreylisted = &smtp.SMTPError{
+	Code:         451,
+	EnhancedCode: smtp.EnhancedCode{4, 7, 1},
+	Message:      "Greylisted, please try again later",
+}
+
+func (g *greylist) set(enabled bool, delay time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.enabled = enabled
+	g.delay = delay
+}
+
+// check records a delivery attempt and reports whether it must be rejected
+func (g *greylist) check(ip, sender, recipient string, now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.enabled {
+		return false
+	}
+	if g.entries == nil {
+		g.entries = make(map[greylistKey]*GreylistEntry)
+	}
+	if now.Sub(g.pruned) >= greylistPruneInterval {
+		g.prune(now)
+	}
+
+	key := greylistKey{ip, sender, recipient}
+	entry, ok := g.entries[key]
+	if !ok {
+		if len(g.entries) >= greylistMaxEntries {
+			g.evictOldest()
+		}
+		entry = &GreylistEntry{
+			ClientIP:  ip,
+			Sender:    sender,
+			Recipient: recipient,
+			FirstSeen: now,
+		}
+		g.entries[key] = entry
+	}
+	entry.LastSeen = now
+
+	// New triplets are always rejected once, known ones until the delay passed
+	if !ok || !entry.Passed && now.Sub(entry.FirstSeen) < g.delay {
+		entry.Rejected++
+		return true
+	}
+
+	entry.Passed = true
+	return false
+}
+
+// prune removes triplets that were not retried within the retry window,
+// and passed ones that haven't been used for the pass lifetime
+func (g *greylist) prune(now time.Time) {
+	g.pruned = now
+	for key, e := range g.entries {
+		lifetime := g.delay + greylistRetryWindow
+		if e.Passed {
+			lifetime = greylistPassLifetime
+		}
+		if now.Sub(e.LastSeen) > lifetime {
+			delete(g.entries, key)
+		}
+	}
+}
+
+// evictOldest removes the least recently seen triplet
+func (g *greylist) evictOldest() {
+	var oldest greylistKey
+	var oldestSeen time.Time
+	for key, e := range g.entries {
+		if oldestSeen.IsZero() || e.LastSeen.Before(oldestSeen) {
+			oldest, oldestSeen = key, e.LastSeen
+		}
+	}
+	delete(g.entries, oldest)
+}
+
+func (g *greylist) list() []GreylistEntry {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	entries := make([]GreylistEntry, 0, len(g.entries))
+	for _, e := range g.entries {
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].FirstSeen.Before(entries[j].FirstSeen)
+	})
+	return entries
+}
+
+func (g *greylist) reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.entries = nil
+}
+
+// clientIP returns the IP part of a connection's remote address
+func clientIP(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
@@ -0,0 +1,51 @@
+package smtp
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestGreylistExpiresTriplets(t *testing.T) {
+	g := &greylist{}
+	g.set(true, time.Minute)
+	start := time.Now()
+
+	g.check("192.0.2.1", "a@example.com", "never-retried@example.com", start)
+	g.check("192.0.2.1", "a@example.com", "passed@example.com", start)
+	if g.check("192.0.2.1", "a@example.com", "passed@example.com", start.Add(2*time.Minute)) {
+		t.Fatal("retry after the delay was rejected")
+	}
+
+	// Past the retry window, only the triplet that passed is kept
+	later := start.Add(time.Minute + greylistRetryWindow + time.Hour)
+	g.check("192.0.2.2", "b@example.com", "c@example.com", later)
+	if _, ok := g.entries[greylistKey{"192.0.2.1", "a@example.com", "never-retried@example.com"}]; ok {
+		t.Error("triplet that was never retried is still kept")
+	}
+	if _, ok := g.entries[greylistKey{"192.0.2.1", "a@example.com", "passed@example.com"}]; !ok {
+		t.Error("triplet that passed was dropped before its lifetime ended")
+	}
+
+	// Past the pass lifetime it goes too
+	g.check("192.0.2.2", "b@example.com", "c@example.com", later.Add(greylistPassLifetime))
+	if _, ok := g.entries[greylistKey{"192.0.2.1", "a@example.com", "passed@example.com"}]; ok {
+		t.Error("triplet that passed is kept past its lifetime")
+	}
+}
+
+func TestGreylistCapsTriplets(t *testing.T) {
+	g := &greylist{}
+	g.set(true, time.Minute)
+	now := time.Now()
+
+	for i := 0; i < greylistMaxEntries+10; i++ {
+		g.check("192.0.2.1", "a@example.com", fmt.Sprintf("r%d@example.com", i), now.Add(time.Duration(i)*time.Millisecond))
+	}
+	if len(g.entries) != greylistMaxEntries {
+		t.Errorf("%d triplets kept, want %d", len(g.entries), greylistMaxEntries)
+	}
+	if _, ok := g.entries[greylistKey{"192.0.2.1", "a@example.com", "r0@example.com"}]; ok {
+		t.Error("least recently seen triplet was not dropped")
+	}
+}
@@ -0,0 +1,81 @@
+package smtp
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// InlinePart is a body part the HTML can refer to with a cid: URL, like an
+// embedded logo
+type InlinePart struct {
+	// Content-ID without angle brackets
+	ContentID   string `json:"contentId"`
+	ContentType string `json:"contentType"`
+	Filename    string `json:"filename"`
+	// Decoded size in bytes
+	Size int `json:"size"`
+}
+
+// inlineParts lists the parts of a raw message that have a Content-ID,
+// other than the text and HTML bodies
+func inlineParts(raw []byte) []InlinePart {
+	parts := []InlinePart{}
+	walkParts(raw, func(p *mimePart) {
+		cid := p.contentID()
+		if cid == "" || isBodyPart(p) {
+			return
+		}
+		_, filename := p.disposition()
+		size := len(p.body)
+		if data, err := p.decode(); err == nil {
+			size = len(data)
+		}
+		parts = append(parts, InlinePart{
+			ContentID:   cid,
+			ContentType: p.mediaType,
+			Filename:    filename,
+			Size:        size,
+		})
+	})
+	return parts
+}
+
+// ReadInlinePart returns the media type and decoded content of the part of
+// a raw message with the given Content-ID. The ID may be given as in a
+// cid: URL, percent-encoded and without angle brackets.
+func ReadInlinePart(raw []byte, contentID string) (string, []byte, error) {
+	if id, err := url.PathUnescape(contentID); err == nil {
+		contentID = id
+	}
+	contentID = strings.Trim(contentID, "<>")
+
+	var found *mimePart
+	err := walkParts(raw, func(p *mimePart) {
+		if found == nil && strings.EqualFold(p.contentID(), contentID) {
+			found = p
+		}
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if found == nil {
+		return "", nil, fmt.Errorf("no part with Content-ID %s", contentID)
+	}
+
+	data, err := found.decode()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decode part %s: %w", contentID, err)
+	}
+	return found.mediaType, data, nil
+}
+
+// isBodyPart reports whether a part is a text or HTML body rather than
+// something shown inside one
+func isBodyPart(p *mimePart) bool {
+	if p.mediaType != "text/plain" && p.mediaType != "text/html" {
+		return false
+	}
+	disposition, filename := p.disposition()
+	return disposition != "attachment" && filename == ""
+}
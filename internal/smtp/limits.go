@@ -0,0 +1,94 @@
+package smtp
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Limits bounds what a single listener accepts. Zero disables a limit.
+type Limits struct {
+	// Maximum time a single read from the client may block while it sends
+	// message data or answers a SASL challenge. Waiting for the next
+	// command is bounded by IdleTimeoutSeconds instead.
+	ReadTimeoutSeconds int `json:"readTimeoutSeconds"`
+	// Maximum time a single write to the client may block
+	WriteTimeoutSeconds int `json:"writeTimeoutSeconds"`
+	// Maximum time to wait for the client's next command
+	IdleTimeoutSeconds int `json:"idleTimeoutSeconds"`
+	// Maximum message size in bytes, advertised with SIZE
+	MaxMessageBytes int64 `json:"maxMessageBytes"`
+	// Maximum number of recipients per message
+	MaxRecipients int `json:"maxRecipients"`
+	// Maximum number of simultaneous client connections
+	MaxConnections int `json:"maxConnections"`
+	// Maximum length of a command or message line in bytes
+	MaxLineLength int `json:"maxLineLength"`
+}
+
+// DefaultLimits returns the limits used when none are configured
+func DefaultLimits() Limits {
+	return Limits{
+		ReadTimeoutSeconds:  10,
+		WriteTimeoutSeconds: 10,
+		IdleTimeoutSeconds:  300,
+		MaxMessageBytes:     1024 * 1024 * 10, // 10MB
+		MaxRecipients:       50,
+		MaxConnections:      0,
+		MaxLineLength:       2000,
+	}
+}
+
+// Validate checks that no limit is negative
+func (l Limits) Validate() error {
+	values := []struct {
+		name  string
+		value int64
+	}{
+		{"read timeout", int64(l.ReadTimeoutSeconds)},
+		{"write timeout", int64(l.WriteTimeoutSeconds)},
+		{"idle timeout", int64(l.IdleTimeoutSeconds)},
+		{"max message size", l.MaxMessageBytes},
+		{"max recipients", int64(l.MaxRecipients)},
+		{"max connections", int64(l.MaxConnections)},
+		{"max line length", int64(l.MaxLineLength)},
+	}
+	for _, v := range values {
+		if v.value < 0 {
+			return fmt.Errorf("%s must not be negative", v.name)
+		}
+	}
+	return nil
+}
+
+func (l Limits) readTimeout() time.Duration {
+	return time.Duration(l.ReadTimeoutSeconds) * time.Second
+}
+
+func (l Limits) writeTimeout() time.Duration {
+	return time.Duration(l.WriteTimeoutSeconds) * time.Second
+}
+
+func (l Limits) idleTimeout() time.Duration {
+	return time.Duration(l.IdleTimeoutSeconds) * time.Second
+}
+
+// limitPrefix starts session errors that were caused by a limit
+const limitPrefix = "limit: "
+
+// tooManyConnectionsReply is sent to clients over the connection limit
+const tooManyConnectionsReply = "421 4.7.0 Too many connections, try again later\r\n"
+
+// limitReason explains a server reply caused by one of the limits, or
+// returns "" if the reply has nothing to do with them
+func (l Limits) limitReason(reply string) string {
+	switch {
+	case strings.HasPrefix(reply, "552 5.3.4"):
+		return fmt.Sprintf(limitPrefix+"message exceeds the maximum size of %d bytes", l.MaxMessageBytes)
+	case strings.HasPrefix(reply, "452 4.5.3"):
+		return fmt.Sprintf(limitPrefix+"more than %d recipients", l.MaxRecipients)
+	case strings.HasPrefix(reply, "500 5.4.0"):
+		return fmt.Sprintf(limitPrefix+"line longer than %d bytes", l.MaxLineLength)
+	}
+	return ""
+}
@@ -0,0 +1,130 @@
+package smtp
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startTestServer serves SMTP with the given limits on a local port and
+// returns its address
+func startTestServer(t *testing.T, limits Limits) string {
+	t.Helper()
+
+	s := NewServer("127.0.0.1", 0, "none", "", "", "none")
+	if err := s.SetLimits(limits); err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.server.Serve(&recordingListener{Listener: l, server: s})
+	t.Cleanup(func() { s.Stop() })
+	return l.Addr().String()
+}
+
+// testClient speaks SMTP line by line
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dialTestServer(t *testing.T, addr string) *testClient {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	c := &testClient{t: t, conn: conn, r: bufio.NewReader(conn)}
+	c.expect("220")
+	return c
+}
+
+// send writes a line and returns the final line of the reply
+func (c *testClient) send(line string) (string, error) {
+	if _, err := fmt.Fprintf(c.conn, "%s\r\n", line); err != nil {
+		return "", err
+	}
+	return c.reply()
+}
+
+func (c *testClient) reply() (string, error) {
+	c.conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		// Continuation lines of multiline replies have a - after the code
+		if len(line) < 4 || line[3] != '-' {
+			return strings.TrimRight(line, "\r\n"), nil
+		}
+	}
+}
+
+func (c *testClient) expect(code string) {
+	c.t.Helper()
+	line, err := c.reply()
+	if err != nil {
+		c.t.Fatalf("waiting for %s: %v", code, err)
+	}
+	if !strings.HasPrefix(line, code) {
+		c.t.Fatalf("got %q, want %s", line, code)
+	}
+}
+
+func TestIdleConnectionOutlivesReadTimeout(t *testing.T) {
+	limits := DefaultLimits()
+	limits.ReadTimeoutSeconds = 1
+	limits.IdleTimeoutSeconds = 10
+	c := dialTestServer(t, startTestServer(t, limits))
+
+	if line, err := c.send("EHLO client.example"); err != nil || !strings.HasPrefix(line, "250") {
+		t.Fatalf("EHLO: %q, %v", line, err)
+	}
+
+	// Sit idle between commands for longer than the read timeout
+	time.Sleep(1500 * time.Millisecond)
+
+	line, err := c.send("NOOP")
+	if err != nil {
+		t.Fatalf("connection closed while idle: %v", err)
+	}
+	if !strings.HasPrefix(line, "250") {
+		t.Fatalf("NOOP: got %q, want 250", line)
+	}
+}
+
+func TestStalledDataHitsReadTimeout(t *testing.T) {
+	limits := DefaultLimits()
+	limits.ReadTimeoutSeconds = 1
+	limits.IdleTimeoutSeconds = 10
+	c := dialTestServer(t, startTestServer(t, limits))
+
+	for _, cmd := range []string{"EHLO client.example", "MAIL FROM:<a@example.com>", "RCPT TO:<b@example.com>"} {
+		if line, err := c.send(cmd); err != nil || !strings.HasPrefix(line, "250") {
+			t.Fatalf("%s: %q, %v", cmd, line, err)
+		}
+	}
+	if line, err := c.send("DATA"); err != nil || !strings.HasPrefix(line, "354") {
+		t.Fatalf("DATA: %q, %v", line, err)
+	}
+	fmt.Fprintf(c.conn, "Subject: stalled\r\n\r\nHello")
+
+	// The server gives up on the message well before the idle timeout
+	start := time.Now()
+	line, err := c.reply()
+	if err == nil && strings.HasPrefix(line, "250") {
+		t.Fatalf("stalled message was accepted: %q", line)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("stalled DATA took %s to time out, want about the 1s read timeout", elapsed)
+	}
+}
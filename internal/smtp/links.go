@@ -0,0 +1,20 @@
+package smtp
+
+import (
+	"github.com/watzon/postpilot/internal/links"
+)
+
+// extractLinks lists and flags the links in the bodies and
+// List-Unsubscribe header of email
+func extractLinks(config links.Config, email *Email) []links.Link {
+	return links.Extract(config, links.Message{
+		ListUnsubscribe: email.Headers["List-Unsubscribe"],
+		Text:            email.Body,
+		HTML:            email.HTML,
+	})
+}
+
+// SetLinks replaces the configuration used to flag links
+func (s *Server) SetLinks(config links.Config) error {
+	return s.links.set(config)
+}
@@ -0,0 +1,27 @@
+package smtp
+
+import (
+	"strings"
+
+	"github.com/watzon/postpilot/internal/links"
+	"github.com/watzon/postpilot/internal/parity"
+)
+
+// compareParts checks the text part of an email against its HTML part,
+// using the http(s) links already extracted from each
+func compareParts(email *Email) *parity.Report {
+	var textLinks, htmlLinks []string
+	for _, l := range email.Links {
+		lower := strings.ToLower(l.URL)
+		if l.Kind != links.KindLink || !(strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")) {
+			continue
+		}
+		switch l.Location {
+		case links.LocationText:
+			textLinks = append(textLinks, l.URL)
+		case links.LocationHTML:
+			htmlLinks = append(htmlLinks, l.URL)
+		}
+	}
+	return parity.Compare(email.Body, email.HTML, textLinks, htmlLinks)
+}
@@ -1,11 +1,18 @@
 package smtp
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
 	"io"
 	"mime"
 	"mime/multipart"
+	"mime/quotedprintable"
 	"net/mail"
 	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
 )
 
 // parseEmail processes a raw email message and extracts its components into the Email struct.
@@ -48,72 +55,211 @@ func parseEmail(email *Email, r io.Reader) error {
 		email.Subject = subject
 	}
 
+	return parseBody(email, header, msg.Body)
+}
+
+// parseBody reads the text and HTML bodies of a message or MIME entity
+// into email. Signed and encrypted messages are recognized, and content
+// that can't be shown as it is, like encrypted data, is left out.
+func parseBody(email *Email, header mail.Header, body io.Reader) error {
 	// Parse the Content-Type header to determine the email structure
 	contentType := header.Get("Content-Type")
 	mediaType, params, err := mime.ParseMediaType(contentType)
 	if err != nil {
 		// If Content-Type parsing fails, treat the entire body as plain text
-		body, err := io.ReadAll(msg.Body)
+		text, err := decodeBody(email, body, header.Get("Content-Transfer-Encoding"), "")
 		if err != nil {
 			return err
 		}
-		email.Body = string(body)
+		email.Body = text
 		return nil
 	}
 
+	if sec := detectSecurity(mediaType, params); sec != nil {
+		if email.Security == nil {
+			email.Security = sec
+		}
+		if sec.hidesContent() {
+			return nil
+		}
+	}
+
 	// Handle multipart messages (e.g., emails with both text and HTML parts)
 	if strings.HasPrefix(mediaType, "multipart/") {
-		mr := multipart.NewReader(msg.Body, params["boundary"])
-		for {
-			// Read each part of the multipart message
-			p, err := mr.NextPart()
-			if err == io.EOF {
-				break
-			}
-			if err != nil {
-				return err
-			}
-
-			// Read the content of this part
-			slurp, err := io.ReadAll(p)
-			if err != nil {
-				return err
-			}
-
-			// Determine the content type of this part and store accordingly
-			partContentType := p.Header.Get("Content-Type")
-			if strings.HasPrefix(partContentType, "text/plain") {
-				email.Body = string(slurp)
-			} else if strings.HasPrefix(partContentType, "text/html") {
-				email.HTML = string(slurp)
-			}
-			// Note: Other content types (e.g., attachments) are currently ignored
+		if err := parseMultipart(email, body, mediaType, params, 0); err != nil {
+			return err
 		}
 	} else {
 		// Handle single-part messages
-		body, err := io.ReadAll(msg.Body)
+		text, err := decodeBody(email, body, header.Get("Content-Transfer-Encoding"), params["charset"])
 		if err != nil {
 			return err
 		}
 		// Store the content based on its media type
 		if strings.HasPrefix(mediaType, "text/html") {
-			email.HTML = string(body)
+			email.HTML = text
 		} else {
 			// Default to treating unknown content types as plain text
-			email.Body = string(body)
+			email.Body = text
 		}
 	}
 
 	return nil
 }
 
+// parseMultipart reads the parts of a multipart body into email. Nested
+// multiparts are read too, so the text and HTML bodies are found in
+// multipart/alternative and multipart/related parts at any depth.
+func parseMultipart(email *Email, r io.Reader, mediaType string, params map[string]string, depth int) error {
+	var reportParts []reportPart
+	mr := multipart.NewReader(r, params["boundary"])
+	for {
+		// Read each part of the multipart message
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		// Determine the content type of this part and store accordingly
+		partContentType := p.Header.Get("Content-Type")
+		partType, partParams, _ := mime.ParseMediaType(partContentType)
+		if mediaType == "multipart/report" && isReportPart(partType) {
+			body, err := decodeBody(email, p, p.Header.Get("Content-Transfer-Encoding"), "")
+			if err != nil {
+				return err
+			}
+			reportParts = append(reportParts, reportPart{mediaType: partType, body: []byte(body)})
+			continue
+		}
+		// Bodies nested in multipart/alternative or multipart/related
+		if strings.HasPrefix(partType, "multipart/") && depth < maxPartDepth {
+			if err := parseMultipart(email, p, partType, partParams, depth+1); err != nil {
+				return err
+			}
+			continue
+		}
+		// Other parts, like attachments and inline images, are read from
+		// the raw message when needed
+		if partType != "text/plain" && partType != "text/html" {
+			continue
+		}
+
+		// Read the content of this part
+		slurp, err := decodeBody(email, p, p.Header.Get("Content-Transfer-Encoding"), partParams["charset"])
+		if err != nil {
+			return err
+		}
+		if partType == "text/plain" {
+			email.Body = slurp
+		} else {
+			email.HTML = slurp
+		}
+	}
+
+	if reportParts != nil {
+		email.Report = parseReport(params["report-type"], reportParts)
+	}
+
+	return nil
+}
+
+// readHeaderBlock copies the header section of a message from r to w,
+// including the blank line that terminates it
+func readHeaderBlock(w *bytes.Buffer, r *bufio.Reader) error {
+	continued := false
+	for {
+		line, err := r.ReadSlice('\n')
+		w.Write(line)
+		switch err {
+		case nil:
+		case bufio.ErrBufferFull:
+			// Overlong line, keep reading until its end
+			continued = true
+			continue
+		case io.EOF:
+			return nil
+		default:
+			return err
+		}
+		if !continued && (string(line) == "\r\n" || string(line) == "\n") {
+			return nil
+		}
+		continued = false
+	}
+}
+
+// headerSubject returns the decoded Subject of a raw header block, or an
+// empty string if it cannot be parsed
+func headerSubject(header []byte) string {
+	msg, err := mail.ReadMessage(bytes.NewReader(header))
+	if err != nil {
+		return ""
+	}
+	subject, err := decodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		return msg.Header.Get("Subject")
+	}
+	return subject
+}
+
+// decodeBody reads a body, undoing its Content-Transfer-Encoding and
+// converting it from charset to UTF-8. 7bit, 8bit and binary bodies are
+// used as they are; bytes that still aren't valid UTF-8 are replaced so
+// that raw 8-bit content without a declared charset can't break the JSON
+// sent to the frontend. A body whose transfer encoding is corrupt is kept
+// as it was sent and a warning is added to email.
+func decodeBody(email *Email, r io.Reader, transferEncoding, charset string) (string, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	body := raw
+	encoding := strings.ToLower(strings.TrimSpace(transferEncoding))
+	switch encoding {
+	case "base64":
+		body, err = io.ReadAll(base64.NewDecoder(base64.StdEncoding, bytes.NewReader(raw)))
+	case "quoted-printable":
+		body, err = io.ReadAll(quotedprintable.NewReader(bytes.NewReader(raw)))
+	}
+	if err != nil {
+		email.ParseWarnings = append(email.ParseWarnings, fmt.Sprintf("%s body could not be decoded and is shown as sent: %v", encoding, err))
+		body = raw
+	}
+
+	switch strings.ToLower(charset) {
+	case "", "utf-8", "utf8", "us-ascii":
+	default:
+		if enc, err := htmlindex.Get(charset); err == nil {
+			if decoded, err := enc.NewDecoder().Bytes(body); err == nil {
+				body = decoded
+			}
+		}
+	}
+
+	return strings.ToValidUTF8(string(body), "\uFFFD"), nil
+}
+
 // decodeHeader decodes an encoded email header string (e.g., UTF-8, Base64)
 // using the MIME word encoding specification (RFC 2047)
 func decodeHeader(header string) (string, error) {
-	dec := new(mime.WordDecoder)
+	dec := &mime.WordDecoder{CharsetReader: charsetReader}
 	return dec.DecodeHeader(header)
 }
 
+// charsetReader converts encoded words in charsets the standard library
+// doesn't know about
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, err
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
 func addressListToStrings(addresses []*mail.Address) []string {
 	result := make([]string, len(addresses))
 	for i, addr := range addresses {
@@ -0,0 +1,43 @@
+package smtp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseEmailKeepsCorruptParts(t *testing.T) {
+	raw := strings.Join([]string{
+		"From: a@example.com",
+		"To: b@example.com",
+		"Subject: corrupt",
+		"MIME-Version: 1.0",
+		`Content-Type: multipart/alternative; boundary="b"`,
+		"",
+		"--b",
+		"Content-Type: text/plain",
+		"Content-Transfer-Encoding: base64",
+		"",
+		"not*base64",
+		"--b",
+		"Content-Type: text/html",
+		"Content-Transfer-Encoding: base64",
+		"",
+		"PHA+aGk8L3A+",
+		"--b--",
+		"",
+	}, "\r\n")
+
+	email := &Email{}
+	if err := parseEmail(email, strings.NewReader(raw)); err != nil {
+		t.Fatalf("parseEmail: %v", err)
+	}
+	if !strings.Contains(email.Body, "not*base64") {
+		t.Errorf("Body = %q, want the part as sent", email.Body)
+	}
+	if email.HTML != "<p>hi</p>" {
+		t.Errorf("HTML = %q, want the decoded part", email.HTML)
+	}
+	if len(email.ParseWarnings) != 1 {
+		t.Errorf("ParseWarnings = %q, want one warning", email.ParseWarnings)
+	}
+}
@@ -0,0 +1,100 @@
+package smtp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// maxPartDepth limits how deeply nested multiparts are walked
+const maxPartDepth = 16
+
+// mimePart is a leaf part of a message
+type mimePart struct {
+	header    textproto.MIMEHeader
+	mediaType string
+	params    map[string]string
+	// Body as it appears in the message, still transfer-encoded
+	body []byte
+}
+
+// walkParts calls fn for every leaf part of a raw message, in order.
+// Multiparts are entered at any depth; message/rfc822 parts are not.
+func walkParts(raw []byte, fn func(*mimePart)) error {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	body, err := io.ReadAll(msg.Body)
+	if err != nil {
+		return err
+	}
+	return walkPart(textproto.MIMEHeader(msg.Header), body, fn, 0)
+}
+
+func walkPart(header textproto.MIMEHeader, body []byte, fn func(*mimePart), depth int) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if !strings.HasPrefix(mediaType, "multipart/") || depth >= maxPartDepth {
+		fn(&mimePart{header: header, mediaType: mediaType, params: params, body: body})
+		return nil
+	}
+
+	mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		p, err := mr.NextRawPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		b, err := io.ReadAll(p)
+		if err != nil {
+			return err
+		}
+		if err := walkPart(p.Header, b, fn, depth+1); err != nil {
+			return err
+		}
+	}
+}
+
+// decode returns the body with its Content-Transfer-Encoding undone
+func (p *mimePart) decode() ([]byte, error) {
+	var r io.Reader = bytes.NewReader(p.body)
+	switch strings.ToLower(strings.TrimSpace(p.header.Get("Content-Transfer-Encoding"))) {
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		r = quotedprintable.NewReader(r)
+	}
+	return io.ReadAll(r)
+}
+
+// contentID returns the Content-ID without its angle brackets
+func (p *mimePart) contentID() string {
+	return strings.Trim(strings.TrimSpace(p.header.Get("Content-ID")), "<>")
+}
+
+// disposition returns the lower-case Content-Disposition type and the
+// file name from its parameters, or from the Content-Type name parameter
+func (p *mimePart) disposition() (string, string) {
+	disposition, params, _ := mime.ParseMediaType(p.header.Get("Content-Disposition"))
+	filename := params["filename"]
+	if filename == "" {
+		filename = p.params["name"]
+	}
+	if name, err := decodeHeader(filename); err == nil {
+		filename = name
+	}
+	return strings.ToLower(disposition), filename
+}
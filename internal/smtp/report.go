@@ -0,0 +1,302 @@
+package smtp
+
+import (
+	"bufio"
+	"bytes"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// Report types from the report-type parameter of multipart/report
+const (
+	ReportTypeDeliveryStatus          = "delivery-status"
+	ReportTypeDispositionNotification = "disposition-notification"
+	ReportTypeFeedbackReport          = "feedback-report"
+)
+
+// Report is the machine readable content of a multipart/report message:
+// a delivery status notification (RFC 3464), a message disposition
+// notification (RFC 8098) or an abuse feedback report (RFC 5965)
+type Report struct {
+	// Report type, one of the ReportType constants
+	Type string `json:"type"`
+	// Reporting MTA (DSN) or user agent (MDN)
+	ReportedBy string `json:"reportedBy"`
+	// ENVID of the original message, if the DSN carries it
+	OriginalEnvelopeID string `json:"originalEnvelopeId"`
+	// Per-recipient delivery status (DSN)
+	Recipients []ReportRecipient `json:"recipients"`
+	// Disposition of the original message (MDN)
+	Disposition *ReportDisposition `json:"disposition"`
+	// Abuse feedback (ARF)
+	Feedback *ReportFeedback `json:"feedback"`
+	// Message-ID of the original message, from the report or the returned
+	// message or headers
+	OriginalMessageID string `json:"originalMessageId"`
+	// Subject of the original message, if it was returned
+	OriginalSubject string `json:"originalSubject"`
+}
+
+// ReportRecipient is the delivery status of one recipient in a DSN
+type ReportRecipient struct {
+	// Recipient as given by the sender (Original-Recipient)
+	OriginalRecipient string `json:"originalRecipient"`
+	// Recipient the MTA tried to deliver to (Final-Recipient)
+	FinalRecipient string `json:"finalRecipient"`
+	// failed, delayed, delivered, relayed or expanded
+	Action string `json:"action"`
+	// Enhanced status code, e.g. 5.1.1
+	Status string `json:"status"`
+	// Diagnostic-Code, usually the remote server's reply
+	DiagnosticCode string `json:"diagnosticCode"`
+	// Remote-MTA the status came from
+	RemoteMTA string `json:"remoteMta"`
+	// "hard" for permanent failures, "soft" for delays and temporary
+	// failures, empty otherwise
+	Bounce string `json:"bounce"`
+}
+
+// ReportDisposition is the content of a message disposition notification
+type ReportDisposition struct {
+	// Recipient whose user agent sent the notification (Final-Recipient)
+	FinalRecipient string `json:"finalRecipient"`
+	// Original-Recipient, if given
+	OriginalRecipient string `json:"originalRecipient"`
+	// Full Disposition field, e.g. "manual-action/MDN-sent-manually; displayed"
+	Disposition string `json:"disposition"`
+	// Disposition type, e.g. displayed or deleted
+	Type string `json:"type"`
+}
+
+// ReportFeedback is the content of an abuse feedback report
+type ReportFeedback struct {
+	// abuse, fraud, virus, not-spam, auth-failure or other
+	FeedbackType string `json:"feedbackType"`
+	// Software that generated the report
+	UserAgent string `json:"userAgent"`
+	// Envelope sender of the reported message
+	OriginalMailFrom string `json:"originalMailFrom"`
+	// Envelope recipients of the reported message
+	OriginalRcptTo []string `json:"originalRcptTo"`
+	// When the reported message arrived
+	ArrivalDate string `json:"arrivalDate"`
+	// IP address the reported message came from
+	SourceIP string `json:"sourceIp"`
+	// Domains the report is about
+	ReportedDomains []string `json:"reportedDomains"`
+	// URIs the report is about
+	ReportedURIs []string `json:"reportedUris"`
+	// Authentication-Results of the reported message
+	AuthenticationResults string `json:"authenticationResults"`
+}
+
+// isReportPart reports whether a part of a multipart/report carries
+// report data or the original message
+func isReportPart(mediaType string) bool {
+	switch mediaType {
+	case "message/delivery-status", "message/global-delivery-status",
+		"message/disposition-notification", "message/global-disposition-notification",
+		"message/feedback-report",
+		"message/rfc822", "message/global", "text/rfc822-headers", "message/global-headers":
+		return true
+	}
+	return false
+}
+
+// reportPart is a part of a multipart/report kept for parseReport
+type reportPart struct {
+	mediaType string
+	body      []byte
+}
+
+// parseReport builds a Report from the parts of a multipart/report. It
+// returns nil if none of the parts carries a known report.
+func parseReport(reportType string, parts []reportPart) *Report {
+	report := &Report{Type: strings.ToLower(reportType)}
+	found := false
+
+	for _, p := range parts {
+		switch p.mediaType {
+		case "message/delivery-status", "message/global-delivery-status":
+			found = true
+			report.Type = ReportTypeDeliveryStatus
+			parseDeliveryStatus(report, p.body)
+		case "message/disposition-notification", "message/global-disposition-notification":
+			found = true
+			report.Type = ReportTypeDispositionNotification
+			parseDisposition(report, p.body)
+		case "message/feedback-report":
+			found = true
+			report.Type = ReportTypeFeedbackReport
+			parseFeedback(report, p.body)
+		default:
+			// The original message or its header section
+			if msg, err := mail.ReadMessage(bytes.NewReader(withHeaderEnd(p.body))); err == nil {
+				if report.OriginalMessageID == "" {
+					report.OriginalMessageID = msg.Header.Get("Message-Id")
+				}
+				subject := msg.Header.Get("Subject")
+				if decoded, err := decodeHeader(subject); err == nil {
+					subject = decoded
+				}
+				report.OriginalSubject = subject
+			}
+		}
+	}
+
+	if !found {
+		return nil
+	}
+	if report.Recipients == nil {
+		report.Recipients = []ReportRecipient{}
+	}
+	return report
+}
+
+func parseDeliveryStatus(report *Report, body []byte) {
+	groups := readFieldGroups(body)
+	if len(groups) == 0 {
+		return
+	}
+
+	perMessage := groups[0]
+	report.ReportedBy = fieldValue(perMessage.Get("Reporting-Mta"))
+	report.OriginalEnvelopeID = perMessage.Get("Original-Envelope-Id")
+
+	for _, g := range groups[1:] {
+		r := ReportRecipient{
+			OriginalRecipient: fieldValue(g.Get("Original-Recipient")),
+			FinalRecipient:    fieldValue(g.Get("Final-Recipient")),
+			Action:            strings.ToLower(g.Get("Action")),
+			Status:            g.Get("Status"),
+			DiagnosticCode:    fieldValue(g.Get("Diagnostic-Code")),
+			RemoteMTA:         fieldValue(g.Get("Remote-Mta")),
+		}
+		if r.FinalRecipient == "" && r.Action == "" {
+			continue
+		}
+		r.Bounce = bounceClass(r.Action, r.Status)
+		report.Recipients = append(report.Recipients, r)
+	}
+}
+
+func parseDisposition(report *Report, body []byte) {
+	fields := mergeFieldGroups(readFieldGroups(body))
+
+	report.ReportedBy = fields.Get("Reporting-Ua")
+	if id := fields.Get("Original-Message-Id"); id != "" {
+		report.OriginalMessageID = id
+	}
+
+	d := &ReportDisposition{
+		FinalRecipient:    fieldValue(fields.Get("Final-Recipient")),
+		OriginalRecipient: fieldValue(fields.Get("Original-Recipient")),
+		Disposition:       fields.Get("Disposition"),
+	}
+	if _, t, ok := strings.Cut(d.Disposition, ";"); ok {
+		t, _, _ = strings.Cut(t, "/")
+		d.Type = strings.ToLower(strings.TrimSpace(t))
+	}
+	report.Disposition = d
+}
+
+func parseFeedback(report *Report, body []byte) {
+	fields := mergeFieldGroups(readFieldGroups(body))
+
+	f := &ReportFeedback{
+		FeedbackType:          strings.ToLower(fields.Get("Feedback-Type")),
+		UserAgent:             fields.Get("User-Agent"),
+		OriginalMailFrom:      fields.Get("Original-Mail-From"),
+		OriginalRcptTo:        fields.Values("Original-Rcpt-To"),
+		ArrivalDate:           fields.Get("Arrival-Date"),
+		SourceIP:              fields.Get("Source-Ip"),
+		ReportedDomains:       fields.Values("Reported-Domain"),
+		ReportedURIs:          fields.Values("Reported-Uri"),
+		AuthenticationResults: fields.Get("Authentication-Results"),
+	}
+	if f.ArrivalDate == "" {
+		f.ArrivalDate = fields.Get("Received-Date")
+	}
+	if f.OriginalRcptTo == nil {
+		f.OriginalRcptTo = []string{}
+	}
+	if f.ReportedDomains == nil {
+		f.ReportedDomains = []string{}
+	}
+	if f.ReportedURIs == nil {
+		f.ReportedURIs = []string{}
+	}
+	report.ReportedBy = f.UserAgent
+	report.Feedback = f
+}
+
+// readFieldGroups parses blank-line separated groups of header-style
+// fields, as used by message/delivery-status
+func readFieldGroups(body []byte) []textproto.MIMEHeader {
+	var groups []textproto.MIMEHeader
+	r := textproto.NewReader(bufio.NewReader(bytes.NewReader(body)))
+	for {
+		// Skip blank lines between groups
+		for {
+			b, err := r.R.Peek(1)
+			if err != nil {
+				return groups
+			}
+			if b[0] != '\r' && b[0] != '\n' {
+				break
+			}
+			r.R.ReadByte()
+		}
+
+		h, err := r.ReadMIMEHeader()
+		if len(h) > 0 {
+			groups = append(groups, h)
+		}
+		if err != nil {
+			return groups
+		}
+	}
+}
+
+func mergeFieldGroups(groups []textproto.MIMEHeader) textproto.MIMEHeader {
+	merged := textproto.MIMEHeader{}
+	for _, g := range groups {
+		for k, v := range g {
+			merged[k] = append(merged[k], v...)
+		}
+	}
+	return merged
+}
+
+// fieldValue strips the type prefix from typed fields such as
+// "rfc822; user@example.com" or "dns; mx.example.com"
+func fieldValue(v string) string {
+	if t, rest, ok := strings.Cut(v, ";"); ok && !strings.ContainsAny(t, " <@") {
+		return strings.TrimSpace(rest)
+	}
+	return strings.TrimSpace(v)
+}
+
+// bounceClass classifies a DSN recipient as a hard or soft bounce
+func bounceClass(action, status string) string {
+	switch action {
+	case "failed":
+		if strings.HasPrefix(status, "4.") {
+			return "soft"
+		}
+		return "hard"
+	case "delayed":
+		return "soft"
+	}
+	return ""
+}
+
+// withHeaderEnd makes sure a header-only part ends with a blank line so
+// that it parses as a message
+func withHeaderEnd(b []byte) []byte {
+	if bytes.Contains(b, []byte("\r\n\r\n")) || bytes.Contains(b, []byte("\n\n")) {
+		return b
+	}
+	return append(append([]byte(nil), b...), "\r\n\r\n"...)
+}
@@ -1,16 +1,26 @@
 package smtp
 
 import (
+	"bufio"
 	"bytes"
 	"crypto/tls"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/emersion/go-smtp"
 	"github.com/google/uuid"
+	"github.com/watzon/postpilot/internal/a11y"
+	"github.com/watzon/postpilot/internal/calendar"
+	"github.com/watzon/postpilot/internal/extract"
+	"github.com/watzon/postpilot/internal/links"
+	"github.com/watzon/postpilot/internal/parity"
+	"github.com/watzon/postpilot/internal/spam"
+	"github.com/watzon/postpilot/internal/tracking"
 )
 
 // Package smtp implements a simple SMTP server for testing and development purposes
@@ -41,6 +51,38 @@ type Server struct {
 	mu sync.RWMutex
 	// In-memory storage of received emails
 	emails []*Email
+	// Number of emails whose notification was dropped because emailChan was full
+	dropped uint64
+	// Fault injection rules applied to every session
+	faults faultInjector
+	// Greylisting state for (client IP, sender, recipient) triplets
+	greylist greylist
+	// Transcripts of recent client connections
+	transcripts transcriptStore
+	// Channel for broadcasting finished sessions to the activity log
+	sessionChan chan SessionLogEntry
+	// Limits applied to client connections
+	limits Limits
+	// Optional ESMTP extensions offered to clients
+	extensions Extensions
+	// Bounce generation settings
+	bounces setting[bounceRules]
+	// DKIM key registry and resolver
+	dkim setting[DKIMConfig]
+	// DNS data for SPF and DMARC evaluation
+	zone setting[ZoneConfig]
+	// Spam scoring rules
+	spam setting[spam.Config]
+	// Size budgets
+	size setting[SizeConfig]
+	// Link flagging configuration
+	links setting[links.Config]
+	// Verification code and sign-in link extractors
+	extract setting[extract.Config]
+	// S/MIME trust store and test key
+	smime setting[SMIMEConfig]
+	// Number of currently open client connections
+	active int64
 }
 
 // Email represents a received email message with all its components
@@ -49,6 +91,14 @@ type Email struct {
 	ID string `json:"id"`
 	// Sender's email address
 	From string `json:"from"`
+	// Envelope sender given with MAIL FROM, empty for the null sender
+	MailFrom string `json:"mailFrom"`
+	// Envelope recipients given with RCPT TO
+	Recipients []string `json:"recipients"`
+	// Name the client gave with HELO or EHLO
+	Helo string `json:"helo"`
+	// IP address of the client that delivered the email
+	ClientIP string `json:"clientIp"`
 	// List of recipient email addresses
 	To []string `json:"to"`
 	// Carbon copy recipients
@@ -69,67 +119,321 @@ type Email struct {
 	Raw string `json:"raw"`
 	// Additional headers
 	Headers map[string][]string `json:"headers"`
+	// Problems found while parsing that didn't stop the email from being
+	// stored, e.g. a part whose transfer encoding is corrupt
+	ParseWarnings []string `json:"parseWarnings"`
+	// ID of the session (and transcript) the email was delivered in
+	SessionID string `json:"sessionId"`
+	// ESMTP extensions used to deliver the email, e.g. SMTPUTF8 or CHUNKING
+	Extensions []string `json:"extensions"`
+	// DSN parameters given with MAIL and RCPT
+	DSN DSNParams `json:"dsn"`
+	// ID of the email this is a bounce for, if PostPilot generated it
+	BounceOf string `json:"bounceOf"`
+	// Parsed DSN, MDN or ARF content if the email is a multipart/report
+	Report *Report `json:"report"`
+	// Parts with a Content-ID, like embedded images, that the HTML can
+	// refer to with cid: URLs
+	Inline []InlinePart `json:"inline"`
+	// Calendar invites found in text/calendar parts and .ics attachments
+	Calendars []calendar.Calendar `json:"calendars"`
+	// Attached files, including attached messages
+	Attachments []Attachment `json:"attachments"`
+	// Attached messages (message/rfc822 parts), such as forwarded emails
+	// and the originals in bounces, parsed into emails of their own
+	Attached []*Email `json:"attached"`
+	// ID of the email this one is attached to, empty for delivered emails
+	ParentID string `json:"parentId"`
+	// How the message is signed or encrypted, nil if it isn't
+	Security *Security `json:"security"`
+	// Verification results of the DKIM-Signature headers, in header order
+	DKIM []DKIMResult `json:"dkim"`
+	// SPF and DMARC evaluation against the configured zone
+	Auth *AuthResults `json:"auth"`
+	// Local spam score with the rules that hit
+	Spam *spam.Report `json:"spam"`
+	// Sizes of the HTML part, message and embedded images against the
+	// configured budgets
+	Size *SizeReport `json:"size"`
+	// Links, image sources and List-Unsubscribe URLs with their flags
+	Links []links.Link `json:"links"`
+	// Verification codes, sign-in links and configured values found in the
+	// email
+	Extracted *extract.Result `json:"extracted"`
+	// Accessibility findings for the HTML part, nil without one
+	Accessibility *a11y.Report `json:"accessibility"`
+	// Comparison of the text part with the HTML part, nil without HTML
+	Parity *parity.Report `json:"parity"`
+	// Likely tracking pixels and click-tracking links, nil without HTML
+	Tracking *tracking.Report `json:"tracking"`
 }
 
 // Session represents an active SMTP session with a client
 type Session struct {
+	// Unique identifier for the session, shared with its transcript
+	id     string
 	server *Server
+	conn   *smtp.Conn
+	// Connection under conn, nil if it didn't come through a
+	// recordingListener
+	wire   *recordingConn
+	rec    *transcriptRecorder
 	from   string
 	to     []string
 	buffer bytes.Buffer
+	// MAIL parameters of the current transaction
+	utf8 bool
+	body smtp.BodyType
+	// DSN parameters of the current transaction
+	dsn DSNParams
+	// Accepted recipients that will bounce, with the reason
+	bounces map[string]*bounceReason
 }
 
 // Mail handles the MAIL FROM command in the SMTP protocol
 func (s *Session) Mail(from string, opts *smtp.MailOptions) error {
+	s.noteEncrypted("MAIL FROM:<%s>", from)
+
+	if opts.Body == smtp.Body8BitMIME && !s.server.extensions.EightBitMIME {
+		return err8BitMIMEDisabled
+	}
+	s.utf8 = opts.UTF8
+	if err := s.checkAddress(from); err != nil {
+		return err
+	}
+
+	if err := s.applyFault(s.server.faults.match(FaultStageMail, from, nil, "")); err != nil {
+		return err
+	}
+
 	s.from = from
+	s.body = opts.Body
+	s.dsn.Return = string(opts.Return)
+	s.dsn.EnvelopeID = opts.EnvelopeID
 	return nil
 }
 
 // Rcpt handles the RCPT TO command in the SMTP protocol
 func (s *Session) Rcpt(to string, opts *smtp.RcptOptions) error {
+	s.noteEncrypted("RCPT TO:<%s>", to)
+
+	if err := s.checkAddress(to); err != nil {
+		return err
+	}
+
+	fault := s.server.faults.match(FaultStageRcpt, s.from, []string{to}, "")
+	if err := s.applyFault(fault); err != nil {
+		return err
+	}
+
+	ip := clientIP(s.conn.Conn().RemoteAddr())
+	if s.server.greylist.check(ip, s.from, to, time.Now()) {
+		log.Printf("Session %s (%s): greylisting <%s> -> <%s>", s.id, ip, s.from, to)
+		s.rec.event("Greylisted <%s> -> <%s>", s.from, to)
+		return errGreylisted
+	}
+
 	s.to = append(s.to, to)
+	s.recordDSN(to, opts)
+
+	if fault != nil && fault.Action == FaultActionBounce {
+		s.markBounce(to, faultBounceReason(fault))
+	} else if reason := s.server.bounces.get().match(to); reason != nil {
+		s.rec.event("<%s> matches a bounce pattern", to)
+		s.markBounce(to, reason)
+	}
 	return nil
 }
 
 // Data handles the DATA command in the SMTP protocol
 // It receives the email content and processes it
 func (s *Session) Data(r io.Reader) error {
-	if _, err := io.Copy(&s.buffer, r); err != nil {
+	s.wire.setDataRunning(true)
+	defer s.wire.setDataRunning(false)
+
+	chunked := isChunked(r)
+	if chunked {
+		s.noteEncrypted("BDAT")
+		if !s.server.extensions.Chunking {
+			return errChunkingDisabled
+		}
+	} else {
+		s.noteEncrypted("DATA")
+	}
+
+	br := bufio.NewReader(r)
+
+	// Read the header block first so data-stage faults can match on the
+	// subject and drop the connection before the body has been transferred
+	if err := readHeaderBlock(&s.buffer, br); err != nil {
+		return err
+	}
+
+	fault := s.server.faults.match(FaultStageData, s.from, s.to, headerSubject(s.buffer.Bytes()))
+	if fault != nil && fault.Action == FaultActionDrop {
+		return s.applyFault(fault)
+	}
+
+	if _, err := io.Copy(&s.buffer, br); err != nil {
 		return err
 	}
 
+	// A truncated reply is sent after the message has been accepted, so the
+	// client is left unsure whether delivery succeeded
+	if fault != nil && fault.Action != FaultActionTruncate {
+		if err := s.applyFault(fault); err != nil {
+			return err
+		}
+	}
+
 	email := &Email{
-		ID:        uuid.New().String(),
-		From:      s.from,
-		To:        s.to,
-		Timestamp: time.Now(),
-		Raw:       s.buffer.String(),
+		ID:         uuid.New().String(),
+		From:       s.from,
+		MailFrom:   s.from,
+		Recipients: s.to,
+		Helo:       s.conn.Hostname(),
+		ClientIP:   clientIP(s.conn.Conn().RemoteAddr()),
+		To:         s.to,
+		Timestamp:  time.Now(),
+		Raw:        s.buffer.String(),
+		SessionID:  s.id,
+		Extensions: s.usedExtensions(chunked),
+		DSN:        s.dsn,
 	}
 
 	// Parse email content
 	if err := parseEmail(email, &s.buffer); err != nil {
+		s.rec.error("failed to parse message: %v", err)
 		return err
 	}
+	inspectSMIME(s.server.smime.get(), email)
+	if sec := email.Security; sec != nil {
+		if sec.Signature != nil {
+			s.rec.event("Signature %s (%s)", sec.Signature.Status, sec.Protocol)
+		}
+		if sec.Decryption != "" {
+			s.rec.event("Decryption %s (%s)", sec.Decryption, sec.Protocol)
+		}
+	}
+	for _, w := range email.ParseWarnings {
+		s.rec.event("Parse warning: %s", w)
+	}
+	parseContent(email, 0)
 
-	s.server.mu.Lock()
-	s.server.emails = append(s.server.emails, email)
-	s.server.mu.Unlock()
+	email.DKIM = verifyDKIM(s.server.dkim.get(), []byte(email.Raw))
+	for _, r := range email.DKIM {
+		if r.Reason != "" {
+			s.rec.event("DKIM %s for d=%s s=%s: %s", r.Status, r.Domain, r.Selector, r.Reason)
+		} else {
+			s.rec.event("DKIM %s for d=%s s=%s", r.Status, r.Domain, r.Selector)
+		}
+	}
 
-	// Send to channel for real-time updates
-	select {
-	case s.server.emailChan <- email:
-	default:
-		log.Println("Email channel full, skipping notification")
+	email.Auth = EvaluateAuth(s.server.zone.get(), email.MailFrom, email.Helo, email.From, email.ClientIP, email.DKIM)
+	s.rec.event("SPF %s for %s, DMARC %s (disposition %s)",
+		email.Auth.SPF.Result, email.Auth.SPF.Domain, email.Auth.DMARC.Result, email.Auth.DMARC.Disposition)
+
+	email.Spam = scoreSpam(s.server.spam.get(), email)
+
+	email.Size = MeasureSize(s.server.size.get(), []byte(email.Raw), email.HTML)
+	for _, w := range email.Size.Warnings {
+		s.rec.event("Size budget exceeded: %s", w.Message)
+	}
+
+	email.Links = extractLinks(s.server.links.get(), email)
+	email.Extracted = extractValues(s.server.extract.get(), email)
+	if email.HTML != "" {
+		email.Accessibility = a11y.Check(email.HTML)
+		email.Tracking = tracking.Detect(email.HTML)
+	}
+	email.Parity = compareParts(email)
+
+	s.rec.update(func(t *Transcript) {
+		t.EmailIDs = append(t.EmailIDs, email.ID)
+	})
+
+	s.server.store(email)
+
+	if fault != nil && fault.Action == FaultActionBounce {
+		recipient := compileGlob(fault.Recipient)
+		for _, to := range s.to {
+			if recipient == nil || recipient.MatchString(to) {
+				s.markBounce(to, faultBounceReason(fault))
+			}
+		}
+	}
+	s.sendBounces(email)
+
+	if fault != nil && fault.Action == FaultActionTruncate {
+		return s.applyFault(fault)
+	}
+
+	return nil
+}
+
+// applyFault carries out the fault rule, if any, and returns the error the
+// current command should fail with
+func (s *Session) applyFault(rule *FaultRule) error {
+	if rule == nil {
+		return nil
+	}
+
+	log.Printf("Session %s (%s): injecting %s fault %q at %s",
+		s.id, s.conn.Conn().RemoteAddr(), rule.Action, rule.Name, rule.Stage)
+	s.rec.event("Injected %s fault %q at %s", rule.Action, rule.Name, rule.Stage)
+
+	switch rule.Action {
+	case FaultActionReply:
+		return rule.replyError()
+	case FaultActionDelay:
+		time.Sleep(rule.delay())
+		return nil
+	case FaultActionDrop:
+		s.conn.Conn().Close()
+		return errConnectionDropped
+	case FaultActionTruncate:
+		conn := s.conn.Conn()
+		io.WriteString(conn, truncatedReply(rule.Stage))
+		conn.Close()
+		return errConnectionDropped
 	}
 
 	return nil
 }
 
+// noteEncrypted records a command in the transcript when the connection is
+// encrypted and the wire conversation can't be captured
+func (s *Session) noteEncrypted(format string, args ...interface{}) {
+	if _, isTLS := s.conn.TLSConnectionState(); isTLS {
+		s.rec.add(DirectionClient, fmt.Sprintf(format, args...)+" (over TLS)")
+	}
+}
+
 // Reset clears the current session state
 func (s *Session) Reset() {
 	s.from = ""
 	s.to = nil
 	s.buffer.Reset()
+	s.utf8 = false
+	s.body = ""
+	s.dsn = DSNParams{}
+	s.bounces = nil
+}
+
+// store adds email to the inbox and notifies listeners
+func (s *Server) store(email *Email) {
+	s.mu.Lock()
+	s.emails = append(s.emails, email)
+	s.mu.Unlock()
+
+	// Send to channel for real-time updates. Data must not wait for slow
+	// consumers, so the notification is dropped and counted instead.
+	select {
+	case s.emailChan <- email:
+	default:
+		dropped := atomic.AddUint64(&s.dropped, 1)
+		log.Printf("Email channel full, skipping notification for %s (%d dropped so far)", email.ID, dropped)
+	}
 }
 
 // Logout handles client disconnection
@@ -143,9 +447,14 @@ type Backend struct {
 }
 
 // NewSession creates a new SMTP session for each client connection
-func (b *Backend) NewSession(_ *smtp.Conn) (smtp.Session, error) {
+func (b *Backend) NewSession(c *smtp.Conn) (smtp.Session, error) {
+	rec := recorderFor(c)
 	return &Session{
+		id:     rec.t.ID,
 		server: b.server,
+		conn:   c,
+		wire:   recordingConnFor(c),
+		rec:    rec,
 	}, nil
 }
 
@@ -159,33 +468,60 @@ func (b *Backend) NewSession(_ *smtp.Conn) (smtp.Session, error) {
 //   - tlsMode: TLS configuration mode
 func NewServer(host string, port int, auth, username, password, tlsMode string) *Server {
 	s := &Server{
-		host:      host,
-		port:      port,
-		auth:      auth,
-		username:  username,
-		password:  password,
-		tlsMode:   tlsMode,
-		emailChan: make(chan *Email, 100),
-		emails:    make([]*Email, 0),
+		host:        host,
+		port:        port,
+		auth:        auth,
+		username:    username,
+		password:    password,
+		tlsMode:     tlsMode,
+		emailChan:   make(chan *Email, 100),
+		sessionChan: make(chan SessionLogEntry, 100),
+		emails:      make([]*Email, 0),
 	}
 
 	be := &Backend{server: s}
 	s.server = smtp.NewServer(be)
 	s.server.Addr = fmt.Sprintf("%s:%d", host, port)
 	s.server.Domain = host
-	s.server.ReadTimeout = 10 * time.Second
-	s.server.WriteTimeout = 10 * time.Second
-	s.server.MaxMessageBytes = 1024 * 1024 * 10 // 10MB
-	s.server.MaxRecipients = 50
 	s.server.AllowInsecureAuth = true
+	s.applyLimits(DefaultLimits())
+	s.applyExtensions(DefaultExtensions())
 
 	return s
 }
 
+// SetLimits replaces the connection limits. It must be called before Start;
+// restart the server to change the limits of a running listener.
+func (s *Server) SetLimits(limits Limits) error {
+	if err := limits.Validate(); err != nil {
+		return err
+	}
+	s.applyLimits(limits)
+	return nil
+}
+
+func (s *Server) applyLimits(limits Limits) {
+	s.limits = limits
+
+	// go-smtp's read timeout only bounds the wait for the next command, so
+	// it serves as the idle timeout. The per-read timeout is enforced by
+	// recordingConn.
+	s.server.ReadTimeout = limits.idleTimeout()
+	s.server.WriteTimeout = limits.writeTimeout()
+	s.server.MaxMessageBytes = limits.MaxMessageBytes
+	s.server.MaxRecipients = limits.MaxRecipients
+	s.server.MaxLineLength = limits.MaxLineLength
+}
+
 // Start begins listening for SMTP connections in a separate goroutine
 func (s *Server) Start() error {
+	l, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+
 	go func() {
-		if err := s.server.ListenAndServe(); err != nil {
+		if err := s.server.Serve(&recordingListener{Listener: l, server: s}); err != nil {
 			log.Printf("SMTP server error: %v", err)
 		}
 	}()
@@ -198,6 +534,62 @@ func (s *Server) Stop() error {
 	return s.server.Close()
 }
 
+// SetFaultRules replaces the fault injection rules. It can be called while
+// the server is running; new rules apply to the next command of every session.
+func (s *Server) SetFaultRules(enabled bool, rules []FaultRule) error {
+	return s.faults.set(enabled, rules)
+}
+
+// SetBounces replaces the bounce settings. It can be called while the
+// server is running.
+func (s *Server) SetBounces(config BounceConfig) error {
+	return s.bounces.set(newBounceRules(config))
+}
+
+// SetGreylisting enables or disables greylisting. Retries of a triplet are
+// accepted once delay has passed since its first attempt.
+func (s *Server) SetGreylisting(enabled bool, delay time.Duration) {
+	s.greylist.set(enabled, delay)
+}
+
+// GreylistEntries returns the greylisting triplet table, oldest first
+func (s *Server) GreylistEntries() []GreylistEntry {
+	return s.greylist.list()
+}
+
+// ResetGreylist forgets all known triplets
+func (s *Server) ResetGreylist() {
+	s.greylist.reset()
+}
+
+// Transcripts returns the transcripts of recent connections, oldest first
+func (s *Server) Transcripts() []Transcript {
+	return s.transcripts.list()
+}
+
+// Transcript returns the transcript with the given session ID
+func (s *Server) Transcript(id string) (Transcript, bool) {
+	return s.transcripts.get(id)
+}
+
+// SessionLog returns the activity log of recent connections, newest first
+func (s *Server) SessionLog(filter SessionLogFilter) []SessionLogEntry {
+	return s.transcripts.sessionLog(filter)
+}
+
+// SessionsChan returns the channel on which finished sessions are announced.
+// Announcements are dropped when nobody keeps up; use SessionLog to catch up.
+func (s *Server) SessionsChan() <-chan SessionLogEntry {
+	return s.sessionChan
+}
+
+func (s *Server) notifySession(e SessionLogEntry) {
+	select {
+	case s.sessionChan <- e:
+	default:
+	}
+}
+
 // GetEmails returns a copy of all stored emails in a thread-safe manner
 func (s *Server) GetEmails() []*Email {
 	s.mu.RLock()
@@ -205,6 +597,12 @@ func (s *Server) GetEmails() []*Email {
 	return s.emails
 }
 
+// DroppedNotifications returns how many emails were stored but never
+// delivered on the email channel because it stayed full
+func (s *Server) DroppedNotifications() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
 // EmailsChan returns the channel used for real-time email notifications
 // Consumers can listen on this channel to receive new emails as they arrive
 func (s *Server) EmailsChan() <-chan *Email {
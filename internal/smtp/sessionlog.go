@@ -0,0 +1,162 @@
+package smtp
+
+import (
+	"strings"
+	"time"
+)
+
+// SessionOutcome describes how a client connection ended
+type SessionOutcome string
+
+const (
+	// OutcomeOpen marks connections that are still active
+	OutcomeOpen SessionOutcome = "open"
+	// OutcomeDelivered marks connections that delivered at least one email
+	OutcomeDelivered SessionOutcome = "delivered"
+	// OutcomeRejected marks connections whose commands were refused
+	OutcomeRejected SessionOutcome = "rejected"
+	// OutcomeTimeout marks connections closed for being idle too long
+	OutcomeTimeout SessionOutcome = "timeout"
+	// OutcomeAborted marks connections the client dropped without QUIT
+	OutcomeAborted SessionOutcome = "aborted"
+	// OutcomeClosed marks connections that quit without sending mail
+	OutcomeClosed SessionOutcome = "closed"
+)
+
+// SessionLogEntry summarizes a client connection for the activity log
+type SessionLogEntry struct {
+	// Session ID, shared with the transcript
+	ID string `json:"id"`
+	// Remote address of the client
+	RemoteAddr string `json:"remoteAddr"`
+	// Hostname given by the client in HELO or EHLO
+	Helo string `json:"helo"`
+	// Time the connection was accepted
+	StartedAt time.Time `json:"startedAt"`
+	// Time the connection was closed, zero while it is still open
+	EndedAt time.Time `json:"endedAt"`
+	// Connection duration in milliseconds, up to now for open connections
+	DurationMs int64 `json:"durationMs"`
+	// Bytes received from the client
+	BytesIn int64 `json:"bytesIn"`
+	// Bytes sent to the client
+	BytesOut int64 `json:"bytesOut"`
+	// How the connection ended
+	Outcome SessionOutcome `json:"outcome"`
+	// Why the connection ended that way, usually the last error reply
+	Reason string `json:"reason"`
+	// IDs of the emails delivered over this connection
+	EmailIDs []string `json:"emailIds"`
+}
+
+// SessionLogFilter narrows down the activity log. Zero values match everything.
+type SessionLogFilter struct {
+	// Only return sessions with this outcome
+	Outcome SessionOutcome `json:"outcome"`
+	// Only return sessions whose remote address contains this string
+	RemoteAddr string `json:"remoteAddr"`
+	// Only return sessions started at or after this time
+	Since time.Time `json:"since"`
+	// Maximum number of sessions to return, newest first
+	Limit int `json:"limit"`
+}
+
+// finish marks the end of the connection and decides its outcome
+func (r *transcriptRecorder) finish(quit, timedOut bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := &r.t
+	t.EndedAt = time.Now()
+
+	lastError := ""
+	if len(t.Errors) > 0 {
+		lastError = t.Errors[len(t.Errors)-1]
+	}
+	// A limit explains the session better than the reply it caused
+	for i := len(t.Errors) - 1; i >= 0; i-- {
+		if strings.HasPrefix(t.Errors[i], limitPrefix) {
+			lastError = t.Errors[i]
+			break
+		}
+	}
+
+	switch {
+	case len(t.EmailIDs) > 0:
+		t.Outcome = OutcomeDelivered
+		t.Reason = lastError
+	case timedOut:
+		t.Outcome = OutcomeTimeout
+		t.Reason = lastError
+	case lastError != "":
+		t.Outcome = OutcomeRejected
+		t.Reason = lastError
+	case !quit:
+		t.Outcome = OutcomeAborted
+		t.Reason = "client disconnected without QUIT"
+	default:
+		t.Outcome = OutcomeClosed
+	}
+}
+
+// entry returns the activity log entry for the connection
+func (r *transcriptRecorder) entry() SessionLogEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := &r.t
+	e := SessionLogEntry{
+		ID:         t.ID,
+		RemoteAddr: t.RemoteAddr,
+		Helo:       t.Helo,
+		StartedAt:  t.StartedAt,
+		EndedAt:    t.EndedAt,
+		BytesIn:    t.BytesIn,
+		BytesOut:   t.BytesOut,
+		Outcome:    t.Outcome,
+		Reason:     t.Reason,
+		EmailIDs:   append([]string(nil), t.EmailIDs...),
+	}
+
+	end := t.EndedAt
+	if end.IsZero() {
+		end = time.Now()
+		e.Outcome = OutcomeOpen
+	}
+	e.DurationMs = end.Sub(t.StartedAt).Milliseconds()
+
+	return e
+}
+
+func (f SessionLogFilter) match(e SessionLogEntry) bool {
+	if f.Outcome != "" && e.Outcome != f.Outcome {
+		return false
+	}
+	if f.RemoteAddr != "" && !strings.Contains(e.RemoteAddr, f.RemoteAddr) {
+		return false
+	}
+	if !f.Since.IsZero() && e.StartedAt.Before(f.Since) {
+		return false
+	}
+	return true
+}
+
+// sessionLog returns the activity log entries matching filter, newest first
+func (s *transcriptStore) sessionLog(filter SessionLogFilter) []SessionLogEntry {
+	s.mu.RLock()
+	recorders := append([]*transcriptRecorder(nil), s.order...)
+	s.mu.RUnlock()
+
+	entries := make([]SessionLogEntry, 0)
+	for i := len(recorders) - 1; i >= 0; i-- {
+		e := recorders[i].entry()
+		if !filter.match(e) {
+			continue
+		}
+		entries = append(entries, e)
+		if filter.Limit > 0 && len(entries) >= filter.Limit {
+			break
+		}
+	}
+	return entries
+}
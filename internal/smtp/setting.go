@@ -0,0 +1,30 @@
+package smtp
+
+import "sync"
+
+// setting holds a part of the server configuration. It can be replaced
+// while sessions are running; sessions read the current value once per
+// message.
+type setting[T interface{ Validate() error }] struct {
+	mu    sync.RWMutex
+	value T
+}
+
+// set validates value and makes it current
+func (s *setting[T]) set(value T) error {
+	if err := value.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.value = value
+	s.mu.Unlock()
+	return nil
+}
+
+// get returns the current value
+func (s *setting[T]) get() T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.value
+}
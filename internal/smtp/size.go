@@ -0,0 +1,318 @@
+package smtp
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/watzon/postpilot/internal/tracking"
+	"golang.org/x/net/html"
+)
+
+// Default size budgets
+const (
+	// Gmail clips HTML bodies larger than about 102KB behind a "View entire
+	// message" link
+	DefaultHTMLSizeLimit    = 102 * 1024
+	DefaultMessageSizeLimit = 10 * 1024 * 1024
+	DefaultImageSizeLimit   = 1024 * 1024
+)
+
+// Budgets a size warning can be about
+const (
+	SizeBudgetHTML    = "html"
+	SizeBudgetMessage = "message"
+	SizeBudgetImages  = "images"
+)
+
+// Sections of an HTML part reported as size contributors
+const (
+	SizeSectionInlineStyles = "inline-styles"
+	SizeSectionStyleBlocks  = "style-blocks"
+	SizeSectionBase64Images = "base64-images"
+	SizeSectionTracking     = "tracking"
+	SizeSectionComments     = "comments"
+	SizeSectionOther        = "other"
+)
+
+// SizeConfig holds the size budgets emails are checked against, in bytes.
+// A budget of 0 is not checked.
+type SizeConfig struct {
+	// Size of the decoded HTML part
+	HTMLLimit int `json:"htmlLimit"`
+	// Size of the whole message as received
+	MessageLimit int `json:"messageLimit"`
+	// Total size of embedded images: inline MIME parts and data URIs
+	ImageLimit int `json:"imageLimit"`
+}
+
+// SizeReport breaks down the size of a message
+type SizeReport struct {
+	// Size of the decoded HTML part in bytes
+	HTML int `json:"html"`
+	// Size of the message as received in bytes
+	Message int `json:"message"`
+	// Total size of EmbeddedImages in bytes
+	Images int `json:"images"`
+	// Images embedded as inline MIME parts or data URIs
+	EmbeddedImages []EmbeddedImage `json:"embeddedImages"`
+	// What the HTML part is made of, largest first
+	Contributors []SizeContributor `json:"contributors"`
+	// Budgets the message exceeds
+	Warnings []SizeWarning `json:"warnings"`
+}
+
+// EmbeddedImage is an image carried inside a message
+type EmbeddedImage struct {
+	// Content-ID or file name of a MIME part, or "data URI"
+	Name string `json:"name"`
+	// Media type of the image
+	Type string `json:"type"`
+	// Size as encoded in the message, in bytes
+	Size int `json:"size"`
+	// Line of the HTML part a data URI is on, 0 for MIME parts
+	Line int `json:"line"`
+}
+
+// SizeContributor is a section of an HTML part with its size
+type SizeContributor struct {
+	// inline-styles, style-blocks, base64-images, tracking, comments or
+	// other
+	Section string `json:"section"`
+	Size    int    `json:"size"`
+	// Share of the HTML part, 0-100
+	Percent float64 `json:"percent"`
+}
+
+// SizeWarning is a budget a message exceeds
+type SizeWarning struct {
+	// html, message or images
+	Budget  string `json:"budget"`
+	Size    int    `json:"size"`
+	Limit   int    `json:"limit"`
+	Message string `json:"message"`
+}
+
+// Validate checks that no budget is negative
+func (c SizeConfig) Validate() error {
+	if c.HTMLLimit < 0 || c.MessageLimit < 0 || c.ImageLimit < 0 {
+		return errors.New("size limits cannot be negative")
+	}
+	return nil
+}
+
+// SetSizeBudget replaces the size budgets emails are checked against
+func (s *Server) SetSizeBudget(config SizeConfig) error {
+	return s.size.set(config)
+}
+
+// MeasureSize breaks down the size of a raw message and its decoded HTML
+// part, and checks them against the budgets in config
+func MeasureSize(config SizeConfig, raw []byte, htmlPart string) *SizeReport {
+	report := &SizeReport{
+		HTML:           len(htmlPart),
+		Message:        len(raw),
+		EmbeddedImages: []EmbeddedImage{},
+		Contributors:   []SizeContributor{},
+		Warnings:       []SizeWarning{},
+	}
+
+	// Images in MIME parts that are shown inline rather than attached
+	walkParts(raw, func(p *mimePart) {
+		if !strings.HasPrefix(p.mediaType, "image/") {
+			return
+		}
+		disposition, filename := p.disposition()
+		cid := p.contentID()
+		if disposition == "attachment" || (cid == "" && disposition != "inline") {
+			return
+		}
+		name := cid
+		if name == "" {
+			name = filename
+		}
+		report.EmbeddedImages = append(report.EmbeddedImages, EmbeddedImage{
+			Name: name,
+			Type: p.mediaType,
+			Size: len(p.body),
+		})
+	})
+
+	sections, dataImages := htmlSections(htmlPart)
+	report.EmbeddedImages = append(report.EmbeddedImages, dataImages...)
+	for _, img := range report.EmbeddedImages {
+		report.Images += img.Size
+	}
+
+	if report.HTML > 0 {
+		for _, section := range []string{
+			SizeSectionInlineStyles, SizeSectionStyleBlocks, SizeSectionBase64Images,
+			SizeSectionTracking, SizeSectionComments, SizeSectionOther,
+		} {
+			if sections[section] <= 0 {
+				continue
+			}
+			report.Contributors = append(report.Contributors, SizeContributor{
+				Section: section,
+				Size:    sections[section],
+				Percent: float64(sections[section]) * 100 / float64(report.HTML),
+			})
+		}
+		sort.SliceStable(report.Contributors, func(i, j int) bool {
+			return report.Contributors[i].Size > report.Contributors[j].Size
+		})
+	}
+
+	if config.HTMLLimit > 0 && report.HTML > config.HTMLLimit {
+		report.Warnings = append(report.Warnings, SizeWarning{
+			Budget: SizeBudgetHTML,
+			Size:   report.HTML,
+			Limit:  config.HTMLLimit,
+			Message: fmt.Sprintf("HTML part is %s, over the %s budget; Gmail will clip it",
+				formatSize(report.HTML), formatSize(config.HTMLLimit)),
+		})
+	}
+	if config.MessageLimit > 0 && report.Message > config.MessageLimit {
+		report.Warnings = append(report.Warnings, SizeWarning{
+			Budget:  SizeBudgetMessage,
+			Size:    report.Message,
+			Limit:   config.MessageLimit,
+			Message: fmt.Sprintf("message is %s, over the %s budget", formatSize(report.Message), formatSize(config.MessageLimit)),
+		})
+	}
+	if config.ImageLimit > 0 && report.Images > config.ImageLimit {
+		report.Warnings = append(report.Warnings, SizeWarning{
+			Budget: SizeBudgetImages,
+			Size:   report.Images,
+			Limit:  config.ImageLimit,
+			Message: fmt.Sprintf("%d embedded images take %s, over the %s budget",
+				len(report.EmbeddedImages), formatSize(report.Images), formatSize(config.ImageLimit)),
+		})
+	}
+
+	return report
+}
+
+// htmlSections measures the sections of an HTML part and returns the data
+// URI images it contains. Sizes are of the markup as written; everything
+// not in a named section is counted as other.
+func htmlSections(s string) (map[string]int, []EmbeddedImage) {
+	sections := make(map[string]int)
+	images := []EmbeddedImage{}
+	if s == "" {
+		return sections, images
+	}
+
+	line := 1
+	inStyle := false
+	z := html.NewTokenizer(strings.NewReader(s))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		raw := z.Raw()
+		start := line
+		line += strings.Count(string(raw), "\n")
+
+		switch tt {
+		case html.CommentToken:
+			sections[SizeSectionComments] += len(raw)
+
+		case html.TextToken:
+			if inStyle {
+				sections[SizeSectionStyleBlocks] += len(raw)
+			}
+
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == "style" {
+				sections[SizeSectionStyleBlocks] += len(raw)
+				inStyle = false
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			if t.Data == "style" {
+				sections[SizeSectionStyleBlocks] += len(raw)
+				inStyle = tt == html.StartTagToken
+				continue
+			}
+			if t.Data == "img" && tracking.CheckImage(attrMap(t)) != nil {
+				sections[SizeSectionTracking] += len(raw)
+				continue
+			}
+
+			// Each byte of an attribute counts towards one section only, so
+			// that other, which is what's left, can't go negative
+			for _, a := range t.Attr {
+				// data URIs in src, background, href or style attributes
+				embedded := 0
+				for _, uri := range dataURIs(a.Val) {
+					sections[SizeSectionBase64Images] += len(uri)
+					embedded += len(uri)
+					mediaType, _, _ := strings.Cut(strings.TrimPrefix(uri, "data:"), ";")
+					images = append(images, EmbeddedImage{
+						Name: "data URI",
+						Type: mediaType,
+						Size: len(uri),
+						Line: start,
+					})
+				}
+
+				switch {
+				case a.Key == "style":
+					sections[SizeSectionInlineStyles] += max(len(a.Val)-embedded, 0)
+				case a.Key == "href" && embedded == 0:
+					sections[SizeSectionTracking] += len(a.Val) - len(tracking.StripParams(a.Val))
+				}
+			}
+		}
+	}
+
+	other := len(s)
+	for _, n := range sections {
+		other -= n
+	}
+	// Attribute values are measured decoded, which is never longer than
+	// written, but don't report a negative size if that ever changes
+	sections[SizeSectionOther] = max(other, 0)
+	return sections, images
+}
+
+// dataURIs returns the data:image URIs in an attribute value
+func dataURIs(v string) []string {
+	var uris []string
+	for {
+		i := strings.Index(strings.ToLower(v), "data:image/")
+		if i < 0 {
+			return uris
+		}
+		v = v[i:]
+		end := strings.IndexAny(v, "\"') \t\r\n")
+		if end < 0 {
+			end = len(v)
+		}
+		uris = append(uris, v[:end])
+		v = v[end:]
+	}
+}
+
+func attrMap(t html.Token) map[string]string {
+	attrs := make(map[string]string, len(t.Attr))
+	for _, a := range t.Attr {
+		attrs[a.Key] = a.Val
+	}
+	return attrs
+}
+
+// formatSize formats a byte count for messages
+func formatSize(n int) string {
+	switch {
+	case n >= 1024*1024:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1024*1024))
+	case n >= 1024:
+		return fmt.Sprintf("%.1fKB", float64(n)/1024)
+	}
+	return fmt.Sprintf("%dB", n)
+}
@@ -0,0 +1,31 @@
+package smtp
+
+import "testing"
+
+func TestHTMLSectionsDontOverlap(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+	}{
+		{"data URI link with tracking parameters", `<a href="data:image/png;base64,AAAA?utm_source=newsletter&utm_medium=email&utm_campaign=launch">x</a>`},
+		{"data URI in inline style", `<div style="background:url(data:image/png;base64,AAAA)">x</div>`},
+		{"tracking pixel with inline style", `<img src="https://t.example.com/open.gif" width="1" height="1" style="display:none">`},
+		{"tracking parameters", `<p style="color:red"><a href="https://example.com/?utm_source=x&amp;utm_medium=y">x</a></p><!-- c -->`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sections, _ := htmlSections(tt.html)
+			total := 0
+			for name, n := range sections {
+				if n < 0 {
+					t.Errorf("section %s is %d bytes", name, n)
+				}
+				total += n
+			}
+			if total != len(tt.html) {
+				t.Errorf("sections add up to %d bytes, want %d: %v", total, len(tt.html), sections)
+			}
+		})
+	}
+}
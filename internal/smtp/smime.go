@@ -0,0 +1,467 @@
+package smtp
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"mime"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/smallstep/pkcs7"
+)
+
+// Protocols of signed or encrypted messages
+const (
+	SecuritySMIME   = "smime"
+	SecurityPGP     = "pgp"
+	SecurityUnknown = "unknown"
+)
+
+// Outcomes of checking a signature
+const (
+	// The signature matches and the signer chains to the trust store
+	SignatureValid = "valid"
+	// The signature matches, but the signer isn't trusted
+	SignatureUntrusted = "untrusted"
+	// The content was changed after signing or the signature is malformed
+	SignatureInvalid = "invalid"
+	// The signature wasn't checked, e.g. a PGP signature
+	SignatureUnverified = "unverified"
+)
+
+// Outcomes of decrypting a message
+const (
+	DecryptionDone        = "decrypted"
+	DecryptionNoKey       = "no-key"
+	DecryptionFailed      = "failed"
+	DecryptionUnsupported = "unsupported"
+)
+
+// maxSecurityDepth limits how many layers, like a signature inside an
+// encrypted message, are unwrapped
+const maxSecurityDepth = 4
+
+// SMIMEConfig holds the certificates S/MIME messages are checked with
+type SMIMEConfig struct {
+	// PEM file of the CA certificates signers are verified against. Without
+	// one, matching signatures are reported as untrusted.
+	TrustFile string `json:"trustFile"`
+	// PEM certificate and private key of a test recipient, used to decrypt
+	// messages encrypted to it
+	CertFile string `json:"certFile"`
+	KeyFile  string `json:"keyFile"`
+}
+
+// Security describes how a message is signed or encrypted
+type Security struct {
+	// smime, pgp or unknown
+	Protocol string `json:"protocol"`
+	// Content type that marks the message as signed or encrypted
+	MediaType string `json:"mediaType"`
+	Signed    bool   `json:"signed"`
+	Encrypted bool   `json:"encrypted"`
+	// Outcome of checking the signature, nil if the message isn't signed
+	// or the signature is still encrypted
+	Signature *Signature `json:"signature"`
+	// decrypted, no-key, failed or unsupported, empty if the message isn't
+	// encrypted
+	Decryption string `json:"decryption"`
+	// What couldn't be checked or shown, and why
+	Notes []string `json:"notes"`
+}
+
+// Signature is the outcome of checking a signature
+type Signature struct {
+	// valid, untrusted, invalid or unverified
+	Status string `json:"status"`
+	// Why the signature isn't valid, empty if it is
+	Reason string `json:"reason"`
+	// Signing time claimed by the signer, if any
+	SigningTime *time.Time `json:"signingTime"`
+	// Certificate of the signer, nil if it isn't included
+	Signer *Certificate `json:"signer"`
+	// Other certificates sent with the signature, like intermediates
+	Certificates []Certificate `json:"certificates"`
+}
+
+// Certificate summarizes an X.509 certificate
+type Certificate struct {
+	Subject      string    `json:"subject"`
+	Issuer       string    `json:"issuer"`
+	Emails       []string  `json:"emails"`
+	SerialNumber string    `json:"serialNumber"`
+	NotBefore    time.Time `json:"notBefore"`
+	NotAfter     time.Time `json:"notAfter"`
+	// valid, expired or not-yet-valid when the email was received
+	Validity   string `json:"validity"`
+	SelfSigned bool   `json:"selfSigned"`
+	// SHA-256 of the DER encoding, in hex
+	Fingerprint string `json:"fingerprint"`
+}
+
+// Validate checks that the test certificate and key are given together
+func (c SMIMEConfig) Validate() error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return errors.New("S/MIME decryption needs both a certificate and a key file")
+	}
+	return nil
+}
+
+// SetSMIME replaces the trust store and test key used for S/MIME messages
+func (s *Server) SetSMIME(config SMIMEConfig) error {
+	return s.smime.set(config)
+}
+
+// inspectSMIME verifies and decrypts an S/MIME message recognized by
+// parseEmail. Content found inside replaces the bodies of email.
+func inspectSMIME(config SMIMEConfig, email *Email) {
+	if email.Security == nil || email.Security.Protocol != SecuritySMIME {
+		return
+	}
+	unwrapSMIME(config, email, []byte(email.Raw), 0)
+}
+
+// detectSecurity recognizes a signed or encrypted message by its content
+// type, or returns nil. Cases that can't be inspected are labelled here.
+func detectSecurity(mediaType string, params map[string]string) *Security {
+	protocol := strings.ToLower(params["protocol"])
+	sec := &Security{MediaType: mediaType, Notes: []string{}}
+
+	switch {
+	case mediaType == "multipart/signed":
+		sec.Signed = true
+		switch {
+		case isSMIMESignature(protocol):
+			sec.Protocol = SecuritySMIME
+		case protocol == "application/pgp-signature":
+			sec.Protocol = SecurityPGP
+			sec.Signature = &Signature{Status: SignatureUnverified, Reason: "PGP signatures aren't verified", Certificates: []Certificate{}}
+		default:
+			sec.Protocol = SecurityUnknown
+			sec.Signature = &Signature{Status: SignatureUnverified, Reason: fmt.Sprintf("unsupported signature protocol %q", protocol), Certificates: []Certificate{}}
+		}
+	case mediaType == "multipart/encrypted":
+		sec.Encrypted = true
+		sec.Decryption = DecryptionUnsupported
+		if protocol == "application/pgp-encrypted" {
+			sec.Protocol = SecurityPGP
+			sec.Notes = append(sec.Notes, "PGP encrypted messages can't be decrypted")
+		} else {
+			sec.Protocol = SecurityUnknown
+			sec.Notes = append(sec.Notes, fmt.Sprintf("unsupported encryption protocol %q", protocol))
+		}
+	case isPKCS7Mime(mediaType):
+		sec.Protocol = SecuritySMIME
+		switch strings.ToLower(params["smime-type"]) {
+		case "signed-data":
+			sec.Signed = true
+		case "certs-only":
+			sec.Notes = append(sec.Notes, "the message only carries certificates")
+		default:
+			// enveloped-data, authEnveloped-data and messages without an
+			// smime-type are taken for encrypted until parsed
+			sec.Encrypted = true
+		}
+	default:
+		return nil
+	}
+	return sec
+}
+
+// hidesContent reports whether the body of a message is wrapped in a
+// form that can't be shown as it is
+func (s *Security) hidesContent() bool {
+	return s.Encrypted || isPKCS7Mime(s.MediaType)
+}
+
+func isSMIMESignature(protocol string) bool {
+	return protocol == "application/pkcs7-signature" || protocol == "application/x-pkcs7-signature"
+}
+
+func isPKCS7Mime(mediaType string) bool {
+	return mediaType == "application/pkcs7-mime" || mediaType == "application/x-pkcs7-mime"
+}
+
+// unwrapSMIME checks one layer of an S/MIME entity, a message or the
+// content of an outer layer, and continues with the content it wraps.
+// Content at depth 0 was already parsed by parseEmail.
+func unwrapSMIME(config SMIMEConfig, email *Email, entity []byte, depth int) {
+	sec := email.Security
+	msg, err := mail.ReadMessage(bytes.NewReader(entity))
+	if err != nil {
+		sec.Notes = append(sec.Notes, fmt.Sprintf("failed to read the wrapped content: %v", err))
+		return
+	}
+	mediaType, params, _ := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	_, body := splitMessage(entity)
+
+	if depth >= maxSecurityDepth {
+		sec.Notes = append(sec.Notes, "too many nested signature and encryption layers")
+		return
+	}
+
+	switch {
+	case mediaType == "multipart/signed" && isSMIMESignature(strings.ToLower(params["protocol"])):
+		sec.Signed = true
+		content, signature, err := splitSigned(body, params["boundary"])
+		if err != nil {
+			sec.Signature = &Signature{Status: SignatureInvalid, Reason: err.Error(), Certificates: []Certificate{}}
+			return
+		}
+		p7, err := pkcs7.Parse(signature)
+		if err != nil {
+			sec.Signature = &Signature{Status: SignatureInvalid, Reason: fmt.Sprintf("malformed signature: %v", pkcs7Reason(err)), Certificates: []Certificate{}}
+		} else {
+			p7.Content = content
+			sec.Signature = checkSMIMESignature(config, p7, email.Timestamp)
+		}
+		unwrapSMIME(config, email, content, depth+1)
+
+	case isPKCS7Mime(mediaType):
+		part := &mimePart{header: textproto.MIMEHeader(msg.Header), body: body}
+		der, err := part.decode()
+		if err != nil {
+			sec.Notes = append(sec.Notes, fmt.Sprintf("failed to decode the S/MIME content: %v", err))
+			return
+		}
+		p7, err := pkcs7.Parse(der)
+		if errors.Is(err, pkcs7.ErrUnsupportedContentType) {
+			// E.g. AuthEnvelopedData, used for AES-GCM
+			if sec.Encrypted {
+				sec.Decryption = DecryptionUnsupported
+			}
+			sec.Notes = append(sec.Notes, fmt.Sprintf("unsupported S/MIME content type %q", params["smime-type"]))
+			return
+		}
+		if err != nil {
+			if sec.Encrypted {
+				sec.Decryption = DecryptionFailed
+			}
+			sec.Notes = append(sec.Notes, fmt.Sprintf("malformed S/MIME content: %v", pkcs7Reason(err)))
+			return
+		}
+
+		switch smimeType := strings.ToLower(params["smime-type"]); {
+		case smimeType == "certs-only":
+			sec.Notes = append(sec.Notes, fmt.Sprintf("%d certificates and no message content", len(p7.Certificates)))
+		case len(p7.Signers) > 0:
+			// Opaque signed content, readable without a key
+			sec.Signed = true
+			sec.Encrypted = sec.Encrypted && depth > 0
+			sec.Signature = checkSMIMESignature(config, p7, email.Timestamp)
+			unwrapSMIME(config, email, p7.Content, depth+1)
+		default:
+			sec.Encrypted = true
+			content, status, note := decryptSMIME(config, p7)
+			sec.Decryption = status
+			if note != "" {
+				sec.Notes = append(sec.Notes, note)
+			}
+			if status == DecryptionDone {
+				unwrapSMIME(config, email, content, depth+1)
+			}
+		}
+
+	case depth > 0:
+		email.Body, email.HTML = "", ""
+		if err := parseBody(email, msg.Header, bytes.NewReader(body)); err != nil {
+			sec.Notes = append(sec.Notes, fmt.Sprintf("failed to parse the wrapped content: %v", err))
+		}
+	}
+}
+
+// splitSigned returns the first part of a multipart/signed body exactly as
+// it was signed, and the decoded signature from the second part
+func splitSigned(body []byte, boundary string) ([]byte, []byte, error) {
+	if boundary == "" {
+		return nil, nil, errors.New("multipart/signed without a boundary")
+	}
+	delimiter := []byte("--" + boundary)
+
+	var parts [][]byte
+	start := -1
+	for pos := 0; pos < len(body); {
+		next := len(body)
+		if i := bytes.IndexByte(body[pos:], '\n'); i >= 0 {
+			next = pos + i + 1
+		}
+		line := bytes.TrimRight(body[pos:next], "\r\n")
+		if bytes.HasPrefix(line, delimiter) {
+			rest := bytes.TrimSpace(line[len(delimiter):])
+			if start >= 0 {
+				// The line break before a delimiter belongs to it
+				end := bytes.TrimSuffix(body[start:pos], []byte("\n"))
+				parts = append(parts, bytes.TrimSuffix(end, []byte("\r")))
+			}
+			if string(rest) == "--" {
+				break
+			}
+			start = next
+		}
+		pos = next
+	}
+	if len(parts) != 2 {
+		return nil, nil, fmt.Errorf("multipart/signed has %d parts, want 2", len(parts))
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(parts[1]))
+	if err != nil {
+		return nil, nil, fmt.Errorf("malformed signature part: %v", err)
+	}
+	_, sigBody := splitMessage(parts[1])
+	signature, err := (&mimePart{header: textproto.MIMEHeader(msg.Header), body: sigBody}).decode()
+	if err != nil {
+		return nil, nil, fmt.Errorf("malformed signature part: %v", err)
+	}
+	return parts[0], signature, nil
+}
+
+// checkSMIMESignature verifies a signature, then the signer against the
+// trust store. Certificate validity is given as of at.
+func checkSMIMESignature(config SMIMEConfig, p7 *pkcs7.PKCS7, at time.Time) *Signature {
+	sig := &Signature{Certificates: []Certificate{}}
+
+	signer := p7.GetOnlySigner()
+	for _, cert := range p7.Certificates {
+		summary := summarizeCertificate(cert, at)
+		if cert == signer {
+			sig.Signer = &summary
+		} else {
+			sig.Certificates = append(sig.Certificates, summary)
+		}
+	}
+	var signingTime time.Time
+	if err := p7.UnmarshalSignedAttribute(pkcs7.OIDAttributeSigningTime, &signingTime); err == nil {
+		sig.SigningTime = &signingTime
+	}
+
+	if err := p7.Verify(); err != nil {
+		sig.Status, sig.Reason = SignatureInvalid, pkcs7Reason(err)
+		return sig
+	}
+
+	sig.Status = SignatureUntrusted
+	if config.TrustFile == "" {
+		sig.Reason = "no trust store is configured"
+		return sig
+	}
+	roots, err := readTrustStore(config.TrustFile)
+	if err != nil {
+		sig.Reason = err.Error()
+		return sig
+	}
+	if err := p7.VerifyWithChain(roots); err != nil {
+		sig.Reason = pkcs7Reason(err)
+		return sig
+	}
+	sig.Status = SignatureValid
+	return sig
+}
+
+// decryptSMIME decrypts enveloped data with the test key. It returns the
+// content, the decryption outcome and a note on why it failed.
+func decryptSMIME(config SMIMEConfig, p7 *pkcs7.PKCS7) ([]byte, string, string) {
+	if config.CertFile == "" {
+		return nil, DecryptionNoKey, "configure a test certificate and key under smime in settings.json to decrypt"
+	}
+	cert, key, err := readSMIMEKey(config)
+	if err != nil {
+		return nil, DecryptionFailed, err.Error()
+	}
+	content, err := p7.Decrypt(cert, key)
+	if errors.Is(err, pkcs7.ErrUnsupportedAlgorithm) {
+		return nil, DecryptionUnsupported, pkcs7Reason(err)
+	}
+	if err != nil {
+		return nil, DecryptionFailed, pkcs7Reason(err)
+	}
+	return content, DecryptionDone, ""
+}
+
+func readTrustStore(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trust store: %v", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificates in %s", path)
+	}
+	return roots, nil
+}
+
+func readSMIMEKey(config SMIMEConfig) (*x509.Certificate, *rsa.PrivateKey, error) {
+	data, err := os.ReadFile(config.CertFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read test certificate: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, nil, fmt.Errorf("%s is not a PEM encoded certificate", config.CertFile)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid certificate in %s: %v", config.CertFile, err)
+	}
+
+	data, err = os.ReadFile(config.KeyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read test key: %v", err)
+	}
+	block, _ = pem.Decode(data)
+	if block == nil {
+		return nil, nil, fmt.Errorf("%s is not a PEM encoded key", config.KeyFile)
+	}
+	key, err := parsePrivateKey(block)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid test key in %s: %v", config.KeyFile, err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, nil, fmt.Errorf("only RSA keys can decrypt, got %T", key)
+	}
+	return cert, rsaKey, nil
+}
+
+func summarizeCertificate(cert *x509.Certificate, at time.Time) Certificate {
+	fingerprint := sha256.Sum256(cert.Raw)
+	c := Certificate{
+		Subject:      cert.Subject.String(),
+		Issuer:       cert.Issuer.String(),
+		Emails:       cert.EmailAddresses,
+		SerialNumber: cert.SerialNumber.Text(16),
+		NotBefore:    cert.NotBefore,
+		NotAfter:     cert.NotAfter,
+		Validity:     "valid",
+		SelfSigned:   bytes.Equal(cert.RawSubject, cert.RawIssuer) && cert.CheckSignatureFrom(cert) == nil,
+		Fingerprint:  hex.EncodeToString(fingerprint[:]),
+	}
+	if c.Emails == nil {
+		c.Emails = []string{}
+	}
+	switch {
+	case at.After(cert.NotAfter):
+		c.Validity = "expired"
+	case at.Before(cert.NotBefore):
+		c.Validity = "not-yet-valid"
+	}
+	return c
+}
+
+// pkcs7Reason makes an error of the pkcs7 package readable on its own
+func pkcs7Reason(err error) string {
+	var mismatch *pkcs7.MessageDigestMismatchError
+	if errors.As(err, &mismatch) {
+		return "message digest mismatch: the content was changed after signing"
+	}
+	return strings.TrimPrefix(err.Error(), "pkcs7: ")
+}
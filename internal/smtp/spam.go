@@ -0,0 +1,20 @@
+package smtp
+
+import (
+	"github.com/watzon/postpilot/internal/spam"
+)
+
+// scoreSpam runs the spam rules against email
+func scoreSpam(config spam.Config, email *Email) *spam.Report {
+	return spam.Score(config, spam.Message{
+		Subject: email.Subject,
+		Headers: email.Headers,
+		Text:    email.Body,
+		HTML:    email.HTML,
+	})
+}
+
+// SetSpam replaces the spam scoring threshold and rules file
+func (s *Server) SetSpam(config spam.Config) error {
+	return s.spam.set(config)
+}
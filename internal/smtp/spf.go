@@ -0,0 +1,456 @@
+package smtp
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// SPF results (RFC 7208 section 2.6)
+const (
+	SPFNone      = "none"
+	SPFNeutral   = "neutral"
+	SPFPass      = "pass"
+	SPFFail      = "fail"
+	SPFSoftFail  = "softfail"
+	SPFTempError = "temperror"
+	SPFPermError = "permerror"
+)
+
+// spfLookupLimit is the number of DNS querying terms an SPF evaluation may
+// use (RFC 7208 section 4.6.4)
+const spfLookupLimit = 10
+
+// spfVoidLookupLimit is the number of DNS querying terms that may find no
+// records (RFC 7208 section 4.6.4)
+const spfVoidLookupLimit = 2
+
+// SPFResult is the outcome of checking the client IP against the SPF
+// record of the envelope sender's domain
+type SPFResult struct {
+	// none, neutral, pass, fail, softfail, temperror or permerror
+	Result string `json:"result"`
+	// Domain whose record was checked: the MAIL FROM domain, or the HELO
+	// name for the null sender
+	Domain string `json:"domain"`
+	// SPF record of Domain
+	Record string `json:"record"`
+	// Mechanism that determined the result, e.g. "-all"
+	Mechanism string `json:"mechanism"`
+	// Explanation of the result
+	Reason string `json:"reason"`
+}
+
+// spfCheck holds the state of one SPF evaluation
+type spfCheck struct {
+	zone    *zone
+	ip      net.IP
+	sender  string
+	helo    string
+	lookups int
+	// Lookups that found no records
+	voids int
+}
+
+// checkSPF evaluates SPF for a message from ip with the given envelope
+// sender and HELO name
+func checkSPF(z *zone, ip net.IP, mailFrom, helo string) SPFResult {
+	sender := mailFrom
+	if sender == "" {
+		sender = "postmaster@" + helo
+	}
+	_, domain, ok := strings.Cut(sender, "@")
+	if !ok || domain == "" {
+		return SPFResult{Result: SPFNone, Reason: "no sender domain to check"}
+	}
+	domain = normalizeName(domain)
+
+	if ip == nil {
+		return SPFResult{Result: SPFNone, Domain: domain, Reason: "client IP is unknown"}
+	}
+
+	c := &spfCheck{zone: z, ip: ip, sender: sender, helo: helo}
+	result, mechanism, reason := c.checkHost(domain)
+	return SPFResult{
+		Result:    result,
+		Domain:    domain,
+		Record:    spfRecord(z, domain),
+		Mechanism: mechanism,
+		Reason:    reason,
+	}
+}
+
+// spfRecords returns the TXT records of domain that are SPF records
+func spfRecords(z *zone, domain string) []string {
+	var found []string
+	for _, txt := range z.lookup(domain, "TXT") {
+		if strings.EqualFold(txt, "v=spf1") || strings.HasPrefix(strings.ToLower(txt), "v=spf1 ") {
+			found = append(found, txt)
+		}
+	}
+	return found
+}
+
+// spfRecord returns the SPF record of domain, or "" if it has none or
+// more than one
+func spfRecord(z *zone, domain string) string {
+	if records := spfRecords(z, domain); len(records) == 1 {
+		return records[0]
+	}
+	return ""
+}
+
+// checkHost implements check_host() of RFC 7208 section 4
+func (c *spfCheck) checkHost(domain string) (result, mechanism, reason string) {
+	records := spfRecords(c.zone, domain)
+	switch len(records) {
+	case 0:
+		return SPFNone, "", fmt.Sprintf("%s has no SPF record", domain)
+	case 1:
+	default:
+		return SPFPermError, "", fmt.Sprintf("%s has %d SPF records", domain, len(records))
+	}
+
+	var redirect string
+	for _, term := range strings.Fields(records[0])[1:] {
+		// Modifiers
+		if name, value, ok := strings.Cut(term, "="); ok && !strings.ContainsAny(name, ":/") {
+			if strings.EqualFold(name, "redirect") {
+				redirect = value
+			}
+			continue
+		}
+
+		qualifier := SPFPass
+		switch term[0] {
+		case '+':
+			term = term[1:]
+		case '-':
+			qualifier, term = SPFFail, term[1:]
+		case '~':
+			qualifier, term = SPFSoftFail, term[1:]
+		case '?':
+			qualifier, term = SPFNeutral, term[1:]
+		}
+
+		match, err := c.matches(domain, term)
+		if err != nil {
+			return err.result, term, err.reason
+		}
+		if match {
+			return qualifier, term, fmt.Sprintf("%s matched %s in the record of %s", c.ip, term, domain)
+		}
+	}
+
+	if redirect != "" {
+		target, err := c.expand(redirect, domain)
+		if err != nil {
+			return err.result, "redirect=" + redirect, err.reason
+		}
+		if err := c.countLookup(); err != nil {
+			return err.result, "redirect=" + redirect, err.reason
+		}
+		result, mechanism, reason := c.checkHost(target)
+		if result == SPFNone {
+			return SPFPermError, "redirect=" + redirect, fmt.Sprintf("redirect target %s has no SPF record", target)
+		}
+		return result, mechanism, reason
+	}
+
+	return SPFNeutral, "", fmt.Sprintf("no mechanism in the record of %s matched %s", domain, c.ip)
+}
+
+// spfError aborts an evaluation with a temperror or permerror
+type spfError struct {
+	result string
+	reason string
+}
+
+func permError(format string, args ...interface{}) *spfError {
+	return &spfError{result: SPFPermError, reason: fmt.Sprintf(format, args...)}
+}
+
+func (c *spfCheck) countLookup() *spfError {
+	c.lookups++
+	if c.lookups > spfLookupLimit {
+		return permError("more than %d DNS lookups", spfLookupLimit)
+	}
+	return nil
+}
+
+// countVoid records a lookup that found no records
+func (c *spfCheck) countVoid() *spfError {
+	c.voids++
+	if c.voids > spfVoidLookupLimit {
+		return permError("more than %d DNS lookups found no records", spfVoidLookupLimit)
+	}
+	return nil
+}
+
+// matches reports whether a mechanism matches the client IP
+func (c *spfCheck) matches(domain, term string) (bool, *spfError) {
+	name, arg, _ := strings.Cut(term, ":")
+	// a/24 and mx//64 put the prefix lengths right after the name
+	if i := strings.IndexByte(name, '/'); i >= 0 {
+		name, arg = name[:i], name[i:]+arg
+	}
+
+	switch strings.ToLower(name) {
+	case "all":
+		return true, nil
+
+	case "include":
+		if err := c.countLookup(); err != nil {
+			return false, err
+		}
+		target, err := c.expand(arg, domain)
+		if err != nil {
+			return false, err
+		}
+		switch result, _, reason := c.checkHost(target); result {
+		case SPFPass:
+			return true, nil
+		case SPFFail, SPFSoftFail, SPFNeutral:
+			return false, nil
+		case SPFTempError:
+			return false, &spfError{result: SPFTempError, reason: reason}
+		default:
+			return false, permError("include:%s: %s", target, reason)
+		}
+
+	case "a", "mx":
+		if err := c.countLookup(); err != nil {
+			return false, err
+		}
+		spec, v4, v6, err := splitCIDR(arg)
+		if err != nil {
+			return false, err
+		}
+		target := domain
+		if spec != "" {
+			if target, err = c.expand(spec, domain); err != nil {
+				return false, err
+			}
+		}
+
+		hosts := []string{target}
+		if strings.EqualFold(name, "mx") {
+			hosts = c.zone.lookup(target, "MX")
+			if len(hosts) > spfLookupLimit {
+				return false, permError("%s has more than %d MX records", target, spfLookupLimit)
+			}
+			if len(hosts) == 0 {
+				return false, c.countVoid()
+			}
+		}
+		for _, h := range hosts {
+			addrs := c.zone.lookupIPs(h)
+			if len(addrs) == 0 {
+				if err := c.countVoid(); err != nil {
+					return false, err
+				}
+			}
+			for _, addr := range addrs {
+				if inPrefix(c.ip, addr, v4, v6) {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+
+	case "ip4", "ip6":
+		network := arg
+		if !strings.Contains(network, "/") {
+			if strings.EqualFold(name, "ip4") {
+				network += "/32"
+			} else {
+				network += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(network)
+		if err != nil || (strings.EqualFold(name, "ip4") != (n.IP.To4() != nil)) {
+			return false, permError("invalid %s network %q", name, arg)
+		}
+		return n.Contains(c.ip), nil
+
+	case "exists":
+		if err := c.countLookup(); err != nil {
+			return false, err
+		}
+		target, err := c.expand(arg, domain)
+		if err != nil {
+			return false, err
+		}
+		if len(c.zone.lookup(target, "A")) == 0 {
+			return false, c.countVoid()
+		}
+		return true, nil
+
+	case "ptr":
+		// Zones have no reverse records, so ptr never matches
+		if err := c.countLookup(); err != nil {
+			return false, err
+		}
+		return false, nil
+	}
+
+	return false, permError("unknown mechanism %q", term)
+}
+
+// splitCIDR separates a domain-spec from its optional /ip4-cidr and
+// //ip6-cidr suffixes
+func splitCIDR(arg string) (spec string, v4, v6 int, err *spfError) {
+	v4, v6 = 32, 128
+	spec = arg
+	if i := strings.Index(spec, "//"); i >= 0 {
+		n, convErr := strconv.Atoi(spec[i+2:])
+		if convErr != nil || n < 0 || n > 128 {
+			return "", 0, 0, permError("invalid ip6 prefix length in %q", arg)
+		}
+		spec, v6 = spec[:i], n
+	}
+	if i := strings.IndexByte(spec, '/'); i >= 0 {
+		n, convErr := strconv.Atoi(spec[i+1:])
+		if convErr != nil || n < 0 || n > 32 {
+			return "", 0, 0, permError("invalid ip4 prefix length in %q", arg)
+		}
+		spec, v4 = spec[:i], n
+	}
+	return spec, v4, v6, nil
+}
+
+// inPrefix reports whether ip and addr share the prefix of the length for
+// their address family
+func inPrefix(ip, addr net.IP, v4, v6 int) bool {
+	if (ip.To4() != nil) != (addr.To4() != nil) {
+		return false
+	}
+	bits, ones := 128, v6
+	if ip.To4() != nil {
+		ip, addr = ip.To4(), addr.To4()
+		bits, ones = 32, v4
+	}
+	mask := net.CIDRMask(ones, bits)
+	return ip.Mask(mask).Equal(addr.Mask(mask))
+}
+
+// expand performs macro expansion on a domain-spec (RFC 7208 section 7)
+func (c *spfCheck) expand(spec, domain string) (string, *spfError) {
+	var b strings.Builder
+	for i := 0; i < len(spec); i++ {
+		if spec[i] != '%' {
+			b.WriteByte(spec[i])
+			continue
+		}
+		if i+1 >= len(spec) {
+			return "", permError("incomplete macro in %q", spec)
+		}
+		i++
+		switch spec[i] {
+		case '%':
+			b.WriteByte('%')
+		case '_':
+			b.WriteByte(' ')
+		case '-':
+			b.WriteString("%20")
+		case '{':
+			end := strings.IndexByte(spec[i:], '}')
+			if end < 0 {
+				return "", permError("unterminated macro in %q", spec)
+			}
+			value, err := c.macro(spec[i+1:i+end], domain)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i += end
+		default:
+			return "", permError("invalid macro in %q", spec)
+		}
+	}
+	return normalizeName(b.String()), nil
+}
+
+// macro expands the body of a %{...} macro
+func (c *spfCheck) macro(body, domain string) (string, *spfError) {
+	if body == "" {
+		return "", permError("empty macro")
+	}
+
+	local, senderDomain, _ := strings.Cut(c.sender, "@")
+	var value string
+	switch body[0] {
+	case 's', 'S':
+		value = c.sender
+	case 'l', 'L':
+		value = local
+	case 'o', 'O':
+		value = senderDomain
+	case 'd', 'D':
+		value = domain
+	case 'i', 'I':
+		value = spfIPMacro(c.ip)
+	case 'p', 'P':
+		value = "unknown"
+	case 'v', 'V':
+		value = "in-addr"
+		if c.ip.To4() == nil {
+			value = "ip6"
+		}
+	case 'h', 'H':
+		value = c.helo
+	default:
+		return "", permError("unknown macro letter %q", body[0])
+	}
+
+	// Transformers: an optional number of parts to keep, an optional r to
+	// reverse, then delimiters
+	rest := body[1:]
+	digits := len(rest) - len(strings.TrimLeft(rest, "0123456789"))
+	keep := 0
+	if digits > 0 {
+		keep, _ = strconv.Atoi(rest[:digits])
+		if keep == 0 {
+			return "", permError("macro keeps zero parts")
+		}
+	}
+	rest = rest[digits:]
+	reverse := false
+	if rest != "" && (rest[0] == 'r' || rest[0] == 'R') {
+		reverse, rest = true, rest[1:]
+	}
+	delimiters := rest
+	if delimiters == "" {
+		delimiters = "."
+	}
+	if strings.Trim(delimiters, ".-+,/_=") != "" {
+		return "", permError("invalid macro delimiters %q", delimiters)
+	}
+
+	parts := strings.FieldsFunc(value, func(r rune) bool {
+		return strings.ContainsRune(delimiters, r)
+	})
+	if reverse {
+		for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+			parts[i], parts[j] = parts[j], parts[i]
+		}
+	}
+	if keep > 0 && keep < len(parts) {
+		parts = parts[len(parts)-keep:]
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// spfIPMacro formats an IP for the i macro: dotted quad for IPv4, dotted
+// nibbles for IPv6
+func spfIPMacro(ip net.IP) string {
+	if v4 := ip.To4(); v4 != nil {
+		return v4.String()
+	}
+	nibbles := make([]string, 0, 32)
+	for _, b := range ip.To16() {
+		nibbles = append(nibbles, strconv.FormatInt(int64(b>>4), 16), strconv.FormatInt(int64(b&0xf), 16))
+	}
+	return strings.Join(nibbles, ".")
+}
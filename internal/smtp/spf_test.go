@@ -0,0 +1,168 @@
+package smtp
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+// testZone parses a zone file
+func testZone(t *testing.T, lines ...string) *zone {
+	t.Helper()
+
+	z := &zone{records: make(map[string]map[string][]string)}
+	if err := z.parse(bufio.NewScanner(strings.NewReader(strings.Join(lines, "\n")))); err != nil {
+		t.Fatal(err)
+	}
+	return z
+}
+
+// spfChain returns an SPF record at domain with n a: mechanisms, each for a
+// host with an address other than the client's, followed by -all
+func spfChain(domain string, n int) []string {
+	record := "v=spf1"
+	lines := []string{}
+	for i := 1; i <= n; i++ {
+		record += fmt.Sprintf(" a:h%d.example.com", i)
+		lines = append(lines, fmt.Sprintf("h%d.example.com. A 198.51.100.%d", i, i))
+	}
+	return append(lines, domain+`. TXT "`+record+` -all"`)
+}
+
+func TestCheckSPF(t *testing.T) {
+	tests := []struct {
+		name      string
+		zone      []string
+		ip        string
+		result    string
+		mechanism string
+		// Part of the reason
+		reason string
+	}{
+		{"no record", nil, "192.0.2.10", SPFNone, "", "no SPF record"},
+		{"ip4 pass", []string{`example.com. TXT "v=spf1 ip4:192.0.2.0/24 -all"`}, "192.0.2.10", SPFPass, "ip4:192.0.2.0/24", ""},
+		{"fail", []string{`example.com. TXT "v=spf1 ip4:198.51.100.0/24 -all"`}, "192.0.2.10", SPFFail, "all", ""},
+		{"softfail", []string{`example.com. TXT "v=spf1 ~all"`}, "192.0.2.10", SPFSoftFail, "all", ""},
+		{"ip6", []string{`example.com. TXT "v=spf1 ip6:2001:db8::/32 -all"`}, "2001:db8::1", SPFPass, "ip6:2001:db8::/32", ""},
+		{"two records", []string{`example.com. TXT "v=spf1 -all"`, `example.com. TXT "v=spf1 +all"`}, "192.0.2.10", SPFPermError, "", "2 SPF records"},
+		{"a with prefix", []string{
+			`example.com. TXT "v=spf1 a/24 -all"`,
+			`example.com. A 192.0.2.1`,
+		}, "192.0.2.10", SPFPass, "a/24", ""},
+		{"mx", []string{
+			`example.com. TXT "v=spf1 mx -all"`,
+			`example.com. MX 10 mail.example.com.`,
+			`mail.example.com. A 192.0.2.10`,
+		}, "192.0.2.10", SPFPass, "mx", ""},
+
+		{"include pass", []string{
+			`example.com. TXT "v=spf1 include:_spf.provider.net -all"`,
+			`_spf.provider.net. TXT "v=spf1 ip4:192.0.2.10 -all"`,
+		}, "192.0.2.10", SPFPass, "include:_spf.provider.net", ""},
+		// A failing include doesn't match, so evaluation goes on
+		{"include no match", []string{
+			`example.com. TXT "v=spf1 include:_spf.provider.net ?all"`,
+			`_spf.provider.net. TXT "v=spf1 -all"`,
+		}, "192.0.2.10", SPFNeutral, "all", ""},
+		{"include without record", []string{
+			`example.com. TXT "v=spf1 include:_spf.provider.net -all"`,
+		}, "192.0.2.10", SPFPermError, "include:_spf.provider.net", "_spf.provider.net has no SPF record"},
+		{"redirect", []string{
+			`example.com. TXT "v=spf1 redirect=_spf.example.net"`,
+			`_spf.example.net. TXT "v=spf1 ip4:192.0.2.10 -all"`,
+		}, "192.0.2.10", SPFPass, "ip4:192.0.2.10", ""},
+		// redirect only applies when no mechanism matched
+		{"redirect after match", []string{
+			`example.com. TXT "v=spf1 ip4:192.0.2.10 redirect=_spf.example.net"`,
+			`_spf.example.net. TXT "v=spf1 -all"`,
+		}, "192.0.2.10", SPFPass, "ip4:192.0.2.10", ""},
+		{"redirect without record", []string{
+			`example.com. TXT "v=spf1 redirect=_spf.example.net"`,
+		}, "192.0.2.10", SPFPermError, "redirect=_spf.example.net", "has no SPF record"},
+
+		{"ten lookups", spfChain("example.com", 10), "192.0.2.10", SPFFail, "all", ""},
+		{"eleven lookups", spfChain("example.com", 11), "192.0.2.10", SPFPermError, "a:h11.example.com", "more than 10 DNS lookups"},
+		// The include itself is the first lookup
+		{"lookups in includes count", append(spfChain("_spf.example.com", 10),
+			`example.com. TXT "v=spf1 include:_spf.example.com -all"`,
+		), "192.0.2.10", SPFPermError, "include:_spf.example.com", "more than 10 DNS lookups"},
+
+		{"two void lookups", []string{
+			`example.com. TXT "v=spf1 a:none1.example.com exists:none2.example.com -all"`,
+		}, "192.0.2.10", SPFFail, "all", ""},
+		{"three void lookups", []string{
+			`example.com. TXT "v=spf1 a:none1.example.com mx:none2.example.com exists:none3.example.com -all"`,
+		}, "192.0.2.10", SPFPermError, "exists:none3.example.com", "more than 2 DNS lookups found no records"},
+
+		{"exists macro", []string{
+			`example.com. TXT "v=spf1 exists:%{ir}.%{v}._spf.%{d} -all"`,
+			`10.2.0.192.in-addr._spf.example.com. A 127.0.0.2`,
+		}, "192.0.2.10", SPFPass, "exists:%{ir}.%{v}._spf.%{d}", ""},
+		{"exists macro no match", []string{
+			`example.com. TXT "v=spf1 exists:%{ir}.%{v}._spf.%{d} -all"`,
+			`10.2.0.192.in-addr._spf.example.com. A 127.0.0.2`,
+		}, "192.0.2.11", SPFFail, "all", ""},
+		{"invalid macro", []string{`example.com. TXT "v=spf1 exists:%{x}.example.com -all"`}, "192.0.2.10", SPFPermError, "exists:%{x}.example.com", "unknown macro letter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkSPF(testZone(t, tt.zone...), net.ParseIP(tt.ip), "user@example.com", "mail.example.com")
+			if got.Result != tt.result || got.Mechanism != tt.mechanism || !strings.Contains(got.Reason, tt.reason) {
+				t.Errorf("got %s %q (%s), want %s %q (%s)", got.Result, got.Mechanism, got.Reason, tt.result, tt.mechanism, tt.reason)
+			}
+		})
+	}
+}
+
+func TestCheckSPFNullSender(t *testing.T) {
+	z := testZone(t, `mail.example.com. TXT "v=spf1 ip4:192.0.2.10 -all"`)
+	got := checkSPF(z, net.ParseIP("192.0.2.10"), "", "mail.example.com")
+	if got.Result != SPFPass || got.Domain != "mail.example.com" {
+		t.Errorf("got %s for %s, want pass for the HELO name", got.Result, got.Domain)
+	}
+}
+
+// The examples of RFC 7208 section 7.4
+func TestSPFMacros(t *testing.T) {
+	tests := []struct {
+		ip   string
+		spec string
+		want string
+	}{
+		{"192.0.2.3", "%{s}", "strong-bad@email.example.com"},
+		{"192.0.2.3", "%{o}", "email.example.com"},
+		{"192.0.2.3", "%{d}", "email.example.com"},
+		{"192.0.2.3", "%{d4}", "email.example.com"},
+		{"192.0.2.3", "%{d3}", "email.example.com"},
+		{"192.0.2.3", "%{d2}", "example.com"},
+		{"192.0.2.3", "%{d1}", "com"},
+		{"192.0.2.3", "%{dr}", "com.example.email"},
+		{"192.0.2.3", "%{d2r}", "example.email"},
+		{"192.0.2.3", "%{l}", "strong-bad"},
+		{"192.0.2.3", "%{l-}", "strong.bad"},
+		{"192.0.2.3", "%{lr}", "strong-bad"},
+		{"192.0.2.3", "%{lr-}", "bad.strong"},
+		{"192.0.2.3", "%{l1r-}", "strong"},
+		{"192.0.2.3", "%{ir}.%{v}._spf.%{d2}", "3.2.0.192.in-addr._spf.example.com"},
+		{"192.0.2.3", "%{lr-}.lp._spf.%{d2}", "bad.strong.lp._spf.example.com"},
+		{"192.0.2.3", "%{lr-}.lp.%{ir}.%{v}._spf.%{d2}", "bad.strong.lp.3.2.0.192.in-addr._spf.example.com"},
+		{"192.0.2.3", "%{ir}.%{v}.%{l1r-}.lp._spf.%{d2}", "3.2.0.192.in-addr.strong.lp._spf.example.com"},
+		{"192.0.2.3", "%{d2}.trusted-domains.example.net", "example.com.trusted-domains.example.net"},
+		{"2001:db8::cb01", "%{ir}.%{v}._spf.%{d2}", "1.0.b.c.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6._spf.example.com"},
+	}
+
+	for _, tt := range tests {
+		c := &spfCheck{ip: net.ParseIP(tt.ip), sender: "strong-bad@email.example.com", helo: "mail.example.com"}
+		got, err := c.expand(tt.spec, "email.example.com")
+		if err != nil {
+			t.Errorf("%s: %s", tt.spec, err.reason)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.spec, got, tt.want)
+		}
+	}
+}
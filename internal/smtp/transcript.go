@@ -0,0 +1,170 @@
+package smtp
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TranscriptDirection identifies who produced a transcript line
+type TranscriptDirection string
+
+const (
+	// DirectionClient marks lines sent by the client
+	DirectionClient TranscriptDirection = "client"
+	// DirectionServer marks lines sent by the server
+	DirectionServer TranscriptDirection = "server"
+	// DirectionEvent marks notes added by PostPilot, such as injected faults
+	DirectionEvent TranscriptDirection = "event"
+)
+
+const (
+	// maxTranscripts is the number of transcripts kept in memory
+	maxTranscripts = 1000
+	// maxTranscriptLines caps the number of lines recorded per transcript
+	maxTranscriptLines = 5000
+)
+
+// TranscriptLine is a single timestamped line of an SMTP conversation
+type TranscriptLine struct {
+	// Time the line was sent or the event happened
+	Time time.Time `json:"time"`
+	// Who sent the line
+	Direction TranscriptDirection `json:"direction"`
+	// Line content without the trailing CRLF
+	Text string `json:"text"`
+}
+
+// Transcript is the recorded conversation of a single client connection
+type Transcript struct {
+	// Unique identifier for the connection, shared with emails it delivered
+	ID string `json:"id"`
+	// Remote address of the client
+	RemoteAddr string `json:"remoteAddr"`
+	// Time the connection was accepted
+	StartedAt time.Time `json:"startedAt"`
+	// Time the connection was closed, zero while it is still open
+	EndedAt time.Time `json:"endedAt"`
+	// Hostname given by the client in HELO or EHLO
+	Helo string `json:"helo"`
+	// Capabilities advertised in the last EHLO reply
+	Capabilities []string `json:"capabilities"`
+	// SASL mechanism requested with AUTH, if any
+	AuthMechanism string `json:"authMechanism"`
+	// Whether the connection was upgraded with STARTTLS
+	TLS bool `json:"tls"`
+	// IDs of the emails delivered over this connection
+	EmailIDs []string `json:"emailIds"`
+	// Bytes received from the client
+	BytesIn int64 `json:"bytesIn"`
+	// Bytes sent to the client
+	BytesOut int64 `json:"bytesOut"`
+	// How the connection ended, empty while it is still open
+	Outcome SessionOutcome `json:"outcome"`
+	// Why the connection ended that way
+	Reason string `json:"reason"`
+	// Error replies and connection errors seen during the conversation
+	Errors []string `json:"errors"`
+	// The conversation itself
+	Lines []TranscriptLine `json:"lines"`
+}
+
+// transcriptRecorder guards a transcript that is being written to
+type transcriptRecorder struct {
+	mu sync.Mutex
+	t  Transcript
+}
+
+func (r *transcriptRecorder) add(dir TranscriptDirection, text string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.addLocked(dir, text)
+}
+
+func (r *transcriptRecorder) addLocked(dir TranscriptDirection, text string) {
+	switch {
+	case len(r.t.Lines) < maxTranscriptLines:
+		r.t.Lines = append(r.t.Lines, TranscriptLine{Time: time.Now(), Direction: dir, Text: text})
+	case len(r.t.Lines) == maxTranscriptLines:
+		r.t.Lines = append(r.t.Lines, TranscriptLine{Time: time.Now(), Direction: DirectionEvent, Text: "[transcript truncated]"})
+	}
+}
+
+// event records a note about the session in the transcript
+func (r *transcriptRecorder) event(format string, args ...interface{}) {
+	r.add(DirectionEvent, fmt.Sprintf(format, args...))
+}
+
+// error records a note about the session and remembers it as an error
+func (r *transcriptRecorder) error(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.t.Errors = append(r.t.Errors, msg)
+	r.addLocked(DirectionEvent, msg)
+}
+
+func (r *transcriptRecorder) update(fn func(t *Transcript)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn(&r.t)
+}
+
+// snapshot returns a copy of the transcript that is safe to hand out
+func (r *transcriptRecorder) snapshot() Transcript {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := r.t
+	t.Capabilities = append([]string(nil), r.t.Capabilities...)
+	t.EmailIDs = append([]string(nil), r.t.EmailIDs...)
+	t.Errors = append([]string(nil), r.t.Errors...)
+	t.Lines = append([]TranscriptLine(nil), r.t.Lines...)
+	return t
+}
+
+// transcriptStore keeps the most recent transcripts in arrival order
+type transcriptStore struct {
+	mu    sync.RWMutex
+	order []*transcriptRecorder
+	byID  map[string]*transcriptRecorder
+}
+
+func (s *transcriptStore) add(r *transcriptRecorder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.byID == nil {
+		s.byID = make(map[string]*transcriptRecorder)
+	}
+	if len(s.order) >= maxTranscripts {
+		delete(s.byID, s.order[0].t.ID)
+		s.order = s.order[1:]
+	}
+	s.order = append(s.order, r)
+	s.byID[r.t.ID] = r
+}
+
+func (s *transcriptStore) get(id string) (Transcript, bool) {
+	s.mu.RLock()
+	r, ok := s.byID[id]
+	s.mu.RUnlock()
+
+	if !ok {
+		return Transcript{}, false
+	}
+	return r.snapshot(), true
+}
+
+func (s *transcriptStore) list() []Transcript {
+	s.mu.RLock()
+	recorders := append([]*transcriptRecorder(nil), s.order...)
+	s.mu.RUnlock()
+
+	transcripts := make([]Transcript, len(recorders))
+	for i, r := range recorders {
+		transcripts[i] = r.snapshot()
+	}
+	return transcripts
+}
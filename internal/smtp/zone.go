@@ -0,0 +1,318 @@
+package smtp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ZoneRecord is a single DNS record used for offline SPF and DMARC
+// evaluation
+type ZoneRecord struct {
+	// Owner name, e.g. example.com or _dmarc.example.com
+	Name string `json:"name"`
+	// TXT, A, AAAA or MX
+	Type string `json:"type"`
+	// Record data: the text of a TXT record, an address, or
+	// "preference host" for MX
+	Value string `json:"value"`
+}
+
+// ZoneConfig holds the DNS data SPF and DMARC are evaluated against. Nothing
+// is looked up on the network.
+type ZoneConfig struct {
+	// Zone file in master file format (RFC 1035). Only TXT, A, AAAA and MX
+	// records are used.
+	File string `json:"file"`
+	// Records added on top of the zone file
+	Records []ZoneRecord `json:"records"`
+}
+
+// zone is the parsed content of a ZoneConfig, keyed by lower-case owner
+// name without the trailing dot and record type
+type zone struct {
+	records map[string]map[string][]string
+}
+
+// Validate checks the configured records and that the zone file parses
+func (c ZoneConfig) Validate() error {
+	_, err := c.load()
+	return err
+}
+
+// SetZone replaces the DNS data used to evaluate SPF and DMARC
+func (s *Server) SetZone(config ZoneConfig) error {
+	return s.zone.set(config)
+}
+
+// load parses the zone file, if any, and adds the configured records. The
+// file is read on every call so edits apply to the next message.
+func (c ZoneConfig) load() (*zone, error) {
+	z := &zone{records: make(map[string]map[string][]string)}
+
+	if c.File != "" {
+		f, err := os.Open(c.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open zone file: %w", err)
+		}
+		defer f.Close()
+
+		if err := z.parse(bufio.NewScanner(f)); err != nil {
+			return nil, fmt.Errorf("%s: %w", c.File, err)
+		}
+	}
+
+	for _, r := range c.Records {
+		if r.Name == "" {
+			return nil, errors.New("zone records need a name")
+		}
+		if err := z.add(r.Name, r.Type, r.Value); err != nil {
+			return nil, fmt.Errorf("record %s: %w", r.Name, err)
+		}
+	}
+	return z, nil
+}
+
+// lookup returns the data of the records of the given type at name. MX
+// data is the exchange host.
+func (z *zone) lookup(name, recordType string) []string {
+	return z.records[normalizeName(name)][recordType]
+}
+
+// lookupIPs returns the A and AAAA addresses of name
+func (z *zone) lookupIPs(name string) []net.IP {
+	var ips []net.IP
+	for _, t := range []string{"A", "AAAA"} {
+		for _, v := range z.lookup(name, t) {
+			if ip := net.ParseIP(v); ip != nil {
+				ips = append(ips, ip)
+			}
+		}
+	}
+	return ips
+}
+
+func (z *zone) add(name, recordType, value string) error {
+	recordType = strings.ToUpper(strings.TrimSpace(recordType))
+	value = strings.TrimSpace(value)
+
+	switch recordType {
+	case "TXT":
+	case "A":
+		if ip := net.ParseIP(value); ip == nil || ip.To4() == nil {
+			return fmt.Errorf("invalid A address %q", value)
+		}
+	case "AAAA":
+		if ip := net.ParseIP(value); ip == nil || ip.To4() != nil {
+			return fmt.Errorf("invalid AAAA address %q", value)
+		}
+	case "MX":
+		// Accept "10 mx.example.com" as well as a bare host
+		fields := strings.Fields(value)
+		if len(fields) == 2 {
+			if _, err := strconv.Atoi(fields[0]); err != nil {
+				return fmt.Errorf("invalid MX preference %q", fields[0])
+			}
+			fields = fields[1:]
+		}
+		if len(fields) != 1 {
+			return fmt.Errorf("invalid MX data %q", value)
+		}
+		value = normalizeName(fields[0])
+	default:
+		return fmt.Errorf("unsupported record type %q", recordType)
+	}
+
+	name = normalizeName(name)
+	if z.records[name] == nil {
+		z.records[name] = make(map[string][]string)
+	}
+	z.records[name][recordType] = append(z.records[name][recordType], value)
+	return nil
+}
+
+func normalizeName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+}
+
+// parse reads a zone file. It understands $ORIGIN, @, relative names,
+// omitted owners, comments and parenthesized multi-line records, and skips
+// record types other than TXT, A, AAAA and MX.
+func (z *zone) parse(s *bufio.Scanner) error {
+	origin := ""
+	owner := ""
+	lineNo := 0
+
+	for s.Scan() {
+		lineNo++
+		line := stripZoneComment(s.Text())
+
+		// Join the lines of a parenthesized record
+		start := lineNo
+		for strings.Count(line, "(") > strings.Count(line, ")") && s.Scan() {
+			lineNo++
+			line += " " + stripZoneComment(s.Text())
+		}
+		line = strings.NewReplacer("(", " ", ")", " ").Replace(line)
+
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		inherit := line[0] == ' ' || line[0] == '\t'
+		tokens, err := zoneTokens(line)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", start, err)
+		}
+
+		switch strings.ToUpper(tokens[0]) {
+		case "$ORIGIN":
+			if len(tokens) != 2 {
+				return fmt.Errorf("line %d: $ORIGIN needs a domain", start)
+			}
+			origin = normalizeName(tokens[1])
+			continue
+		case "$TTL":
+			continue
+		case "$INCLUDE", "$GENERATE":
+			return fmt.Errorf("line %d: %s is not supported", start, tokens[0])
+		}
+
+		if !inherit {
+			owner = qualifyName(tokens[0], origin)
+			tokens = tokens[1:]
+		}
+		if owner == "" {
+			return fmt.Errorf("line %d: record without an owner name", start)
+		}
+
+		// Skip the optional TTL and class, in either order
+		for len(tokens) > 0 && (isTTL(tokens[0]) || isClass(tokens[0])) {
+			tokens = tokens[1:]
+		}
+		if len(tokens) < 2 {
+			return fmt.Errorf("line %d: incomplete record", start)
+		}
+
+		recordType := strings.ToUpper(tokens[0])
+		data := tokens[1:]
+		var value string
+		switch recordType {
+		case "TXT":
+			value = strings.Join(data, "")
+		case "A", "AAAA":
+			value = data[0]
+		case "MX":
+			if len(data) != 2 {
+				return fmt.Errorf("line %d: MX needs a preference and a host", start)
+			}
+			value = data[0] + " " + qualifyName(data[1], origin)
+		default:
+			continue
+		}
+		if err := z.add(owner, recordType, value); err != nil {
+			return fmt.Errorf("line %d: %w", start, err)
+		}
+	}
+	return s.Err()
+}
+
+// stripZoneComment removes a ; comment that is not inside a quoted string
+func stripZoneComment(line string) string {
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
+
+// zoneTokens splits a record into fields. Quoted strings are unquoted and
+// unescaped, so the strings of a TXT record can simply be concatenated.
+func zoneTokens(line string) ([]string, error) {
+	var tokens []string
+	var cur strings.Builder
+	inToken, quoted := false, false
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\\' && i+1 < len(line):
+			// \DDD is a decimal byte, anything else is taken literally
+			if i+3 < len(line) && isDigits(line[i+1:i+4]) {
+				n, _ := strconv.Atoi(line[i+1 : i+4])
+				cur.WriteByte(byte(n))
+				i += 3
+			} else {
+				cur.WriteByte(line[i+1])
+				i++
+			}
+			inToken = true
+		case c == '"':
+			quoted = !quoted
+			inToken = true
+		case (c == ' ' || c == '\t') && !quoted:
+			if inToken {
+				tokens = append(tokens, cur.String())
+				cur.Reset()
+				inToken = false
+			}
+		default:
+			cur.WriteByte(c)
+			inToken = true
+		}
+	}
+	if quoted {
+		return nil, errors.New("unterminated quoted string")
+	}
+	if inToken {
+		tokens = append(tokens, cur.String())
+	}
+	return tokens, nil
+}
+
+func qualifyName(name, origin string) string {
+	switch {
+	case name == "@":
+		return origin
+	case strings.HasSuffix(name, "."), origin == "":
+		return normalizeName(name)
+	}
+	return normalizeName(name + "." + origin)
+}
+
+func isTTL(s string) bool {
+	if s == "" || s[0] < '0' || s[0] > '9' {
+		return false
+	}
+	return strings.Trim(strings.ToLower(s), "0123456789smhdw") == ""
+}
+
+func isClass(s string) bool {
+	switch strings.ToUpper(s) {
+	case "IN", "CH", "HS", "CS":
+		return true
+	}
+	return false
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+package spam
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// htmlDoc is what the rules need to know about an HTML part
+type htmlDoc struct {
+	// Visible text, with whitespace collapsed
+	text string
+	// Links with their text
+	links []htmlLink
+	// Number of <img> elements
+	images int
+}
+
+type htmlLink struct {
+	href string
+	text string
+}
+
+// parseHTML extracts links, images and visible text from an HTML part.
+// Unparseable markup yields whatever was read before the error.
+func parseHTML(s string) *htmlDoc {
+	doc := &htmlDoc{}
+	if s == "" {
+		return doc
+	}
+
+	var text strings.Builder
+	var link *htmlLink
+	var linkText strings.Builder
+	skip := 0
+
+	z := html.NewTokenizer(strings.NewReader(s))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			doc.text = strings.Join(strings.Fields(text.String()), " ")
+			return doc
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			switch t.DataAtom.String() {
+			case "script", "style", "head", "title":
+				if t.Type == html.StartTagToken {
+					skip++
+				}
+			case "img":
+				doc.images++
+			case "a":
+				if href := attr(t, "href"); href != "" {
+					link = &htmlLink{href: href}
+					linkText.Reset()
+				}
+			}
+
+		case html.EndTagToken:
+			t := z.Token()
+			switch t.DataAtom.String() {
+			case "script", "style", "head", "title":
+				if skip > 0 {
+					skip--
+				}
+			case "a":
+				if link != nil {
+					link.text = strings.Join(strings.Fields(linkText.String()), " ")
+					doc.links = append(doc.links, *link)
+					link = nil
+				}
+			}
+
+		case html.TextToken:
+			if skip > 0 {
+				continue
+			}
+			t := string(z.Text())
+			text.WriteString(t)
+			text.WriteByte(' ')
+			if link != nil {
+				linkText.WriteString(t)
+			}
+		}
+	}
+}
+
+func attr(t html.Token, name string) string {
+	for _, a := range t.Attr {
+		if a.Key == name {
+			return strings.TrimSpace(a.Val)
+		}
+	}
+	return ""
+}
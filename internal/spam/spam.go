@@ -0,0 +1,361 @@
+package spam
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/watzon/postpilot/internal/links"
+)
+
+// Package spam scores messages with local heuristic rules, so that mail
+// likely to be filtered can be spotted before it ships
+
+// Targets a configured rule can match against
+const (
+	TargetSubject = "subject"
+	TargetHeader  = "header"
+	TargetText    = "text"
+	TargetHTML    = "html"
+	TargetBody    = "body"
+)
+
+// Config controls scoring
+type Config struct {
+	// Score at or above which a message is considered spam
+	Threshold float64 `json:"threshold"`
+	// JSON file with a list of Rules. Rules named after a built-in rule
+	// without a pattern change its score; a score of 0 disables it.
+	RulesFile string `json:"rulesFile"`
+}
+
+// Rule is a rule loaded from the rules file
+type Rule struct {
+	// Unique name, e.g. FREE_MONEY
+	Name string `json:"name"`
+	// What the rule detects
+	Description string `json:"description"`
+	// Score added when the rule hits
+	Score float64 `json:"score"`
+	// What Pattern is matched against: subject, header, text, html or body
+	// (text or HTML)
+	Target string `json:"target"`
+	// Header name for the header target
+	Header string `json:"header"`
+	// Regular expression. For the header target an empty pattern hits
+	// when the header is missing.
+	Pattern string `json:"pattern"`
+}
+
+// Message is the content rules are evaluated against
+type Message struct {
+	Subject string
+	// Headers by canonical name
+	Headers map[string][]string
+	// Plain text and HTML parts
+	Text string
+	HTML string
+}
+
+// Report is the score of a message with the rules that contributed to it
+type Report struct {
+	// Sum of the scores of all hits
+	Score float64 `json:"score"`
+	// Threshold the score was compared against
+	Threshold float64 `json:"threshold"`
+	// Whether Score reaches Threshold
+	Spam bool `json:"spam"`
+	// Rules that hit, highest score first
+	Hits []Hit `json:"hits"`
+}
+
+// Hit is a rule that matched a message
+type Hit struct {
+	// Name of the rule
+	Rule string `json:"rule"`
+	// What the rule detects
+	Description string `json:"description"`
+	// Score the rule added
+	Score float64 `json:"score"`
+	// What triggered the rule, e.g. the offending link
+	Detail string `json:"detail"`
+}
+
+// DefaultThreshold matches the usual SpamAssassin default
+const DefaultThreshold = 5.0
+
+// builtin is a rule implemented in code. check returns a detail string and
+// whether the rule hit.
+type builtin struct {
+	name        string
+	description string
+	score       float64
+	check       func(m *Message, doc *htmlDoc) (string, bool)
+}
+
+var shorteners = []string{
+	"bit.ly", "tinyurl.com", "goo.gl", "t.co", "ow.ly", "is.gd", "buff.ly",
+	"rebrand.ly", "cutt.ly", "shorturl.at", "tiny.cc", "bit.do", "t.ly", "rb.gy",
+}
+
+var spamPhrases = regexp.MustCompile(`(?i)\b(act now|click here|100% free|risk[- ]free|you('| ha)ve won|winner|limited time|no obligation|cash bonus|earn \$|double your|guaranteed|order now|urgent response)\b`)
+
+var builtins = []builtin{
+	{"MISSING_MESSAGE_ID", "Message has no Message-ID header", 1.0, func(m *Message, _ *htmlDoc) (string, bool) {
+		return "", m.header("Message-Id") == ""
+	}},
+	{"MISSING_DATE", "Message has no Date header", 1.0, func(m *Message, _ *htmlDoc) (string, bool) {
+		return "", m.header("Date") == ""
+	}},
+	{"MISSING_SUBJECT", "Message has no subject", 0.5, func(m *Message, _ *htmlDoc) (string, bool) {
+		return "", strings.TrimSpace(m.Subject) == ""
+	}},
+	{"SUBJECT_ALL_CAPS", "Subject is all capital letters", 1.5, func(m *Message, _ *htmlDoc) (string, bool) {
+		letters, upper := 0, 0
+		for _, r := range m.Subject {
+			if unicode.IsLetter(r) {
+				letters++
+				if unicode.IsUpper(r) {
+					upper++
+				}
+			}
+		}
+		return m.Subject, letters >= 5 && upper == letters
+	}},
+	{"SUBJECT_EXCESS_PUNCTUATION", "Subject has repeated ! or ? or $", 0.5, func(m *Message, _ *htmlDoc) (string, bool) {
+		return m.Subject, strings.Contains(m.Subject, "!!") || strings.Contains(m.Subject, "??") || strings.Contains(m.Subject, "$$")
+	}},
+	{"MISSING_TEXT_PART", "HTML message without a plain text alternative", 1.0, func(m *Message, _ *htmlDoc) (string, bool) {
+		return "", m.HTML != "" && strings.TrimSpace(m.Text) == ""
+	}},
+	{"HTML_IMAGE_ONLY", "HTML consists of images with little text", 2.0, func(m *Message, doc *htmlDoc) (string, bool) {
+		text := len(strings.Fields(doc.text))
+		return fmt.Sprintf("%d images, %d words", doc.images, text), doc.images > 0 && text < 30
+	}},
+	{"URL_SHORTENER", "Links go through a URL shortener", 1.5, func(m *Message, doc *htmlDoc) (string, bool) {
+		for _, u := range m.links(doc) {
+			host := hostOf(u)
+			for _, s := range shorteners {
+				if host == s || strings.HasSuffix(host, "."+s) {
+					return u, true
+				}
+			}
+		}
+		return "", false
+	}},
+	{"URL_IP_ADDRESS", "Links point at a bare IP address", 1.5, func(m *Message, doc *htmlDoc) (string, bool) {
+		for _, u := range m.links(doc) {
+			if net.ParseIP(hostOf(u)) != nil {
+				return u, true
+			}
+		}
+		return "", false
+	}},
+	{"LINK_TEXT_MISMATCH", "Link text shows a different domain than the link goes to", 2.0, func(m *Message, doc *htmlDoc) (string, bool) {
+		for _, l := range doc.links {
+			if links.TextMismatch(l.text, l.href) {
+				return fmt.Sprintf("%q links to %s", l.text, l.href), true
+			}
+		}
+		return "", false
+	}},
+	{"SPAM_PHRASES", "Body uses phrases common in spam", 1.0, func(m *Message, doc *htmlDoc) (string, bool) {
+		if p := spamPhrases.FindString(m.Text + "\n" + doc.text); p != "" {
+			return p, true
+		}
+		return "", false
+	}},
+	{"MISSING_LIST_UNSUBSCRIBE", "Body mentions unsubscribing but there is no List-Unsubscribe header", 0.5, func(m *Message, doc *htmlDoc) (string, bool) {
+		body := strings.ToLower(m.Text + "\n" + doc.text)
+		return "", strings.Contains(body, "unsubscribe") && m.header("List-Unsubscribe") == ""
+	}},
+}
+
+// Validate checks the threshold and that the rules file, if any, loads
+func (c Config) Validate() error {
+	if c.Threshold < 0 {
+		return errors.New("spam threshold must not be negative")
+	}
+	_, err := c.loadRules()
+	return err
+}
+
+// compiledRule is a rules file entry ready for matching
+type compiledRule struct {
+	Rule
+	pattern *regexp.Regexp
+}
+
+// loadRules reads and compiles the rules file. It is read on every call so
+// that edits apply to the next message.
+func (c Config) loadRules() ([]compiledRule, error) {
+	if c.RulesFile == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(c.RulesFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spam rules: %w", err)
+	}
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid spam rules file %s: %w", c.RulesFile, err)
+	}
+
+	compiled := make([]compiledRule, 0, len(rules))
+	for _, r := range rules {
+		if r.Name == "" {
+			return nil, errors.New("spam rules need a name")
+		}
+		cr := compiledRule{Rule: r}
+		if r.Pattern != "" {
+			if cr.pattern, err = regexp.Compile(r.Pattern); err != nil {
+				return nil, fmt.Errorf("spam rule %s: %w", r.Name, err)
+			}
+		}
+
+		switch r.Target {
+		case TargetSubject, TargetText, TargetHTML, TargetBody:
+			if r.Pattern == "" {
+				return nil, fmt.Errorf("spam rule %s needs a pattern", r.Name)
+			}
+		case TargetHeader:
+			if r.Header == "" {
+				return nil, fmt.Errorf("spam rule %s needs a header name", r.Name)
+			}
+		case "":
+			if !isBuiltin(r.Name) {
+				return nil, fmt.Errorf("spam rule %s needs a target", r.Name)
+			}
+		default:
+			return nil, fmt.Errorf("spam rule %s has unknown target %q", r.Name, r.Target)
+		}
+		compiled = append(compiled, cr)
+	}
+	return compiled, nil
+}
+
+func isBuiltin(name string) bool {
+	for _, b := range builtins {
+		if b.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Score runs the built-in rules and the rules file against m. A rules file
+// that fails to load is reported as a hit with no score rather than
+// failing the message.
+func Score(config Config, m Message) *Report {
+	threshold := config.Threshold
+	if threshold == 0 {
+		threshold = DefaultThreshold
+	}
+	report := &Report{Threshold: threshold, Hits: []Hit{}}
+
+	rules, err := config.loadRules()
+	if err != nil {
+		report.Hits = append(report.Hits, Hit{Rule: "RULES_FILE_ERROR", Description: "Custom rules could not be loaded", Detail: err.Error()})
+	}
+
+	// Rules without a pattern named after a built-in override its score
+	overrides := make(map[string]float64)
+	for _, r := range rules {
+		if r.Pattern == "" && r.Target == "" && isBuiltin(r.Name) {
+			overrides[r.Name] = r.Score
+		}
+	}
+
+	doc := parseHTML(m.HTML)
+	for _, b := range builtins {
+		score := b.score
+		if s, ok := overrides[b.name]; ok {
+			score = s
+		}
+		if score == 0 {
+			continue
+		}
+		if detail, hit := b.check(&m, doc); hit {
+			report.Hits = append(report.Hits, Hit{Rule: b.name, Description: b.description, Score: score, Detail: detail})
+		}
+	}
+
+	for _, r := range rules {
+		if r.Target == "" {
+			continue
+		}
+		if detail, hit := r.match(&m, doc); hit {
+			report.Hits = append(report.Hits, Hit{Rule: r.Name, Description: r.Description, Score: r.Score, Detail: detail})
+		}
+	}
+
+	for _, h := range report.Hits {
+		report.Score += h.Score
+	}
+	sort.SliceStable(report.Hits, func(i, j int) bool {
+		return report.Hits[i].Score > report.Hits[j].Score
+	})
+	report.Spam = report.Score >= threshold
+	return report
+}
+
+func (r compiledRule) match(m *Message, doc *htmlDoc) (string, bool) {
+	var subjects []string
+	switch r.Target {
+	case TargetSubject:
+		subjects = []string{m.Subject}
+	case TargetText:
+		subjects = []string{m.Text}
+	case TargetHTML:
+		subjects = []string{m.HTML}
+	case TargetBody:
+		subjects = []string{m.Text, doc.text}
+	case TargetHeader:
+		values := m.Headers[textproto.CanonicalMIMEHeaderKey(r.Header)]
+		if r.pattern == nil {
+			return r.Header + " is missing", len(values) == 0
+		}
+		subjects = values
+	}
+
+	for _, s := range subjects {
+		if match := r.pattern.FindString(s); match != "" {
+			return match, true
+		}
+	}
+	return "", false
+}
+
+func (m *Message) header(name string) string {
+	if v := m.Headers[textproto.CanonicalMIMEHeaderKey(name)]; len(v) > 0 {
+		return strings.TrimSpace(v[0])
+	}
+	return ""
+}
+
+// links returns the URLs linked from the HTML part and those written out
+// in the text part
+func (m *Message) links(doc *htmlDoc) []string {
+	var urls []string
+	for _, l := range doc.links {
+		urls = append(urls, l.href)
+	}
+	return append(urls, links.TextURLs(m.Text)...)
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
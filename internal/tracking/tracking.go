@@ -0,0 +1,296 @@
+package tracking
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Package tracking finds open-tracking pixels and click-tracking redirects
+// in the HTML of a message, so templates can be audited for what an email
+// service provider injects
+
+// Reasons an image or link is taken for tracking
+const (
+	// The image is 1x1 or smaller
+	ReasonTinyImage = "tiny-image"
+	// The image is hidden with CSS
+	ReasonHiddenImage = "hidden-image"
+	// The URL matches a known open or click tracking endpoint
+	ReasonKnownTracker = "known-tracker"
+	// The URL looks like a tracking endpoint, e.g. /track/open or a click.
+	// subdomain
+	ReasonTrackingURL = "tracking-url"
+	// The URL carries another URL to redirect to in its query
+	ReasonRedirect = "redirect"
+)
+
+// Report lists the tracking found in an HTML part
+type Report struct {
+	// Likely open-tracking pixels
+	Pixels []Pixel `json:"pixels"`
+	// Likely click-tracking redirects
+	Links []Link `json:"links"`
+	// Names of the known tracking services seen, sorted
+	Services []string `json:"services"`
+}
+
+// Pixel is an image that likely reports when the email is opened
+type Pixel struct {
+	URL string `json:"url"`
+	// Known service the URL belongs to, if any
+	Service string   `json:"service"`
+	Reasons []string `json:"reasons"`
+	// Line of the HTML part, 1-based
+	Line int `json:"line"`
+}
+
+// Link is a link that likely goes through a click-tracking redirect
+type Link struct {
+	URL string `json:"url"`
+	// Where the redirect leads, if the URL says
+	Destination string `json:"destination"`
+	// Known service the URL belongs to, if any
+	Service string   `json:"service"`
+	Reasons []string `json:"reasons"`
+	// Line of the HTML part, 1-based
+	Line int `json:"line"`
+}
+
+// tracker is an open or click tracking endpoint of a known service
+type tracker struct {
+	service string
+	open    *regexp.Regexp
+	click   *regexp.Regexp
+}
+
+// trackers are the endpoints of common email service providers, matched
+// against the URL without its scheme
+var trackers = []tracker{
+	{"SendGrid", regexp.MustCompile(`(?i)sendgrid\.net/wf/open`), regexp.MustCompile(`(?i)sendgrid\.net/(wf|ls)/click`)},
+	{"Mailchimp", regexp.MustCompile(`(?i)list-manage\.com/track/open`), regexp.MustCompile(`(?i)list-manage\.com/track/click`)},
+	{"Mandrill", regexp.MustCompile(`(?i)mandrillapp\.com/track/open`), regexp.MustCompile(`(?i)mandrillapp\.com/track/click`)},
+	{"Mailgun", regexp.MustCompile(`(?i)^email\.[^/]+/o/`), regexp.MustCompile(`(?i)^email\.[^/]+/c/`)},
+	{"Postmark", regexp.MustCompile(`(?i)pstmrk\.it/open`), regexp.MustCompile(`(?i)^click\.pstmrk\.it/`)},
+	{"Amazon SES", regexp.MustCompile(`(?i)awstrack\.me/I0/`), regexp.MustCompile(`(?i)awstrack\.me/L0/`)},
+	{"Mailjet", regexp.MustCompile(`(?i)\.mjt\.lu/oo/`), regexp.MustCompile(`(?i)\.mjt\.lu/lnk/`)},
+	{"Customer.io", regexp.MustCompile(`(?i)customer\.io/e/o/`), regexp.MustCompile(`(?i)customer\.io/e/c/`)},
+	{"HubSpot", regexp.MustCompile(`(?i)hubspotemail\.net/`), regexp.MustCompile(`(?i)hubspotlinks\.com/`)},
+	{"Klaviyo", regexp.MustCompile(`(?i)klaviyomail\.com/.*open`), regexp.MustCompile(`(?i)klclick\d*\.com/`)},
+	{"SparkPost", regexp.MustCompile(`(?i)spgo\.io/q/`), regexp.MustCompile(`(?i)spgo\.io/f/`)},
+}
+
+// openPath and clickPath match tracking endpoints of services not listed
+// in trackers
+var (
+	openPath  = regexp.MustCompile(`(?i)/(track|trk|t)/open|/open(ed)?(\.gif|\.png|\.php|/)|/(pixel|beacon|spacer-track)(\.gif|\.png|/|\?|$)|[?&](open|pixel)=`)
+	clickPath = regexp.MustCompile(`(?i)^(click|clicks|links?|track|trk|go|email)\.[^/]+/|/(track|trk|t)/click|/ls/click|/click(\?|/)|/redirect(\?|/)`)
+)
+
+// redirectParams are query parameters that commonly carry the destination
+// of a redirect
+var redirectParams = []string{"url", "u", "redirect", "redirect_url", "redirect_uri", "target", "dest", "destination", "link", "to", "goto", "r"}
+
+// trackingParams are added to links to attribute clicks
+var trackingParams = map[string]bool{
+	"mc_cid": true, "mc_eid": true, "_hsenc": true, "_hsmi": true, "mkt_tok": true,
+	"gclid": true, "fbclid": true, "vero_id": true, "trk": true, "tracking_id": true,
+}
+
+// Detect finds the tracking pixels and click-tracking links of an HTML part
+func Detect(s string) *Report {
+	report := &Report{Pixels: []Pixel{}, Links: []Link{}, Services: []string{}}
+	services := make(map[string]bool)
+
+	line := 1
+	z := html.NewTokenizer(strings.NewReader(s))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		start := line
+		line += strings.Count(string(z.Raw()), "\n")
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+
+		t := z.Token()
+		attrs := make(map[string]string, len(t.Attr))
+		for _, a := range t.Attr {
+			attrs[a.Key] = a.Val
+		}
+
+		switch t.Data {
+		case "img":
+			if p := CheckImage(attrs); p != nil {
+				p.Line = start
+				report.Pixels = append(report.Pixels, *p)
+				services[p.Service] = true
+			}
+		case "a", "area":
+			if l := CheckLink(attrs["href"]); l != nil {
+				l.Line = start
+				report.Links = append(report.Links, *l)
+				services[l.Service] = true
+			}
+		}
+	}
+
+	for service := range services {
+		if service != "" {
+			report.Services = append(report.Services, service)
+		}
+	}
+	sort.Strings(report.Services)
+	return report
+}
+
+// CheckImage returns the pixel an image with the given attributes is
+// likely to be, or nil. Only remote images can track.
+func CheckImage(attrs map[string]string) *Pixel {
+	src := strings.TrimSpace(attrs["src"])
+	rest, ok := withoutScheme(src)
+	if !ok {
+		return nil
+	}
+
+	p := &Pixel{URL: src, Reasons: []string{}}
+	style := strings.ToLower(strings.ReplaceAll(attrs["style"], " ", ""))
+	if isTiny(attrs["width"]) && isTiny(attrs["height"]) ||
+		isTiny(cssValue(style, "width")) && isTiny(cssValue(style, "height")) {
+		p.Reasons = append(p.Reasons, ReasonTinyImage)
+	}
+	if strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") ||
+		cssValue(style, "opacity") == "0" {
+		p.Reasons = append(p.Reasons, ReasonHiddenImage)
+	}
+	for _, t := range trackers {
+		if t.open.MatchString(rest) {
+			p.Service = t.service
+			p.Reasons = append(p.Reasons, ReasonKnownTracker)
+			break
+		}
+	}
+	if p.Service == "" && openPath.MatchString(rest) {
+		p.Reasons = append(p.Reasons, ReasonTrackingURL)
+	}
+
+	if len(p.Reasons) == 0 {
+		return nil
+	}
+	return p
+}
+
+// CheckLink returns the tracked link href is likely to be, or nil
+func CheckLink(href string) *Link {
+	href = strings.TrimSpace(href)
+	rest, ok := withoutScheme(href)
+	if !ok {
+		return nil
+	}
+
+	l := &Link{URL: href, Reasons: []string{}}
+	for _, t := range trackers {
+		if t.click.MatchString(rest) {
+			l.Service = t.service
+			l.Reasons = append(l.Reasons, ReasonKnownTracker)
+			break
+		}
+	}
+	if l.Service == "" && clickPath.MatchString(rest) {
+		l.Reasons = append(l.Reasons, ReasonTrackingURL)
+	}
+	if l.Destination = destination(href); l.Destination != "" {
+		l.Reasons = append(l.Reasons, ReasonRedirect)
+	}
+
+	if len(l.Reasons) == 0 {
+		return nil
+	}
+	return l
+}
+
+// StripParams removes the utm_* and other click attribution parameters
+// from a URL. The rest of the URL is kept as written.
+func StripParams(href string) string {
+	base, query, ok := strings.Cut(href, "?")
+	if !ok {
+		return href
+	}
+	query, fragment, hasFragment := strings.Cut(query, "#")
+
+	params := strings.Split(query, "&")
+	kept := params[:0:0]
+	for _, param := range params {
+		key, _, _ := strings.Cut(param, "=")
+		if key, err := url.QueryUnescape(key); err == nil && isTrackingParam(key) {
+			continue
+		}
+		kept = append(kept, param)
+	}
+	if len(kept) == len(params) {
+		return href
+	}
+
+	if len(kept) > 0 {
+		base += "?" + strings.Join(kept, "&")
+	}
+	if hasFragment {
+		base += "#" + fragment
+	}
+	return base
+}
+
+func isTrackingParam(key string) bool {
+	key = strings.ToLower(key)
+	return strings.HasPrefix(key, "utm_") || trackingParams[key]
+}
+
+// destination returns the http(s) URL a redirect carries in its query
+func destination(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	q := u.Query()
+	for _, param := range redirectParams {
+		v := strings.TrimSpace(q.Get(param))
+		if _, ok := withoutScheme(v); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// withoutScheme returns an http(s) or protocol-relative URL without its
+// scheme, and false for other URLs
+func withoutScheme(u string) (string, bool) {
+	lower := strings.ToLower(u)
+	for _, prefix := range []string{"https://", "http://", "//"} {
+		if strings.HasPrefix(lower, prefix) {
+			return u[len(prefix):], true
+		}
+	}
+	return "", false
+}
+
+// cssValue returns the value of a property in a lower-case style
+// attribute without spaces
+func cssValue(style, property string) string {
+	for _, decl := range strings.Split(style, ";") {
+		if name, value, ok := strings.Cut(decl, ":"); ok && name == property {
+			return strings.TrimSuffix(value, "!important")
+		}
+	}
+	return ""
+}
+
+func isTiny(v string) bool {
+	n, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(v), "px"), 64)
+	return err == nil && n <= 1
+}